@@ -0,0 +1,135 @@
+// Package cli はgocon2025コマンドのサブコマンドを実装する
+//
+// サブコマンド
+//
+//	map analyze     指定した要素数のmapの内部構造を分析する
+//	map growth      要素の追加に伴うmapの成長パターンを表示する
+//	map compare     キーと値の型が異なるmapを比較する
+//	tasks run       タスクを遅延付きで並行実行し、開始時刻と処理時間を表示する
+//	frames render   フレームを描画し、PNGの連番またはGIFに書き出す
+//
+// すべてのサブコマンドは--format text|json|csvで出力形式を指定できる
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 終了コード
+const (
+	ExitOK    = 0 // 正常終了
+	ExitError = 1 // サブコマンドの実行に失敗した
+	ExitUsage = 2 // 引数が不正
+)
+
+// runFunc はフラグを解析した後に残った引数でサブコマンドを実行し、出力する表を返す
+// 一部の処理だけが失敗した場合は、成功した分の表とエラーの両方を返す
+type runFunc func(ctx context.Context, args []string) (table, error)
+
+// command はサブコマンドの定義
+type command struct {
+	group   string                         // 1語目。map, tasks, frames のいずれか
+	name    string                         // 2語目
+	summary string                         // 使い方に表示する説明
+	setup   func(fs *flag.FlagSet) runFunc // フラグを登録し、実行する関数を返す
+}
+
+// commands はサブコマンドの一覧。使い方にはこの順に表示する
+var commands = []command{
+	{group: "map", name: "analyze", summary: "指定した要素数のmapの内部構造を分析する", setup: mapAnalyze},
+	{group: "map", name: "growth", summary: "要素の追加に伴うmapの成長パターンを表示する", setup: mapGrowth},
+	{group: "map", name: "compare", summary: "キーと値の型が異なるmapを比較する", setup: mapCompare},
+	{group: "tasks", name: "run", summary: "タスクを遅延付きで並行実行し、開始時刻と処理時間を表示する", setup: tasksRun},
+	{group: "frames", name: "render", summary: "フレームを描画し、PNGの連番またはGIFに書き出す", setup: framesRender},
+}
+
+// Run はargs（プログラム名を除く引数）で指定したサブコマンドを実行し、終了コードを返す
+// 結果はstdoutに、エラーと使い方はstderrに書き出す
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 1 && isHelp(args[0]) {
+		usage(stdout)
+		return ExitOK
+	}
+	if len(args) < 2 {
+		usage(stderr)
+		return ExitUsage
+	}
+	c, ok := lookup(args[0], args[1])
+	if !ok {
+		fmt.Fprintf(stderr, "gocon2025: unknown command %q\n\n", strings.Join(args[:2], " "))
+		usage(stderr)
+		return ExitUsage
+	}
+
+	fs := flag.NewFlagSet("gocon2025 "+c.group+" "+c.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	f := formatText
+	fs.Var(&f, "format", "出力形式 (text, json, csv)")
+	run := c.setup(fs)
+	if err := fs.Parse(args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	t, err := run(ctx, fs.Args())
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Name(), err)
+		fs.Usage()
+		return ExitUsage
+	}
+	if t.columns != nil {
+		if werr := t.write(stdout, f); werr != nil {
+			err = errors.Join(err, werr)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Name(), err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+// usageError はフラグの解析後に見つかった引数の誤り
+type usageError struct {
+	msg string
+}
+
+// Error はエラーメッセージを返す
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// lookup はグループと名前からサブコマンドを探す
+func lookup(group, name string) (command, bool) {
+	for _, c := range commands {
+		if c.group == group && c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// isHelp は引数が使い方の表示を求めているかどうかを返す
+func isHelp(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// usage はサブコマンドの一覧を書き出す
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: gocon2025 <command> <subcommand> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s%s\n", c.group+" "+c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "各サブコマンドのフラグは gocon2025 <command> <subcommand> -h で表示する")
+}
@@ -0,0 +1,154 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/cli"
+)
+
+// run はコマンドを実行し、終了コードと標準出力・標準エラー出力を返す
+func run(ctx context.Context, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(ctx, args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// decodeJSON は--format jsonの出力を行ごとのオブジェクトとして読み込む
+func decodeJSON(t *testing.T, out string) []map[string]any {
+	t.Helper()
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("JSONを読み込めませんでした: %v: %s", err, out)
+	}
+	return rows
+}
+
+// decodeCSV は--format csvの出力を読み込む
+func decodeCSV(t *testing.T, out string) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("CSVを読み込めませんでした: %v: %s", err, out)
+	}
+	return records
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Table Driven Test - 引数の誤り", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			args         []string
+			expectCode   int
+			expectStderr string
+		}{
+			{name: "引数なし", args: nil, expectCode: cli.ExitUsage, expectStderr: "usage: gocon2025"},
+			{name: "サブコマンドなし", args: []string{"map"}, expectCode: cli.ExitUsage, expectStderr: "usage: gocon2025"},
+			{name: "未知のコマンド", args: []string{"maps", "growth"}, expectCode: cli.ExitUsage, expectStderr: `unknown command "maps growth"`},
+			{name: "未知のサブコマンド", args: []string{"map", "shrink"}, expectCode: cli.ExitUsage, expectStderr: `unknown command "map shrink"`},
+			{name: "未知のフラグ", args: []string{"map", "growth", "--size", "1"}, expectCode: cli.ExitUsage, expectStderr: "flag provided but not defined: -size"},
+			{name: "未知の出力形式", args: []string{"map", "compare", "--format", "xml"}, expectCode: cli.ExitUsage, expectStderr: `unknown format "xml"`},
+			{name: "フラグの値が不正", args: []string{"map", "analyze", "--size", "-1"}, expectCode: cli.ExitUsage, expectStderr: "-size must not be negative"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				code, stdout, stderr := run(context.Background(), tc.args...)
+
+				if code != tc.expectCode {
+					t.Errorf("終了コードが期待値と異なります: got %d, want %d", code, tc.expectCode)
+				}
+				if stdout != "" {
+					t.Errorf("標準出力に何も書き出さないことを期待しました: %q", stdout)
+				}
+				if !strings.Contains(stderr, tc.expectStderr) {
+					t.Errorf("標準エラー出力に%qが含まれることを期待しました: %q", tc.expectStderr, stderr)
+				}
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 使い方の表示", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			args         []string
+			expectStdout string
+			expectStderr string
+		}{
+			{name: "help", args: []string{"help"}, expectStdout: "tasks run"},
+			{name: "--help", args: []string{"--help"}, expectStdout: "frames render"},
+			{name: "サブコマンドの-h", args: []string{"tasks", "run", "-h"}, expectStderr: "-concurrency"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				code, stdout, stderr := run(context.Background(), tc.args...)
+
+				if code != cli.ExitOK {
+					t.Errorf("終了コードが期待値と異なります: got %d, want %d", code, cli.ExitOK)
+				}
+				if !strings.Contains(stdout, tc.expectStdout) || !strings.Contains(stderr, tc.expectStderr) {
+					t.Errorf("使い方が期待値と異なります: stdout %q, stderr %q", stdout, stderr)
+				}
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 出力形式", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			format []string
+			check  func(t *testing.T, out string)
+		}{
+			{
+				name:   "既定はtext",
+				format: nil,
+				check: func(t *testing.T, out string) {
+					lines := strings.Split(strings.TrimSpace(out), "\n")
+					if len(lines) != 4 || !strings.HasPrefix(lines[0], "TYPE") || !strings.HasPrefix(lines[1], "map[string]int") {
+						t.Errorf("表が期待値と異なります: %q", out)
+					}
+				},
+			},
+			{
+				name:   "json",
+				format: []string{"--format", "json"},
+				check: func(t *testing.T, out string) {
+					rows := decodeJSON(t, out)
+					if len(rows) != 3 || rows[2]["type"] != "map[int]int" || rows[2]["size"] != float64(100) {
+						t.Errorf("JSONが期待値と異なります: %v", rows)
+					}
+					// キーは列の順に出力する
+					if i, j := strings.Index(out, `"type"`), strings.Index(out, `"size"`); i > j {
+						t.Errorf("キーの順序が期待値と異なります: %s", out)
+					}
+				},
+			},
+			{
+				name:   "csv",
+				format: []string{"--format=csv"},
+				check: func(t *testing.T, out string) {
+					records := decodeCSV(t, out)
+					if len(records) != 4 || strings.Join(records[0], ",") != "type,map_pointer,size" || records[1][2] != "100" {
+						t.Errorf("CSVが期待値と異なります: %v", records)
+					}
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				code, stdout, stderr := run(context.Background(), append([]string{"map", "compare"}, tc.format...)...)
+
+				if code != cli.ExitOK {
+					t.Fatalf("終了コードが期待値と異なります: got %d, want %d: %s", code, cli.ExitOK, stderr)
+				}
+				tc.check(t, stdout)
+			})
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// framePrefix はPNGの連番のファイル名の接頭辞
+const framePrefix = "frame_"
+
+// framesRender はフレームを描画し、--outに書き出したフレームごとの情報を出力する
+// --outの拡張子が.gifの場合はアニメーションGIF、それ以外の場合はディレクトリにPNGの連番を書き出す
+// --outを指定しない場合は描画だけを行う
+func framesRender(fs *flag.FlagSet) runFunc {
+	count := fs.Int("count", 30, "描画するフレーム数")
+	out := fs.String("out", "", "書き出し先のディレクトリ、または.gifのファイル")
+	width := fs.Int("width", 320, "フレームの幅")
+	height := fs.Int("height", 180, "フレームの高さ")
+	delay := fs.Duration("delay", 100*time.Millisecond, "GIFの1フレームの表示時間")
+	return func(ctx context.Context, _ []string) (table, error) {
+		switch {
+		case *count <= 0:
+			return table{}, usageError{fmt.Sprintf("-count must be positive: %d", *count)}
+		case *width <= 0 || *height <= 0:
+			return table{}, usageError{fmt.Sprintf("-width and -height must be positive: %dx%d", *width, *height)}
+		}
+
+		sink, file, err := openSink(*out, *delay)
+		if err != nil {
+			return table{}, err
+		}
+		rec := &recordingSink{FrameSink: sink}
+
+		cfg := synctest.RenderConfig{Width: *width, Height: *height, TotalFrames: *count}
+		frames := synctest.NewVideoProcessor().RenderFrames(ctx, cfg)
+		err = synctest.WriteFrames(ctx, frames, rec)
+
+		t := table{columns: []string{"frame", "width", "height", "file"}}
+		for _, index := range rec.written {
+			t.add(index, *width, *height, file(index))
+		}
+		return t, err
+	}
+}
+
+// openSink は書き出し先に応じたFrameSinkと、フレーム番号から書き出したファイルを返す関数を返す
+func openSink(out string, delay time.Duration) (synctest.FrameSink, func(int) string, error) {
+	switch {
+	case out == "":
+		return discardSink{}, func(int) string { return "" }, nil
+	case strings.EqualFold(filepath.Ext(out), ".gif"):
+		f, err := os.Create(out)
+		if err != nil {
+			return nil, nil, err
+		}
+		return closingSink{FrameSink: synctest.NewGIFSink(f, delay), file: f}, func(int) string { return out }, nil
+	default:
+		sink, err := synctest.NewPNGSequenceSink(out, framePrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sink, func(index int) string {
+			return filepath.Join(out, fmt.Sprintf("%s%04d.png", framePrefix, index))
+		}, nil
+	}
+}
+
+// recordingSink は書き出しに成功したフレームの番号を記録するFrameSink
+type recordingSink struct {
+	synctest.FrameSink
+	written []int
+}
+
+// WriteFrame はフレームを書き出し、成功した場合に番号を記録する
+func (s *recordingSink) WriteFrame(f synctest.Frame) error {
+	if err := s.FrameSink.WriteFrame(f); err != nil {
+		return err
+	}
+	s.written = append(s.written, f.Index)
+	return nil
+}
+
+// closingSink はsinkを閉じた後に書き出し先のファイルも閉じるFrameSink
+type closingSink struct {
+	synctest.FrameSink
+	file *os.File
+}
+
+// Close はsinkとファイルを閉じる
+func (s closingSink) Close() error {
+	return errors.Join(s.FrameSink.Close(), s.file.Close())
+}
+
+// discardSink はフレームを書き出さずに捨てるFrameSink
+type discardSink struct{}
+
+// WriteFrame は何もしない
+func (discardSink) WriteFrame(synctest.Frame) error { return nil }
+
+// Close は何もしない
+func (discardSink) Close() error { return nil }
@@ -0,0 +1,136 @@
+package cli_test
+
+import (
+	"context"
+	"fmt"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/cli"
+)
+
+func TestFramesRender(t *testing.T) {
+	t.Run("Table Driven Test - フレームの書き出し", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			out   func(dir string) string
+			check func(t *testing.T, out string, records [][]string)
+		}{
+			{
+				name: "PNGの連番",
+				out:  func(dir string) string { return filepath.Join(dir, "frames") },
+				check: func(t *testing.T, out string, records [][]string) {
+					for i, record := range records[1:] {
+						want := filepath.Join(out, fmt.Sprintf("frame_%04d.png", i+1))
+						if record[0] != strconv.Itoa(i+1) || record[3] != want {
+							t.Errorf("書き出したファイルが期待値と異なります: %v", record)
+						}
+						f, err := os.Open(record[3])
+						if err != nil {
+							t.Fatalf("ファイルを開けませんでした: %v", err)
+						}
+						cfg, err := png.DecodeConfig(f)
+						f.Close()
+						if err != nil || cfg.Width != 16 || cfg.Height != 8 {
+							t.Errorf("PNGが期待値と異なります: %+v, %v", cfg, err)
+						}
+					}
+				},
+			},
+			{
+				name: "GIF",
+				out:  func(dir string) string { return filepath.Join(dir, "movie.gif") },
+				check: func(t *testing.T, out string, records [][]string) {
+					for _, record := range records[1:] {
+						if record[3] != out {
+							t.Errorf("書き出したファイルが期待値と異なります: %v", record)
+						}
+					}
+					f, err := os.Open(out)
+					if err != nil {
+						t.Fatalf("ファイルを開けませんでした: %v", err)
+					}
+					defer f.Close()
+					anim, err := gif.DecodeAll(f)
+					if err != nil || len(anim.Image) != 3 {
+						t.Errorf("GIFが期待値と異なります: %v", err)
+					}
+				},
+			},
+			{
+				name: "書き出さない",
+				out:  func(string) string { return "" },
+				check: func(t *testing.T, _ string, records [][]string) {
+					for _, record := range records[1:] {
+						if record[3] != "" {
+							t.Errorf("ファイルを書き出さないことを期待しました: %v", record)
+						}
+					}
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				out := tc.out(t.TempDir())
+				args := []string{"frames", "render", "--count", "3", "--width", "16", "--height", "8", "--format", "csv"}
+				if out != "" {
+					args = append(args, "--out", out)
+				}
+
+				code, stdout, stderr := run(context.Background(), args...)
+
+				if code != cli.ExitOK {
+					t.Fatalf("終了コードが期待値と異なります: got %d, want %d: %s", code, cli.ExitOK, stderr)
+				}
+				records := decodeCSV(t, stdout)
+				if len(records) != 4 || strings.Join(records[0], ",") != "frame,width,height,file" {
+					t.Fatalf("出力が期待値と異なります: %v", records)
+				}
+				tc.check(t, out, records)
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 引数の誤り", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			args         []string
+			expectStderr string
+		}{
+			{name: "フレーム数が0", args: []string{"--count", "0"}, expectStderr: "-count must be positive"},
+			{name: "幅が負", args: []string{"--width", "-1"}, expectStderr: "-width and -height must be positive"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				code, _, stderr := run(context.Background(), append([]string{"frames", "render"}, tc.args...)...)
+
+				if code != cli.ExitUsage {
+					t.Errorf("終了コードが期待値と異なります: got %d, want %d", code, cli.ExitUsage)
+				}
+				if !strings.Contains(stderr, tc.expectStderr) {
+					t.Errorf("標準エラー出力に%qが含まれることを期待しました: %q", tc.expectStderr, stderr)
+				}
+			})
+		}
+	})
+
+	t.Run("書き出し先を作成できない場合は失敗する", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		code, stdout, _ := run(context.Background(), "frames", "render", "--count", "1", "--out", filepath.Join(file, "frames"))
+
+		if code != cli.ExitError || stdout != "" {
+			t.Errorf("何も出力せずに失敗することを期待しました: %d %q", code, stdout)
+		}
+	})
+}
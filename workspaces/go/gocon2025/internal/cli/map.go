@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/mapinternals"
+)
+
+// analysisColumns はMapAnalysisを出力する列
+var analysisColumns = []string{"size", "go_version", "map_pointer", "bucket_count", "load_factor"}
+
+// mapAnalyze は--sizeで指定した数の要素を追加したmapを分析する
+func mapAnalyze(fs *flag.FlagSet) runFunc {
+	size := fs.Int("size", 100, "mapに追加する要素数")
+	return func(_ context.Context, _ []string) (table, error) {
+		if *size < 0 {
+			return table{}, usageError{fmt.Sprintf("-size must not be negative: %d", *size)}
+		}
+		m := mapinternals.NewMapInternals()
+		for i := range *size {
+			m.Add(fmt.Sprintf("key_%d", i), i)
+		}
+		t := table{columns: analysisColumns}
+		addAnalysis(&t, m.AnalyzeStructure())
+		return t, nil
+	}
+}
+
+// mapGrowth はDemonstrateGrowthの各段階の分析結果を出力する
+func mapGrowth(*flag.FlagSet) runFunc {
+	return func(context.Context, []string) (table, error) {
+		t := table{columns: analysisColumns}
+		for _, a := range mapinternals.DemonstrateGrowth() {
+			addAnalysis(&t, a)
+		}
+		return t, nil
+	}
+}
+
+// mapCompare はCompareMapTypesの結果を型ごとの行として出力する
+func mapCompare(*flag.FlagSet) runFunc {
+	return func(context.Context, []string) (table, error) {
+		c := mapinternals.CompareMapTypes()
+		t := table{columns: []string{"type", "map_pointer", "size"}}
+		t.add("map[string]int", pointer(c.StringIntPointer), c.StringIntSize)
+		t.add("map[int]string", pointer(c.IntStringPointer), c.IntStringSize)
+		t.add("map[int]int", pointer(c.IntIntPointer), c.IntIntSize)
+		return t, nil
+	}
+}
+
+// addAnalysis は分析結果を1行として追加する
+func addAnalysis(t *table, a mapinternals.MapAnalysis) {
+	t.add(a.Size, a.GoVersion, pointer(a.MapPointer), a.BucketCount, a.LoadFactor)
+}
+
+// pointer はポインタアドレスを16進数の文字列にする
+func pointer(p uintptr) string {
+	return fmt.Sprintf("0x%x", p)
+}
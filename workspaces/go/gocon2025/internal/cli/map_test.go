@@ -0,0 +1,74 @@
+package cli_test
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/cli"
+)
+
+func TestMapCommands(t *testing.T) {
+	t.Run("Table Driven Test - mapの分析", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			args          []string
+			expectSizes   []float64
+			expectBuckets []float64
+		}{
+			{name: "analyze 既定の要素数", args: []string{"map", "analyze"}, expectSizes: []float64{100}, expectBuckets: []float64{32}},
+			{name: "analyze 要素数を指定", args: []string{"map", "analyze", "--size", "10"}, expectSizes: []float64{10}, expectBuckets: []float64{2}},
+			{name: "analyze 空のmap", args: []string{"map", "analyze", "--size", "0"}, expectSizes: []float64{0}, expectBuckets: []float64{1}},
+			{
+				name:          "growth",
+				args:          []string{"map", "growth"},
+				expectSizes:   []float64{0, 1, 5, 10, 20, 50, 100, 200, 500, 1000},
+				expectBuckets: []float64{1, 1, 1, 2, 4, 16, 32, 64, 128, 256},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				code, stdout, stderr := run(context.Background(), append(tc.args, "--format", "json")...)
+
+				if code != cli.ExitOK {
+					t.Fatalf("終了コードが期待値と異なります: got %d, want %d: %s", code, cli.ExitOK, stderr)
+				}
+				var sizes, buckets []float64
+				for _, row := range decodeJSON(t, stdout) {
+					sizes = append(sizes, row["size"].(float64))
+					buckets = append(buckets, row["bucket_count"].(float64))
+					if row["go_version"] != runtime.Version() {
+						t.Errorf("Goのバージョンが期待値と異なります: %v", row["go_version"])
+					}
+				}
+				if !slices.Equal(sizes, tc.expectSizes) {
+					t.Errorf("要素数が期待値と異なります: got %v, want %v", sizes, tc.expectSizes)
+				}
+				if !slices.Equal(buckets, tc.expectBuckets) {
+					t.Errorf("バケット数が期待値と異なります: got %v, want %v", buckets, tc.expectBuckets)
+				}
+			})
+		}
+	})
+
+	t.Run("compareはmapの型ごとに1行を出力する", func(t *testing.T) {
+		code, stdout, stderr := run(context.Background(), "map", "compare", "--format", "csv")
+
+		if code != cli.ExitOK {
+			t.Fatalf("終了コードが期待値と異なります: got %d, want %d: %s", code, cli.ExitOK, stderr)
+		}
+		var types []string
+		for _, record := range decodeCSV(t, stdout)[1:] {
+			types = append(types, record[0])
+			if record[1] == "0x0" {
+				t.Errorf("mapのポインタが取得できていません: %v", record)
+			}
+		}
+		want := []string{"map[string]int", "map[int]string", "map[int]int"}
+		if !slices.Equal(types, want) {
+			t.Errorf("型が期待値と異なります: got %v, want %v", types, want)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// format はサブコマンドの出力形式
+type format string
+
+const (
+	formatText format = "text" // 列を揃えた表
+	formatJSON format = "json" // 行ごとのオブジェクトの配列
+	formatCSV  format = "csv"  // ヘッダー行付きのCSV
+)
+
+// String は出力形式の名前を返す
+func (f *format) String() string {
+	return string(*f)
+}
+
+// Set は--formatに指定された出力形式を検証して設定する
+func (f *format) Set(v string) error {
+	switch format(v) {
+	case formatText, formatJSON, formatCSV:
+		*f = format(v)
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q (want text, json or csv)", v)
+	}
+}
+
+// table はサブコマンドの結果。列の順序を保ったまま各形式で書き出す
+type table struct {
+	columns []string
+	rows    [][]any // 各行の値。columnsと同じ順序で、JSONでは値の型のまま出力する
+}
+
+// add は行を追加する
+func (t *table) add(values ...any) {
+	t.rows = append(t.rows, values)
+}
+
+// write は表を指定した形式でwに書き出す
+func (t table) write(w io.Writer, f format) error {
+	switch f {
+	case formatJSON:
+		return t.writeJSON(w)
+	case formatCSV:
+		return t.writeCSV(w)
+	default:
+		return t.writeText(w)
+	}
+}
+
+// writeText は列をタブで揃えた表を書き出す
+func (t table) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.columns, "\t")))
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = fmt.Sprint(v)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeCSV はヘッダー行に続けて各行を書き出す
+func (t table) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(t.columns)
+	for _, row := range t.rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = fmt.Sprint(v)
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON は列名をキーとするオブジェクトの配列を書き出す
+// mapではキーの順序が失われるため、列の順序でオブジェクトを組み立てる
+func (t table) writeJSON(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, row := range t.rows {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for j, v := range row {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(t.columns[j])
+			value, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("encode %s: %w", t.columns[j], err)
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(w)
+	return err
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// tasksRun は引数のタスクをProcessWithDelayで並行実行し、タスクごとの開始時刻と処理時間を出力する
+// 結果は完了した順ではなく引数の順に出力する
+func tasksRun(fs *flag.FlagSet) runFunc {
+	delay := fs.Duration("delay", 100*time.Millisecond, "1タスクの処理にかかる時間")
+	concurrency := fs.Int("concurrency", 0, "同時に実行するタスク数の上限。0以下の場合はGOMAXPROCS")
+	return func(ctx context.Context, tasks []string) (table, error) {
+		if len(tasks) == 0 {
+			return table{}, usageError{"no tasks given"}
+		}
+		if *delay < 0 {
+			return table{}, usageError{fmt.Sprintf("-delay must not be negative: %v", *delay)}
+		}
+
+		p := synctest.NewTaskProcessor()
+		process := func(ctx context.Context, task string) (string, error) {
+			r := <-p.ProcessWithDelay(ctx, *delay, task)
+			return r.Value, r.Err
+		}
+
+		start := time.Now()
+		var results []synctest.Result[string]
+		for r := range synctest.Run(ctx, tasks, process, synctest.WithWorkerPool(*concurrency, 0)) {
+			results = append(results, r)
+		}
+		slices.SortFunc(results, func(a, b synctest.Result[string]) int { return a.Index - b.Index })
+
+		t := table{columns: []string{"index", "task", "result", "started", "duration", "error"}}
+		var errs []error
+		for _, r := range results {
+			var msg string
+			if r.Err != nil {
+				msg = r.Err.Error()
+				errs = append(errs, fmt.Errorf("task %q: %w", tasks[r.Index], r.Err))
+			}
+			t.add(r.Index, tasks[r.Index], r.Value, r.StartedAt.Sub(start).String(), r.Duration.String(), msg)
+		}
+		if len(results) < len(tasks) {
+			errs = append(errs, fmt.Errorf("%d of %d tasks not run: %w", len(tasks)-len(results), len(tasks), context.Cause(ctx)))
+		}
+		return t, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,123 @@
+package cli_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/cli"
+)
+
+func TestTasksRun(t *testing.T) {
+	t.Run("Table Driven Test - タスクの並行実行", func(t *testing.T) {
+		testCases := []struct {
+			name            string
+			args            []string
+			expectStarted   []string
+			expectDurations []string
+		}{
+			{
+				name:            "既定の遅延",
+				args:            []string{"--concurrency", "3", "a", "b", "c"},
+				expectStarted:   []string{"0s", "0s", "0s"},
+				expectDurations: []string{"100ms", "100ms", "100ms"},
+			},
+			{
+				// 2並列のため、3つ目のタスクは1つ目のタスクが完了してから開始する
+				name:            "同時実行数の制限",
+				args:            []string{"--delay", "50ms", "--concurrency", "2", "a", "b", "c"},
+				expectStarted:   []string{"0s", "0s", "50ms"},
+				expectDurations: []string{"50ms", "50ms", "50ms"},
+			},
+			{
+				name:            "1並列",
+				args:            []string{"--delay", "1s", "--concurrency", "1", "a", "b"},
+				expectStarted:   []string{"0s", "1s"},
+				expectDurations: []string{"1s", "1s"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					code, stdout, stderr := run(context.Background(), append([]string{"tasks", "run", "--format", "json"}, tc.args...)...)
+
+					if code != cli.ExitOK {
+						t.Fatalf("終了コードが期待値と異なります: got %d, want %d: %s", code, cli.ExitOK, stderr)
+					}
+					var tasks, results, started, durations []string
+					for _, row := range decodeJSON(t, stdout) {
+						tasks = append(tasks, row["task"].(string))
+						results = append(results, row["result"].(string))
+						started = append(started, row["started"].(string))
+						durations = append(durations, row["duration"].(string))
+					}
+					// 結果は引数の順に出力する
+					wantTasks := tc.args[len(tc.args)-len(tc.expectStarted):]
+					if !slices.Equal(tasks, wantTasks) {
+						t.Errorf("タスクの順序が期待値と異なります: got %v, want %v", tasks, wantTasks)
+					}
+					for i, r := range results {
+						if r != "処理完了: "+wantTasks[i] {
+							t.Errorf("結果が期待値と異なります: %q", r)
+						}
+					}
+					if !slices.Equal(started, tc.expectStarted) {
+						t.Errorf("開始時刻が期待値と異なります: got %v, want %v", started, tc.expectStarted)
+					}
+					if !slices.Equal(durations, tc.expectDurations) {
+						t.Errorf("処理時間が期待値と異なります: got %v, want %v", durations, tc.expectDurations)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 引数の誤り", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			args         []string
+			expectStderr string
+		}{
+			{name: "タスクなし", args: []string{"tasks", "run"}, expectStderr: "no tasks given"},
+			{name: "負の遅延", args: []string{"tasks", "run", "--delay", "-1s", "a"}, expectStderr: "-delay must not be negative"},
+			{name: "遅延の形式が不正", args: []string{"tasks", "run", "--delay", "1", "a"}, expectStderr: "invalid value"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				code, _, stderr := run(context.Background(), tc.args...)
+
+				if code != cli.ExitUsage {
+					t.Errorf("終了コードが期待値と異なります: got %d, want %d", code, cli.ExitUsage)
+				}
+				if !strings.Contains(stderr, tc.expectStderr) {
+					t.Errorf("標準エラー出力に%qが含まれることを期待しました: %q", tc.expectStderr, stderr)
+				}
+			})
+		}
+	})
+
+	t.Run("キャンセルすると完了したタスクを出力して失敗する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+			defer cancel()
+
+			code, stdout, stderr := run(ctx, "tasks", "run", "--concurrency", "1", "--format", "csv", "a", "b", "c")
+
+			if code != cli.ExitError {
+				t.Errorf("終了コードが期待値と異なります: got %d, want %d", code, cli.ExitError)
+			}
+			records := decodeCSV(t, stdout)
+			if len(records) != 2 || records[1][1] != "a" {
+				t.Errorf("完了したタスクだけを出力することを期待しました: %v", records)
+			}
+			if !strings.Contains(stderr, "2 of 3 tasks not run: context deadline exceeded") {
+				t.Errorf("標準エラー出力が期待値と異なります: %q", stderr)
+			}
+		})
+	})
+}
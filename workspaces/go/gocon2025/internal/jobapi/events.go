@@ -0,0 +1,64 @@
+package jobapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// streamEvents はジョブの進捗をServer-Sent Eventsで配信する
+// 接続した時点までのイベントを送信した後、新しいイベントを順に送信し、doneのイベントを送信すると終了する
+// Last-Event-IDヘッダーを指定した場合は、そのIDより後のイベントから送信する
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	after := 0
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("jobapi: invalid Last-Event-ID %q", v))
+			return
+		}
+		after = id
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		events, finished, changed := j.since(after)
+		for _, e := range events {
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			after = e.ID
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		if finished {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent はイベントをServer-Sent Eventsの形式で書き出す
+func writeEvent(w io.Writer, e Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,267 @@
+package jobapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/jobapi"
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// sseEvent は受信したServer-Sent Eventsの1件
+type sseEvent struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// readEvents はServer-Sent Eventsのストリームを終わりまで読み込む
+func readEvents(t *testing.T, r io.Reader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var e sseEvent
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, e)
+			e = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			e.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.Type = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("イベントを読み込めませんでした: %v", err)
+	}
+	return events
+}
+
+// eventTypes はイベントのIDと種類を"id:type"の形式で返す
+func eventTypes(events []sseEvent) []string {
+	var types []string
+	for _, e := range events {
+		types = append(types, e.ID+":"+e.Type)
+	}
+	return types
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("Table Driven Test - 進捗の配信", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			target      string
+			body        string
+			lastEventID string
+			connectAt   time.Duration // ジョブを作成してから接続するまでの時間
+			expect      []string
+		}{
+			{
+				name:   "タスク",
+				target: "/jobs/tasks",
+				body:   `{"tasks": ["a", "b", "c"]}`,
+				expect: []string{"1:task", "2:task", "3:task", "4:done"},
+			},
+			{
+				name:   "フレーム",
+				target: "/jobs/renders",
+				body:   `{"total_frames": 3, "width": 8, "height": 8}`,
+				expect: []string{"1:frame", "2:frame", "3:frame", "4:done"},
+			},
+			{
+				// 接続した時点までのイベントも送信する
+				name:      "途中から接続",
+				target:    "/jobs/tasks",
+				body:      `{"tasks": ["a", "b", "c"]}`,
+				connectAt: 150 * time.Millisecond,
+				expect:    []string{"1:task", "2:task", "3:task", "4:done"},
+			},
+			{
+				name:        "Last-Event-IDより後から再開",
+				target:      "/jobs/tasks",
+				body:        `{"tasks": ["a", "b", "c"]}`,
+				lastEventID: "2",
+				expect:      []string{"3:task", "4:done"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 1並列のため、タスクは100msごとに1つずつ完了する
+					tasks := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(1, 0))
+					s := jobapi.NewServer(jobapi.Config{Tasks: tasks})
+					defer s.Close()
+					created := decodeStatus(t, do(s, http.MethodPost, tc.target, tc.body))
+					time.Sleep(tc.connectAt)
+
+					req := httptest.NewRequest(http.MethodGet, "/jobs/"+created.ID+"/events", nil)
+					if tc.lastEventID != "" {
+						req.Header.Set("Last-Event-ID", tc.lastEventID)
+					}
+					rec := httptest.NewRecorder()
+					s.ServeHTTP(rec, req)
+
+					if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+						t.Errorf("Content-Typeが期待値と異なります: %q", ct)
+					}
+					events := readEvents(t, rec.Body)
+					if got := eventTypes(events); !slices.Equal(got, tc.expect) {
+						t.Fatalf("イベントが期待値と異なります: got %v, want %v", got, tc.expect)
+					}
+					var done jobapi.JobStatus
+					if err := json.Unmarshal([]byte(events[len(events)-1].Data), &done); err != nil {
+						t.Fatalf("終了のイベントを読み込めませんでした: %v", err)
+					}
+					if done.ID != created.ID || done.State != "completed" {
+						t.Errorf("終了のイベントの状態が期待値と異なります: %+v", done)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("フレームの進捗と残り時間", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			s := jobapi.NewServer(jobapi.Config{})
+			defer s.Close()
+			created := decodeStatus(t, do(s, http.MethodPost, "/jobs/renders", `{"total_frames": 2, "width": 8, "height": 8}`))
+
+			rec := do(s, http.MethodGet, "/jobs/"+created.ID+"/events", "")
+
+			var frames []jobapi.FrameEvent
+			for _, e := range readEvents(t, rec.Body) {
+				if e.Type != "frame" {
+					continue
+				}
+				var f jobapi.FrameEvent
+				if err := json.Unmarshal([]byte(e.Data), &f); err != nil {
+					t.Fatalf("フレームのイベントを読み込めませんでした: %v", err)
+				}
+				frames = append(frames, f)
+			}
+			want := []jobapi.FrameEvent{{Frame: 1, Completed: 1, Total: 2}, {Frame: 2, Completed: 2, Total: 2}}
+			if !slices.Equal(frames, want) {
+				t.Errorf("フレームのイベントが期待値と異なります: got %+v, want %+v", frames, want)
+			}
+		})
+	})
+
+	t.Run("失敗したタスクも原因とともにイベントで通知する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// 開いたブレーカーがすべてのタスクを拒否する
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Minute})
+			_ = b.Execute(context.Background(), func(context.Context) error { return errors.New("依存先が停止しています") })
+			s := jobapi.NewServer(jobapi.Config{Tasks: synctestpkg.NewTaskProcessor(synctestpkg.WithCircuitBreaker(b))})
+			defer s.Close()
+			created := decodeStatus(t, do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["a"]}`))
+
+			events := readEvents(t, do(s, http.MethodGet, "/jobs/"+created.ID+"/events", "").Body)
+
+			if got, want := eventTypes(events), []string{"1:task", "2:done"}; !slices.Equal(got, want) {
+				t.Fatalf("イベントが期待値と異なります: got %v, want %v", got, want)
+			}
+			var task jobapi.TaskEvent
+			if err := json.Unmarshal([]byte(events[0].Data), &task); err != nil {
+				t.Fatalf("タスクのイベントを読み込めませんでした: %v", err)
+			}
+			if task.Error == "" || task.Message != "" || task.Completed != 0 || task.Total != 1 {
+				t.Errorf("失敗したタスクのイベントが期待値と異なります: %+v", task)
+			}
+		})
+	})
+
+	t.Run("接続を切ると配信を止める", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			s := jobapi.NewServer(jobapi.Config{})
+			defer s.Close()
+			created := decodeStatus(t, do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["a"]}`))
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+
+			req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/jobs/"+created.ID+"/events", nil)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if elapsed := time.Since(start); elapsed != 50*time.Millisecond {
+				t.Errorf("接続を切った時点で返ることを期待しました: %v", elapsed)
+			}
+			if events := readEvents(t, rec.Body); len(events) != 0 {
+				t.Errorf("イベントがないことを期待しました: %v", eventTypes(events))
+			}
+		})
+	})
+
+	t.Run("不正なLast-Event-ID", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			s := jobapi.NewServer(jobapi.Config{})
+			defer s.Close()
+			created := decodeStatus(t, do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["a"]}`))
+
+			req := httptest.NewRequest(http.MethodGet, "/jobs/"+created.ID+"/events", nil)
+			req.Header.Set("Last-Event-ID", "abc")
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("ステータスコードが期待値と異なります: got %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	})
+
+	t.Run("HTTPサーバー経由でジョブを実行して進捗を受信する", func(t *testing.T) {
+		s := jobapi.NewServer(jobapi.Config{})
+		defer s.Close()
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		resp, err := ts.Client().Post(ts.URL+"/jobs/renders", "application/json", strings.NewReader(`{"total_frames": 5, "width": 16, "height": 16}`))
+		if err != nil {
+			t.Fatalf("リクエストに失敗しました: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("ステータスコードが期待値と異なります: got %d, want %d", resp.StatusCode, http.StatusAccepted)
+		}
+
+		stream, err := ts.Client().Get(ts.URL + resp.Header.Get("Location") + "/events")
+		if err != nil {
+			t.Fatalf("リクエストに失敗しました: %v", err)
+		}
+		defer stream.Body.Close()
+		events := readEvents(t, stream.Body)
+
+		want := []string{"1:frame", "2:frame", "3:frame", "4:frame", "5:frame", "6:done"}
+		if got := eventTypes(events); !slices.Equal(got, want) {
+			t.Errorf("イベントが期待値と異なります: got %v, want %v", got, want)
+		}
+
+		status, err := ts.Client().Get(ts.URL + resp.Header.Get("Location"))
+		if err != nil {
+			t.Fatalf("リクエストに失敗しました: %v", err)
+		}
+		defer status.Body.Close()
+		var got jobapi.JobStatus
+		if err := json.NewDecoder(status.Body).Decode(&got); err != nil {
+			t.Fatalf("レスポンスを読み込めませんでした: %v", err)
+		}
+		if got.State != "completed" || got.Completed != 5 {
+			t.Errorf("ジョブの状態が期待値と異なります: %+v", got)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package jobapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// JobKind はジョブの種類
+type JobKind string
+
+const (
+	// KindTasks はTaskProcessor.ProcessWithGoroutineでタスクを並行実行するジョブ
+	KindTasks JobKind = "tasks"
+	// KindRender はVideoProcessor.StartRenderでフレームを描画するジョブ
+	KindRender JobKind = "render"
+)
+
+// JobStatus はAPIが返すジョブの状態
+type JobStatus struct {
+	ID         string    `json:"id"`
+	Kind       JobKind   `json:"kind"`
+	State      string    `json:"state"`     // running, completed, canceled, failed のいずれか
+	Completed  int       `json:"completed"` // 完了したタスクまたはフレームの数
+	Total      int       `json:"total"`     // タスクまたはフレームの総数
+	CreatedAt  time.Time `json:"created_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"` // 実行中はゼロ値
+	Results    []string  `json:"results,omitempty"`    // タスクのジョブで完了したタスクのメッセージ。完了した順
+	Error      string    `json:"error,omitempty"`      // キャンセルまたは失敗した原因
+}
+
+// Event はSSEで配信するジョブの進捗
+type Event struct {
+	ID   int    // ジョブごとに1から振る連番。SSEのidとして送信する
+	Type string // SSEのevent。task, frame, done のいずれか
+	Data any    // SSEのdataとしてJSONで送信する値
+}
+
+// TaskEvent はタスクが1つ終了したことを表すイベントのデータ。タスクごとに1つ送信する
+type TaskEvent struct {
+	Message   string `json:"message"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+	Error     string `json:"error,omitempty"` // タスクが失敗した原因。成功した場合は空
+}
+
+// FrameEvent はフレームを1つ描画したことを表すイベントのデータ
+type FrameEvent struct {
+	Frame     int     `json:"frame"`
+	Completed int     `json:"completed"`
+	Total     int     `json:"total"`
+	ETA       float64 `json:"eta_seconds"` // 残りのフレームを描画するまでの推定秒数
+}
+
+// job はサーバーが管理するジョブ。IDごとにキャンセルできるコンテキストを持つ
+type job struct {
+	id     string
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	status  JobStatus
+	events  []Event
+	changed chan struct{} // イベントが追加されると閉じられる
+	done    chan struct{}
+}
+
+// newJob は実行中のジョブを作成する
+func newJob(id string, kind JobKind, total int, now time.Time, cancel context.CancelCauseFunc) *job {
+	return &job{
+		id:     id,
+		cancel: cancel,
+		status: JobStatus{
+			ID:        id,
+			Kind:      kind,
+			State:     synctest.JobRunning.String(),
+			Total:     total,
+			CreatedAt: now,
+		},
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Status は現在の状態を返す
+func (j *job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.status
+	s.Results = append([]string(nil), s.Results...)
+	return s
+}
+
+// since はafterより後のイベントと、ジョブが終了したかどうかを返す
+// 新しいイベントがない場合は、イベントが追加されると閉じられるチャネルを返す
+func (j *job) since(after int) ([]Event, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	after = min(max(after, 0), len(j.events))
+	return append([]Event(nil), j.events[after:]...), j.finishedLocked(), j.changed
+}
+
+// update は状態を更新してイベントを追加する
+func (j *job) update(typ string, fn func(s *JobStatus) any) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	data := fn(&j.status)
+	j.events = append(j.events, Event{ID: len(j.events) + 1, Type: typ, Data: data})
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// finish は終了の状態を記録して終了のイベントを追加する
+func (j *job) finish(state synctest.JobState, err error, now time.Time) {
+	j.cancel(nil)
+	j.update("done", func(s *JobStatus) any {
+		s.State = state.String()
+		s.FinishedAt = now
+		if err != nil {
+			s.Error = err.Error()
+		}
+		snapshot := *s
+		snapshot.Results = append([]string(nil), s.Results...)
+		return snapshot
+	})
+	close(j.done)
+}
+
+// finishedAt はジョブが終了した時刻を返す。実行中はゼロ値
+func (j *job) finishedAt() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.FinishedAt
+}
+
+// finishedLocked はジョブが終了したかどうかを返す
+func (j *job) finishedLocked() bool {
+	return !j.status.FinishedAt.IsZero()
+}
+
+// runTasks はタスクを並行実行し、終了したタスクごとにイベントを追加する
+// 失敗したタスクのイベントには、その原因を設定する
+func (j *job) runTasks(ctx context.Context, clock synctest.Clock, p synctest.TaskProcessor, tasks []string) {
+	var errs []error
+	for r := range p.ProcessWithGoroutine(ctx, tasks) {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			j.update("task", func(s *JobStatus) any {
+				return TaskEvent{Completed: s.Completed, Total: s.Total, Error: r.Err.Error()}
+			})
+			continue
+		}
+		j.update("task", func(s *JobStatus) any {
+			s.Completed++
+			s.Results = append(s.Results, r.Value)
+			return TaskEvent{Message: r.Value, Completed: s.Completed, Total: s.Total}
+		})
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		j.finish(synctest.JobCanceled, context.Cause(ctx), clock.Now())
+	case len(errs) > 0:
+		j.finish(synctest.JobFailed, fmt.Errorf("jobapi: %d of %d tasks failed: %w", len(errs), len(tasks), errs[0]), clock.Now())
+	default:
+		j.finish(synctest.JobCompleted, nil, clock.Now())
+	}
+}
+
+// runRender はフレームを描画し、描画したフレームごとにイベントを追加する
+func (j *job) runRender(ctx context.Context, clock synctest.Clock, p synctest.VideoProcessor, cfg synctest.RenderConfig) {
+	rj := p.StartRender(ctx, cfg, nil)
+	for e := range rj.Events() {
+		if e.Kind != synctest.EventFrameCompleted {
+			continue
+		}
+		j.update("frame", func(s *JobStatus) any {
+			s.Completed = e.Status.Completed
+			return FrameEvent{Frame: e.Frame, Completed: e.Status.Completed, Total: e.Status.Total, ETA: e.Status.ETA.Seconds()}
+		})
+	}
+
+	status := rj.Status()
+	err := status.Err
+	if status.State == synctest.JobCanceled && context.Cause(ctx) != nil {
+		err = context.Cause(ctx)
+	}
+	j.finish(status.State, err, clock.Now())
+}
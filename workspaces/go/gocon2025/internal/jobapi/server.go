@@ -0,0 +1,289 @@
+// Package jobapi はTaskProcessorとVideoProcessorをHTTPのジョブサービスとして公開する
+//
+// エンドポイント
+//
+//	POST   /jobs/tasks        タスクを並行実行するジョブを開始する
+//	POST   /jobs/renders      フレームを描画するジョブを開始する
+//	GET    /jobs/{id}         ジョブの状態と結果を返す
+//	DELETE /jobs/{id}         ジョブをキャンセルする
+//	GET    /jobs/{id}/events  ジョブの進捗をServer-Sent Eventsで配信する
+package jobapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// 受け付けるリクエストの上限
+const (
+	maxRequestBody = 1 << 20 // リクエストボディの大きさ
+	maxTasks       = 1000    // 1つのジョブのタスク数
+	maxFrames      = 10000   // 1つのジョブのフレーム数
+	maxFrameSize   = 4096    // フレームの幅と高さ
+)
+
+var (
+	// ErrJobCanceled はDELETEでジョブをキャンセルしたことを表す
+	ErrJobCanceled = errors.New("jobapi: job canceled")
+	// ErrServerClosed はClose後のサーバーにジョブを投入したこと、またはCloseで実行中のジョブを中断したことを表す
+	ErrServerClosed = errors.New("jobapi: server closed")
+)
+
+// Config はサーバーの設定
+type Config struct {
+	Tasks synctest.TaskProcessor  // タスクのジョブを実行するプロセッサ。nilの場合はNewTaskProcessor()
+	Video synctest.VideoProcessor // 描画のジョブを実行するプロセッサ。nilの場合はNewVideoProcessor()
+	Clock synctest.Clock          // ジョブの作成・終了時刻を記録するClock。nilの場合はシステムクロック
+	// Retention は終了したジョブの状態とイベントを参照できる時間。0以下の場合は5分
+	Retention time.Duration
+	// MaxFinished は保持する終了したジョブ数の上限。0以下の場合は1000
+	// 上限を超えた場合は先に終了したジョブから削除する
+	MaxFinished int
+}
+
+// normalized は既定値を補った設定を返す
+func (c Config) normalized() Config {
+	if c.Tasks == nil {
+		c.Tasks = synctest.NewTaskProcessor()
+	}
+	if c.Video == nil {
+		c.Video = synctest.NewVideoProcessor()
+	}
+	if c.Clock == nil {
+		c.Clock = synctest.NewRealClock()
+	}
+	if c.Retention <= 0 {
+		c.Retention = 5 * time.Minute
+	}
+	if c.MaxFinished <= 0 {
+		c.MaxFinished = 1000
+	}
+	return c
+}
+
+// TaskRequest はPOST /jobs/tasksのリクエストボディ
+type TaskRequest struct {
+	Tasks []string `json:"tasks"`
+}
+
+// RenderRequest はPOST /jobs/rendersのリクエストボディ
+type RenderRequest struct {
+	TotalFrames int `json:"total_frames"`
+	Width       int `json:"width,omitempty"`  // 0の場合は320
+	Height      int `json:"height,omitempty"` // 0の場合は180
+}
+
+// errorResponse はエラーのレスポンスボディ
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server はプロセッサをジョブとして公開するhttp.Handler
+// ジョブはリクエストではなくサーバーのコンテキストで実行するため、POSTのレスポンスを返した後も実行を続ける
+// 終了したジョブはRetentionとMaxFinishedの範囲で保持し、それを過ぎると404 Not Foundを返す
+type Server struct {
+	tasks synctest.TaskProcessor
+	video synctest.VideoProcessor
+	clock synctest.Clock
+	cfg   Config
+	mux   *http.ServeMux
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	jobs     map[string]*job
+	finished []*job // 終了した順に並ぶ保持しているジョブ
+	seq      int
+	closed   bool
+}
+
+// NewServer はサーバーを作成する
+func NewServer(cfg Config) *Server {
+	cfg = cfg.normalized()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s := &Server{
+		tasks:  cfg.Tasks,
+		video:  cfg.Video,
+		clock:  cfg.Clock,
+		cfg:    cfg,
+		mux:    http.NewServeMux(),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*job),
+	}
+	s.mux.HandleFunc("POST /jobs/tasks", s.createTasks)
+	s.mux.HandleFunc("POST /jobs/renders", s.createRender)
+	s.mux.HandleFunc("GET /jobs/{id}", s.getJob)
+	s.mux.HandleFunc("DELETE /jobs/{id}", s.cancelJob)
+	s.mux.HandleFunc("GET /jobs/{id}/events", s.streamEvents)
+	return s
+}
+
+// ServeHTTP はリクエストをエンドポイントに振り分ける
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close は新しいジョブの受け付けを停止し、実行中のジョブをキャンセルして終了を待つ
+// 終了したジョブの状態は、Retentionを過ぎるまで引き続き取得できる
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel(ErrServerClosed)
+	s.wg.Wait()
+}
+
+// createTasks はタスクを並行実行するジョブを開始する
+func (s *Server) createTasks(w http.ResponseWriter, r *http.Request) {
+	var req TaskRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if len(req.Tasks) == 0 || len(req.Tasks) > maxTasks {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("jobapi: tasks must contain 1 to %d tasks", maxTasks))
+		return
+	}
+
+	s.start(w, KindTasks, len(req.Tasks), func(ctx context.Context, j *job) {
+		j.runTasks(ctx, s.clock, s.tasks, req.Tasks)
+	})
+}
+
+// createRender はフレームを描画するジョブを開始する
+func (s *Server) createRender(w http.ResponseWriter, r *http.Request) {
+	var req RenderRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	switch {
+	case req.TotalFrames <= 0 || req.TotalFrames > maxFrames:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("jobapi: total_frames must be 1 to %d", maxFrames))
+		return
+	case req.Width < 0 || req.Width > maxFrameSize || req.Height < 0 || req.Height > maxFrameSize:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("jobapi: width and height must be 0 to %d", maxFrameSize))
+		return
+	}
+
+	cfg := synctest.RenderConfig{TotalFrames: req.TotalFrames, Width: req.Width, Height: req.Height}
+	s.start(w, KindRender, req.TotalFrames, func(ctx context.Context, j *job) {
+		j.runRender(ctx, s.clock, s.video, cfg)
+	})
+}
+
+// start はジョブにIDとキャンセルできるコンテキストを割り当てて実行し、202 Acceptedを返す
+func (s *Server) start(w http.ResponseWriter, kind JobKind, total int, run func(ctx context.Context, j *job)) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, ErrServerClosed)
+		return
+	}
+	s.seq++
+	id := fmt.Sprintf("job-%d", s.seq)
+	ctx, cancel := context.WithCancelCause(s.ctx)
+	j := newJob(id, kind, total, s.clock.Now(), cancel)
+	s.jobs[id] = j
+	s.wg.Go(func() {
+		run(ctx, j)
+		s.retire(j)
+	})
+	s.mu.Unlock()
+
+	w.Header().Set("Location", "/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, j.Status())
+}
+
+// retire は終了したジョブを保持しているジョブに加え、保持期間を過ぎたジョブを削除する
+func (s *Server) retire(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = append(s.finished, j)
+	s.pruneLocked()
+}
+
+// pruneLocked はRetentionを過ぎたジョブと、MaxFinishedを超えた古いジョブを削除する
+func (s *Server) pruneLocked() {
+	now := s.clock.Now()
+	n := 0
+	for _, j := range s.finished {
+		if len(s.finished)-n <= s.cfg.MaxFinished && now.Sub(j.finishedAt()) < s.cfg.Retention {
+			break
+		}
+		delete(s.jobs, j.id)
+		n++
+	}
+	s.finished = slices.Delete(s.finished, 0, n)
+}
+
+// getJob はジョブの状態と結果を返す
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, j.Status())
+}
+
+// cancelJob はジョブをキャンセルし、終了した後の状態を返す
+// 終了したジョブに対しては何もせずに状態を返す
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	j.cancel(ErrJobCanceled)
+	select {
+	case <-j.done:
+	case <-r.Context().Done():
+		return
+	}
+	writeJSON(w, http.StatusOK, j.Status())
+}
+
+// lookup はパスのIDのジョブを返す。存在しない場合は404 Not Foundを返す
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*job, bool) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	s.pruneLocked()
+	j, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("jobapi: job %q not found", id))
+	}
+	return j, ok
+}
+
+// decode はリクエストボディのJSONを読み込む。失敗した場合は400 Bad Requestを返す
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("jobapi: decode request: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON はvをJSONで書き出す
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError はエラーをJSONで書き出す
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,270 @@
+package jobapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/jobapi"
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// do はリクエストをサーバーで処理したレスポンスを返す
+func do(s http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeStatus はレスポンスボディのジョブの状態を読み込む
+func decodeStatus(t *testing.T, rec *httptest.ResponseRecorder) jobapi.JobStatus {
+	t.Helper()
+	var status jobapi.JobStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("レスポンスを読み込めませんでした: %v: %s", err, rec.Body.String())
+	}
+	return status
+}
+
+func TestServer(t *testing.T) {
+	t.Run("Table Driven Test - ジョブの実行", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			target        string
+			body          string
+			expectKind    jobapi.JobKind
+			expectTotal   int
+			expectResults []string
+			expectElapsed time.Duration
+		}{
+			{
+				// タスクは並行に実行するため、100msですべて完了する
+				name:          "タスク",
+				target:        "/jobs/tasks",
+				body:          `{"tasks": ["a", "b", "c"]}`,
+				expectKind:    jobapi.KindTasks,
+				expectTotal:   3,
+				expectResults: []string{"タスク完了: a", "タスク完了: b", "タスク完了: c"},
+				expectElapsed: 100 * time.Millisecond,
+			},
+			{
+				name:        "描画",
+				target:      "/jobs/renders",
+				body:        `{"total_frames": 4, "width": 8, "height": 8}`,
+				expectKind:  jobapi.KindRender,
+				expectTotal: 4,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					s := jobapi.NewServer(jobapi.Config{})
+					defer s.Close()
+
+					rec := do(s, http.MethodPost, tc.target, tc.body)
+					if rec.Code != http.StatusAccepted {
+						t.Fatalf("ステータスコードが期待値と異なります: got %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+					}
+					created := decodeStatus(t, rec)
+					if loc := rec.Header().Get("Location"); loc != "/jobs/"+created.ID {
+						t.Errorf("Locationが期待値と異なります: %q", loc)
+					}
+					if created.State != "running" || created.Kind != tc.expectKind || created.Total != tc.expectTotal {
+						t.Errorf("作成したジョブの状態が期待値と異なります: %+v", created)
+					}
+
+					time.Sleep(time.Second)
+					rec = do(s, http.MethodGet, "/jobs/"+created.ID, "")
+					if rec.Code != http.StatusOK {
+						t.Fatalf("ステータスコードが期待値と異なります: got %d, want %d", rec.Code, http.StatusOK)
+					}
+					status := decodeStatus(t, rec)
+					if status.State != "completed" || status.Completed != tc.expectTotal || status.Error != "" {
+						t.Errorf("終了したジョブの状態が期待値と異なります: %+v", status)
+					}
+					results := slices.Sorted(slices.Values(status.Results))
+					if !slices.Equal(results, tc.expectResults) {
+						t.Errorf("結果が期待値と異なります: got %v, want %v", results, tc.expectResults)
+					}
+					if elapsed := status.FinishedAt.Sub(status.CreatedAt); elapsed != tc.expectElapsed {
+						t.Errorf("実行時間が期待値と異なります: got %v, want %v", elapsed, tc.expectElapsed)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 不正なリクエスト", func(t *testing.T) {
+		testCases := []struct {
+			name       string
+			method     string
+			target     string
+			body       string
+			expectCode int
+		}{
+			{name: "JSONではない", method: http.MethodPost, target: "/jobs/tasks", body: `tasks`, expectCode: http.StatusBadRequest},
+			{name: "未知のフィールド", method: http.MethodPost, target: "/jobs/tasks", body: `{"task": ["a"]}`, expectCode: http.StatusBadRequest},
+			{name: "タスクなし", method: http.MethodPost, target: "/jobs/tasks", body: `{"tasks": []}`, expectCode: http.StatusBadRequest},
+			{name: "フレーム数が0", method: http.MethodPost, target: "/jobs/renders", body: `{"total_frames": 0}`, expectCode: http.StatusBadRequest},
+			{name: "フレームが大きすぎる", method: http.MethodPost, target: "/jobs/renders", body: `{"total_frames": 1, "width": 10000}`, expectCode: http.StatusBadRequest},
+			{name: "存在しないジョブの取得", method: http.MethodGet, target: "/jobs/job-404", expectCode: http.StatusNotFound},
+			{name: "存在しないジョブのキャンセル", method: http.MethodDelete, target: "/jobs/job-404", expectCode: http.StatusNotFound},
+			{name: "存在しないジョブのイベント", method: http.MethodGet, target: "/jobs/job-404/events", expectCode: http.StatusNotFound},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					s := jobapi.NewServer(jobapi.Config{})
+					defer s.Close()
+
+					rec := do(s, tc.method, tc.target, tc.body)
+
+					if rec.Code != tc.expectCode {
+						t.Errorf("ステータスコードが期待値と異なります: got %d, want %d", rec.Code, tc.expectCode)
+					}
+					var body struct {
+						Error string `json:"error"`
+					}
+					if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+						t.Errorf("エラーのメッセージを返すことを期待しました: %s", rec.Body.String())
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 実行中のジョブの中断", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			stop        func(s *jobapi.Server, id string)
+			expectError string
+			expectOther string // 同時に実行していた他のジョブの状態
+		}{
+			{
+				name: "DELETE",
+				stop: func(s *jobapi.Server, id string) {
+					rec := do(s, http.MethodDelete, "/jobs/"+id, "")
+					if rec.Code != http.StatusOK {
+						t.Errorf("ステータスコードが期待値と異なります: got %d, want %d", rec.Code, http.StatusOK)
+					}
+				},
+				expectError: jobapi.ErrJobCanceled.Error(),
+				expectOther: "completed",
+			},
+			{
+				name:        "Close",
+				stop:        func(s *jobapi.Server, _ string) { s.Close() },
+				expectError: jobapi.ErrServerClosed.Error(),
+				expectOther: "canceled",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 1並列のため、50msの時点では1つ目のタスクを実行している
+					tasks := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(1, 0))
+					s := jobapi.NewServer(jobapi.Config{Tasks: tasks})
+					defer s.Close()
+					other := decodeStatus(t, do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["x"]}`))
+					created := decodeStatus(t, do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["a", "b"]}`))
+
+					time.Sleep(50 * time.Millisecond)
+					tc.stop(s, created.ID)
+
+					status := decodeStatus(t, do(s, http.MethodGet, "/jobs/"+created.ID, ""))
+					if status.State != "canceled" || status.Error != tc.expectError || status.Completed != 0 {
+						t.Errorf("中断したジョブの状態が期待値と異なります: %+v", status)
+					}
+					if elapsed := status.FinishedAt.Sub(status.CreatedAt); elapsed != 50*time.Millisecond {
+						t.Errorf("中断した時刻が期待値と異なります: got %v, want %v", elapsed, 50*time.Millisecond)
+					}
+
+					// DELETEは他のジョブに影響せず、Closeはすべてのジョブを中断する
+					time.Sleep(100 * time.Millisecond)
+					if got := decodeStatus(t, do(s, http.MethodGet, "/jobs/"+other.ID, "")).State; got != tc.expectOther {
+						t.Errorf("他のジョブの状態が期待値と異なります: got %s, want %s", got, tc.expectOther)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 終了したジョブの保持", func(t *testing.T) {
+		// 並行に実行するため、ジョブはそれぞれ100msで終了する
+		testCases := []struct {
+			name        string
+			cfg         jobapi.Config
+			jobs        int
+			wait        time.Duration // すべてのジョブを作成してから状態を取得するまでの時間
+			expectCodes []int
+		}{
+			{name: "保持期間内", cfg: jobapi.Config{Retention: time.Minute}, jobs: 2, wait: 30 * time.Second, expectCodes: []int{http.StatusOK, http.StatusOK}},
+			{name: "保持期間を過ぎると削除する", cfg: jobapi.Config{Retention: time.Minute}, jobs: 2, wait: 2 * time.Minute, expectCodes: []int{http.StatusNotFound, http.StatusNotFound}},
+			{
+				name: "上限を超えると先に終了したジョブから削除する", cfg: jobapi.Config{MaxFinished: 2}, jobs: 3, wait: time.Second,
+				expectCodes: []int{http.StatusNotFound, http.StatusOK, http.StatusOK},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					s := jobapi.NewServer(tc.cfg)
+					defer s.Close()
+					var ids []string
+					for range tc.jobs {
+						ids = append(ids, decodeStatus(t, do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["a"]}`)).ID)
+						// 終了する順序を作成した順にそろえる
+						time.Sleep(time.Millisecond)
+					}
+					time.Sleep(tc.wait)
+
+					var codes []int
+					for _, id := range ids {
+						codes = append(codes, do(s, http.MethodGet, "/jobs/"+id, "").Code)
+					}
+					if !slices.Equal(codes, tc.expectCodes) {
+						t.Errorf("ステータスコードが期待値と異なります: got %v, want %v", codes, tc.expectCodes)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Close後のジョブの投入", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			s := jobapi.NewServer(jobapi.Config{})
+			s.Close()
+
+			rec := do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["a"]}`)
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("ステータスコードが期待値と異なります: got %d, want %d", rec.Code, http.StatusServiceUnavailable)
+			}
+		})
+	})
+
+	t.Run("終了したジョブのキャンセル", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			s := jobapi.NewServer(jobapi.Config{})
+			defer s.Close()
+			created := decodeStatus(t, do(s, http.MethodPost, "/jobs/tasks", `{"tasks": ["a"]}`))
+			time.Sleep(time.Second)
+
+			rec := do(s, http.MethodDelete, "/jobs/"+created.ID, "")
+
+			if status := decodeStatus(t, rec); rec.Code != http.StatusOK || status.State != "completed" {
+				t.Errorf("終了したジョブはそのままの状態を返すことを期待しました: %d %+v", rec.Code, status)
+			}
+		})
+	})
+}
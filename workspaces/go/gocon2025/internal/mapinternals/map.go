@@ -1,4 +1,5 @@
-package main
+// Package mapinternals はGo1.24以降のmapの内部構造と成長パターンを分析する
+package mapinternals
 
 import (
 	"fmt"
@@ -177,28 +178,3 @@ func (mtc MapTypeComparison) String() string {
 		mtc.IntStringPointer, mtc.IntStringSize,
 		mtc.IntIntPointer, mtc.IntIntSize)
 }
-
-func main() {
-	fmt.Println("GO1.24+ Map Internal Structure Analysis")
-	fmt.Println("=====================================")
-
-	// 基本的なmap操作
-	m := NewMapInternals()
-	m.Add("hello", 1)
-	m.Add("world", 2)
-
-	fmt.Printf("Basic map analysis: %s\n", m.AnalyzeStructure())
-
-	// map成長パターンの実証
-	fmt.Println("\nMap Growth Pattern:")
-	growthResults := DemonstrateGrowth()
-	for _, result := range growthResults {
-		fmt.Printf("  %s\n", result)
-	}
-
-	// 異なるmap型の比較
-	fmt.Println("\nMap Type Comparison:")
-	comparison := CompareMapTypes()
-	fmt.Printf("  %s\n", comparison)
-}
-
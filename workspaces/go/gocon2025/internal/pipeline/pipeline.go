@@ -0,0 +1,175 @@
+// Package pipeline はチャネルをつないで処理を組み立てるための汎用的なステージを提供する
+//
+// 各ステージは入力のチャネルを受け取って出力のチャネルを返す関数で、
+// TaskProcessorやVideoProcessorが返すチャネルをそのまま入力にできる
+//
+//	p := pipeline.New(ctx)
+//	encoded := pipeline.Map(p, processor.GenerateFrames(ctx, 100), encode)
+//	pipeline.ForEach(p, pipeline.Batch(p, encoded, 10, 0), write)
+//	err := p.Wait()
+//
+// ステージはPipelineのコンテキストが終了すると処理を打ち切り、出力のチャネルを1回だけ閉じる
+// いずれかのステージでエラーが発生した場合は最初のエラーを記録し、すべてのステージを打ち切る
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// errStopped はStopでパイプラインを終了したことを表す。Waitはこのエラーを返さない
+var errStopped = errors.New("pipeline: stopped")
+
+// Pipeline はステージの実行とエラーをまとめて管理する単位
+type Pipeline struct {
+	clock  synctest.Clock
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error // 最初に発生したエラー
+}
+
+// Option はパイプラインの設定を変更する関数
+type Option func(*Pipeline)
+
+// WithClock はBatchなどのステージが待機に利用するClockを指定する
+// nilを指定した場合はシステムクロックを利用する
+func WithClock(c synctest.Clock) Option {
+	return func(p *Pipeline) {
+		if c == nil {
+			c = synctest.NewRealClock()
+		}
+		p.clock = c
+	}
+}
+
+// New はctxが終了すると打ち切られるパイプラインを作成する
+func New(ctx context.Context, opts ...Option) *Pipeline {
+	pctx, cancel := context.WithCancelCause(ctx)
+	p := &Pipeline{clock: synctest.NewRealClock(), parent: ctx, ctx: pctx, cancel: cancel}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Context はステージに渡されるコンテキストを返す
+// エラーが発生した場合やStopした場合、親のコンテキストが終了した場合に終了する
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Wait はすべてのステージが終了するのを待ち、最初に発生したエラーを返す
+// エラーが発生せずに親のコンテキストが終了した場合はその原因を返す
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	if p.parent.Err() != nil {
+		return context.Cause(p.parent)
+	}
+	return nil
+}
+
+// Stop はエラーとして記録せずにすべてのステージを打ち切る
+// 終わりのない入力をTakeで必要な数だけ処理した後などに利用する
+func (p *Pipeline) Stop() {
+	p.cancel(errStopped)
+}
+
+// Go はパイプラインのステージとしてfnを実行する
+// fnがエラーを返した場合は最初のエラーとして記録し、すべてのステージを打ち切る
+func (p *Pipeline) Go(fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := fn(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// fail は最初のエラーを記録してすべてのステージを打ち切る
+// 打ち切られた後に発生したエラーは、打ち切りによって生じたものとみなして記録しない
+func (p *Pipeline) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil || p.ctx.Err() != nil {
+		return
+	}
+	p.err = err
+	p.cancel(err)
+}
+
+// send はctxが終了するまでvをoutに送信する。送信できた場合はtrueを返す
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// receive はctxが終了するまでinから受信する。inが閉じられた場合やctxが終了した場合はokがfalseになる
+func receive[T any](ctx context.Context, in <-chan T) (v T, ok bool) {
+	select {
+	case v, ok = <-in:
+		return v, ok
+	case <-ctx.Done():
+		return v, false
+	}
+}
+
+// From はvaluesを順に送信するソースのステージ
+func From[T any](p *Pipeline, values ...T) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for _, v := range values {
+			if !send(ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// ForEach はinから受信した値をfnで処理する終端のステージ
+func ForEach[T any](p *Pipeline, in <-chan T, fn func(ctx context.Context, v T) error) {
+	p.Go(func(ctx context.Context) error {
+		for {
+			v, ok := receive(ctx, in)
+			if !ok {
+				return nil
+			}
+			if err := fn(ctx, v); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// Collect はinが閉じられるまで受信した値を集め、すべてのステージの終了を待ってWaitの結果とともに返す
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var values []T
+	for {
+		v, ok := receive(p.ctx, in)
+		if !ok {
+			break
+		}
+		values = append(values, v)
+	}
+	return values, p.Wait()
+}
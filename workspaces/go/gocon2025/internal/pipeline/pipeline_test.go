@@ -0,0 +1,181 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/pipeline"
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// counter は0から順に終わりなく値を送信するソース
+func counter(p *pipeline.Pipeline) <-chan int {
+	out := make(chan int)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for i := 0; ; i++ {
+			select {
+			case out <- i:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+func TestPipeline(t *testing.T) {
+	t.Run("Table Driven Test - 最初のエラー", func(t *testing.T) {
+		errFirst := errors.New("最初のエラー")
+		errSecond := errors.New("2番目のエラー")
+
+		testCases := []struct {
+			name      string
+			build     func(p *pipeline.Pipeline) <-chan int
+			expectErr error
+		}{
+			{
+				name: "エラーなし",
+				build: func(p *pipeline.Pipeline) <-chan int {
+					return pipeline.From(p, 1, 2, 3)
+				},
+			},
+			{
+				name: "途中のステージのエラー",
+				build: func(p *pipeline.Pipeline) <-chan int {
+					return pipeline.Map(p, pipeline.From(p, 1, 2, 3), func(_ context.Context, v int) (int, error) {
+						if v == 2 {
+							return 0, errFirst
+						}
+						return v, nil
+					})
+				},
+				expectErr: errFirst,
+			},
+			{
+				// 上流のエラーで打ち切られた後に下流で発生したエラーは記録しない
+				name: "打ち切られた後のエラーは無視する",
+				build: func(p *pipeline.Pipeline) <-chan int {
+					failed := pipeline.Map(p, counter(p), func(_ context.Context, v int) (int, error) {
+						if v == 3 {
+							return 0, errFirst
+						}
+						return v, nil
+					})
+					return pipeline.Map(p, failed, func(ctx context.Context, v int) (int, error) {
+						if v == 2 {
+							<-ctx.Done()
+							return 0, errSecond
+						}
+						return v, nil
+					})
+				},
+				expectErr: errFirst,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					p := pipeline.New(context.Background())
+
+					_, err := pipeline.Collect(p, tc.build(p))
+
+					if !errors.Is(err, tc.expectErr) || (tc.expectErr == nil && err != nil) {
+						t.Errorf("エラーが期待値と異なります: got %v, want %v", err, tc.expectErr)
+					}
+					if tc.expectErr != nil && !errors.Is(context.Cause(p.Context()), tc.expectErr) {
+						t.Errorf("コンテキストの終了の原因が期待値と異なります: %v", context.Cause(p.Context()))
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("親のコンテキストの終了", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			p := pipeline.New(ctx)
+
+			ticks := pipeline.Map(p, counter(p), func(ctx context.Context, v int) (int, error) {
+				time.Sleep(30 * time.Millisecond)
+				return v, nil
+			})
+			values, err := pipeline.Collect(p, ticks)
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("エラーが期待値と異なります: got %v, want %v", err, context.DeadlineExceeded)
+			}
+			if want := []int{0, 1, 2}; !slices.Equal(values, want) {
+				t.Errorf("受信した値が期待値と異なります: got %v, want %v", values, want)
+			}
+		})
+	})
+
+	t.Run("Stopで終わらない入力を打ち切る", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			p := pipeline.New(context.Background())
+
+			var values []int
+			for v := range pipeline.Take(p, counter(p), 3) {
+				values = append(values, v)
+			}
+			p.Stop()
+
+			if err := p.Wait(); err != nil {
+				t.Errorf("Stopした場合はエラーを返さないことを期待しました: %v", err)
+			}
+			if want := []int{0, 1, 2}; !slices.Equal(values, want) {
+				t.Errorf("受信した値が期待値と異なります: got %v, want %v", values, want)
+			}
+		})
+	})
+
+	t.Run("フレームの生成から書き出しまでを組み立てる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx := context.Background()
+			processor := synctestpkg.NewVideoProcessor()
+			p := pipeline.New(ctx)
+
+			// 1フレームの生成に50ms、エンコードに20ms、書き出しに100msかかる
+			// バッファがあるため、書き出しの間も生成とエンコードは進む
+			encoded := pipeline.Map(p, processor.GenerateFrames(ctx, 6), func(ctx context.Context, frame synctestpkg.FrameResult) (string, error) {
+				if frame.Err != nil {
+					return "", frame.Err
+				}
+				time.Sleep(20 * time.Millisecond)
+				return "frame-" + strconv.Itoa(frame.Index), nil
+			})
+			var written [][]string
+			var writtenAt []time.Duration
+			start := time.Now()
+			pipeline.ForEach(p, pipeline.Batch(p, pipeline.Buffer(p, encoded, 6), 4, 0), func(ctx context.Context, batch []string) error {
+				time.Sleep(100 * time.Millisecond)
+				written = append(written, batch)
+				writtenAt = append(writtenAt, time.Since(start))
+				return nil
+			})
+
+			if err := p.Wait(); err != nil {
+				t.Fatalf("エラーが発生しました: %v", err)
+			}
+			want := [][]string{
+				{"frame-1", "frame-2", "frame-3", "frame-4"},
+				{"frame-5", "frame-6"},
+			}
+			if !slices.EqualFunc(written, want, slices.Equal) {
+				t.Errorf("書き出した内容が期待値と異なります: got %v, want %v", written, want)
+			}
+			// 4フレーム目は220msにエンコードを終え、6フレーム目は320msにエンコードを終えて書き出しの完了を待つ
+			if wantAt := []time.Duration{320 * time.Millisecond, 420 * time.Millisecond}; !slices.Equal(writtenAt, wantAt) {
+				t.Errorf("書き出した時刻が期待値と異なります: got %v, want %v", writtenAt, wantAt)
+			}
+		})
+	})
+}
@@ -0,0 +1,265 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// Map はinから受信した値をfnで変換して送信する
+func Map[In, Out any](p *Pipeline, in <-chan In, fn func(ctx context.Context, v In) (Out, error)) <-chan Out {
+	out := make(chan Out)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for {
+			v, ok := receive(ctx, in)
+			if !ok {
+				return nil
+			}
+			w, err := fn(ctx, v)
+			if err != nil {
+				return err
+			}
+			if !send(ctx, out, w) {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Filter はinから受信した値のうちfnがtrueを返したものだけを送信する
+func Filter[T any](p *Pipeline, in <-chan T, fn func(ctx context.Context, v T) (bool, error)) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for {
+			v, ok := receive(ctx, in)
+			if !ok {
+				return nil
+			}
+			keep, err := fn(ctx, v)
+			if err != nil {
+				return err
+			}
+			if keep && !send(ctx, out, v) {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// FlatMap はinから受信した値をfnで0個以上の値に変換し、順に送信する
+func FlatMap[In, Out any](p *Pipeline, in <-chan In, fn func(ctx context.Context, v In) ([]Out, error)) <-chan Out {
+	out := make(chan Out)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for {
+			v, ok := receive(ctx, in)
+			if !ok {
+				return nil
+			}
+			ws, err := fn(ctx, v)
+			if err != nil {
+				return err
+			}
+			for _, w := range ws {
+				if !send(ctx, out, w) {
+					return nil
+				}
+			}
+		}
+	})
+	return out
+}
+
+// Batch はinから受信した値をsize個ずつまとめて送信する
+// maxWaitが正の場合は、最初の値を受信してからmaxWaitが経過した時点でsize個に満たなくても送信する
+// 経過時間はWithClockで指定したClockで計る
+// inが閉じられた時点で残っている値は、size個に満たなくてもまとめて送信する
+// sizeが0以下の場合は1として扱う
+func Batch[T any](p *Pipeline, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	size = max(size, 1)
+	out := make(chan []T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+
+		var batch []T
+		var timer synctest.Timer
+		var expired <-chan time.Time
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, expired = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			b := batch
+			batch = nil
+			return send(ctx, out, b)
+		}
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return nil
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = p.clock.NewTimer(maxWait)
+					expired = timer.C()
+				}
+				if len(batch) >= size && !flush() {
+					return nil
+				}
+			case <-expired:
+				timer, expired = nil, nil
+				if !flush() {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// FanOut はinから受信した値をn個のチャネルに分配する
+// 各値は受信できる状態のいずれか1つのチャネルにだけ送信されるため、複数のワーカーで並列に処理できる
+// nが0以下の場合は1として扱う
+func FanOut[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = out
+		p.Go(func(ctx context.Context) error {
+			defer close(out)
+			for {
+				v, ok := receive(ctx, in)
+				if !ok {
+					return nil
+				}
+				if !send(ctx, out, v) {
+					return nil
+				}
+			}
+		})
+	}
+	return outs
+}
+
+// FanIn は複数のチャネルから受信した値を1つのチャネルにまとめて送信する
+// すべての入力が閉じられると出力を閉じる。値の順序は保証しない
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for {
+				v, ok := receive(ctx, in)
+				if !ok {
+					return nil
+				}
+				if !send(ctx, out, v) {
+					return nil
+				}
+			}
+		})
+	}
+	p.Go(func(context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// Tee はinから受信した値を2つのチャネルの両方に送信する
+// 両方のチャネルが受信するまで次の値を受信しないため、遅い方の受信に合わせて進む
+func Tee[T any](p *Pipeline, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out1)
+		defer close(out2)
+		for {
+			v, ok := receive(ctx, in)
+			if !ok {
+				return nil
+			}
+			// 送信を終えたチャネルをnilにして、もう一方の送信だけを待つ
+			o1, o2 := out1, out2
+			for o1 != nil || o2 != nil {
+				select {
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	})
+	return out1, out2
+}
+
+// Take はinから受信した最初のn個の値を送信して出力を閉じる
+// 上流のステージが止まらないように、出力を閉じた後もinが閉じられるまで受信して捨てる
+// 上流が終わらない場合は、必要な値を受信した後にPipeline.Stopで打ち切る
+func Take[T any](p *Pipeline, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		taken := 0
+		for taken < n {
+			v, ok := receive(ctx, in)
+			if !ok {
+				break
+			}
+			if !send(ctx, out, v) {
+				break
+			}
+			taken++
+		}
+		close(out)
+
+		for {
+			if _, ok := receive(ctx, in); !ok {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Buffer はinから受信した値をsize個まで溜めながら送信する
+// 下流の受信が一時的に遅れても、上流はsize個までは待たずに送信を続けられる
+func Buffer[T any](p *Pipeline, in <-chan T, size int) <-chan T {
+	out := make(chan T, max(size, 0))
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for {
+			v, ok := receive(ctx, in)
+			if !ok {
+				return nil
+			}
+			if !send(ctx, out, v) {
+				return nil
+			}
+		}
+	})
+	return out
+}
@@ -0,0 +1,422 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/pipeline"
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// delayed はvaluesをintervalごとに送信するソース
+func delayed[T any](p *pipeline.Pipeline, interval time.Duration, values ...T) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for _, v := range values {
+			time.Sleep(interval)
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+func TestStages(t *testing.T) {
+	errBroken := errors.New("処理に失敗")
+
+	t.Run("Table Driven Test - 変換と絞り込み", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			build        func(p *pipeline.Pipeline) <-chan string
+			expectValues []string
+			expectErr    error
+		}{
+			{
+				name: "Map",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.Map(p, pipeline.From(p, 1, 2, 3), func(_ context.Context, v int) (string, error) {
+						return strconv.Itoa(v * 10), nil
+					})
+				},
+				expectValues: []string{"10", "20", "30"},
+			},
+			{
+				name: "Mapのエラー",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.Map(p, pipeline.From(p, 1, 2, 3), func(_ context.Context, v int) (string, error) {
+						if v == 3 {
+							return "", errBroken
+						}
+						return strconv.Itoa(v), nil
+					})
+				},
+				expectValues: []string{"1", "2"},
+				expectErr:    errBroken,
+			},
+			{
+				name: "Filter",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.Filter(p, pipeline.From(p, "a", "bb", "ccc", "dd"), func(_ context.Context, v string) (bool, error) {
+						return len(v) == 2, nil
+					})
+				},
+				expectValues: []string{"bb", "dd"},
+			},
+			{
+				name: "Filterのエラー",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.Filter(p, pipeline.From(p, "a", "bb", "ccc"), func(_ context.Context, v string) (bool, error) {
+						if v == "bb" {
+							return false, errBroken
+						}
+						return true, nil
+					})
+				},
+				expectValues: []string{"a"},
+				expectErr:    errBroken,
+			},
+			{
+				name: "FlatMap",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.FlatMap(p, pipeline.From(p, 0, 1, 2), func(_ context.Context, v int) ([]string, error) {
+						return slices.Repeat([]string{strconv.Itoa(v)}, v), nil
+					})
+				},
+				expectValues: []string{"1", "2", "2"},
+			},
+			{
+				name: "FlatMapのエラー",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.FlatMap(p, pipeline.From(p, 1, 2), func(_ context.Context, v int) ([]string, error) {
+						if v == 2 {
+							return nil, errBroken
+						}
+						return []string{"x", "y"}, nil
+					})
+				},
+				expectValues: []string{"x", "y"},
+				expectErr:    errBroken,
+			},
+			{
+				name: "Take",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.Take(p, pipeline.From(p, "a", "b", "c", "d"), 2)
+				},
+				expectValues: []string{"a", "b"},
+			},
+			{
+				name: "入力より多いTake",
+				build: func(p *pipeline.Pipeline) <-chan string {
+					return pipeline.Take(p, pipeline.From(p, "a"), 5)
+				},
+				expectValues: []string{"a"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					p := pipeline.New(context.Background())
+
+					values, err := pipeline.Collect(p, tc.build(p))
+
+					if !slices.Equal(values, tc.expectValues) {
+						t.Errorf("受信した値が期待値と異なります: got %v, want %v", values, tc.expectValues)
+					}
+					if !errors.Is(err, tc.expectErr) || (tc.expectErr == nil && err != nil) {
+						t.Errorf("エラーが期待値と異なります: got %v, want %v", err, tc.expectErr)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - Batch", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			interval      time.Duration
+			size          int
+			maxWait       time.Duration
+			expectBatches [][]int
+			expectAt      []time.Duration
+		}{
+			{
+				name:          "size個ずつまとめ、残りは入力が閉じられたときに送信する",
+				interval:      10 * time.Millisecond,
+				size:          2,
+				expectBatches: [][]int{{1, 2}, {3, 4}, {5}},
+				expectAt:      []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond},
+			},
+			{
+				name:          "maxWaitが経過した時点で送信する",
+				interval:      40 * time.Millisecond,
+				size:          3,
+				maxWait:       50 * time.Millisecond,
+				expectBatches: [][]int{{1, 2}, {3, 4}, {5}},
+				expectAt:      []time.Duration{90 * time.Millisecond, 170 * time.Millisecond, 200 * time.Millisecond},
+			},
+			{
+				name:          "sizeが0以下の場合は1つずつ送信する",
+				interval:      10 * time.Millisecond,
+				size:          0,
+				expectBatches: [][]int{{1}, {2}, {3}, {4}, {5}},
+				expectAt: []time.Duration{
+					10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond,
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					p := pipeline.New(context.Background())
+					start := time.Now()
+
+					var batches [][]int
+					var at []time.Duration
+					for b := range pipeline.Batch(p, delayed(p, tc.interval, 1, 2, 3, 4, 5), tc.size, tc.maxWait) {
+						batches = append(batches, b)
+						at = append(at, time.Since(start))
+					}
+
+					if err := p.Wait(); err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+					if !slices.EqualFunc(batches, tc.expectBatches, slices.Equal) {
+						t.Errorf("まとめた値が期待値と異なります: got %v, want %v", batches, tc.expectBatches)
+					}
+					if !slices.Equal(at, tc.expectAt) {
+						t.Errorf("送信した時刻が期待値と異なります: got %v, want %v", at, tc.expectAt)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("WithClockで指定したClockでmaxWaitを計る", func(t *testing.T) {
+		clock := synctestpkg.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		p := pipeline.New(context.Background(), pipeline.WithClock(clock))
+		in := make(chan int)
+		batches := pipeline.Batch(p, in, 3, time.Second)
+
+		in <- 1
+		in <- 2
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+
+		if b := <-batches; !slices.Equal(b, []int{1, 2}) {
+			t.Errorf("まとめた値が期待値と異なります: got %v, want [1 2]", b)
+		}
+		close(in)
+		if _, ok := <-batches; ok {
+			t.Error("入力が閉じられた後に値を受信しました")
+		}
+		if err := p.Wait(); err != nil {
+			t.Errorf("エラーが発生しました: %v", err)
+		}
+	})
+
+	t.Run("FanOutとFanInで並列に処理する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			p := pipeline.New(context.Background())
+			start := time.Now()
+
+			var running, peak atomic.Int32
+			work := func(ctx context.Context, v int) (int, error) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := peak.Load()
+					if n <= old || peak.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
+				return v * v, nil
+			}
+			var workers []<-chan int
+			for _, ch := range pipeline.FanOut(p, pipeline.From(p, 1, 2, 3, 4, 5, 6), 3) {
+				workers = append(workers, pipeline.Map(p, ch, work))
+			}
+			values, err := pipeline.Collect(p, pipeline.FanIn(p, workers...))
+
+			if err != nil {
+				t.Fatalf("エラーが発生しました: %v", err)
+			}
+			slices.Sort(values)
+			if want := []int{1, 4, 9, 16, 25, 36}; !slices.Equal(values, want) {
+				t.Errorf("受信した値が期待値と異なります: got %v, want %v", values, want)
+			}
+			// 3並列で6つの値を処理するため、2回分の処理時間で終わる
+			if elapsed := time.Since(start); elapsed != 200*time.Millisecond {
+				t.Errorf("処理時間が期待値と異なります: got %v, want 200ms", elapsed)
+			}
+			if peak.Load() != 3 {
+				t.Errorf("同時に処理した数が期待値と異なります: got %d, want 3", peak.Load())
+			}
+		})
+	})
+
+	t.Run("FanOutのワーカーのエラーですべてのステージを打ち切る", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			p := pipeline.New(context.Background())
+
+			var workers []<-chan int
+			for i, ch := range pipeline.FanOut(p, counter(p), 2) {
+				workers = append(workers, pipeline.Map(p, ch, func(ctx context.Context, v int) (int, error) {
+					time.Sleep(10 * time.Millisecond)
+					if i == 1 {
+						return 0, errBroken
+					}
+					return v, nil
+				}))
+			}
+			_, err := pipeline.Collect(p, pipeline.FanIn(p, workers...))
+
+			if !errors.Is(err, errBroken) {
+				t.Errorf("エラーが期待値と異なります: got %v, want %v", err, errBroken)
+			}
+		})
+	})
+
+	t.Run("Teeは両方のチャネルに同じ値を送信する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			p := pipeline.New(context.Background())
+
+			left, right := pipeline.Tee(p, pipeline.From(p, 1, 2, 3))
+			var fromLeft []int
+			pipeline.ForEach(p, left, func(_ context.Context, v int) error {
+				fromLeft = append(fromLeft, v)
+				return nil
+			})
+			var fromRight []int
+			var rightAt []time.Duration
+			start := time.Now()
+			pipeline.ForEach(p, right, func(_ context.Context, v int) error {
+				time.Sleep(50 * time.Millisecond)
+				fromRight = append(fromRight, v)
+				rightAt = append(rightAt, time.Since(start))
+				return nil
+			})
+
+			if err := p.Wait(); err != nil {
+				t.Fatalf("エラーが発生しました: %v", err)
+			}
+			if want := []int{1, 2, 3}; !slices.Equal(fromLeft, want) || !slices.Equal(fromRight, want) {
+				t.Errorf("受信した値が期待値と異なります: left %v, right %v", fromLeft, fromRight)
+			}
+			// 遅い方の受信に合わせて進むため、右側は受信のたびに50msかかる
+			if want := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 150 * time.Millisecond}; !slices.Equal(rightAt, want) {
+				t.Errorf("右側の処理時刻が期待値と異なります: got %v, want %v", rightAt, want)
+			}
+		})
+	})
+
+	t.Run("Bufferは下流が遅れても上流の送信を待たせない", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			size         int
+			expectSentAt time.Duration // 上流が最後の値を送信し終えた時刻
+		}{
+			{name: "バッファなし", size: 0, expectSentAt: 200 * time.Millisecond},
+			{name: "すべての値を溜められるバッファ", size: 3, expectSentAt: 0},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					p := pipeline.New(context.Background())
+					start := time.Now()
+
+					var sentAt time.Duration
+					source := make(chan int)
+					p.Go(func(ctx context.Context) error {
+						defer close(source)
+						for i := range 4 {
+							source <- i
+						}
+						sentAt = time.Since(start)
+						return nil
+					})
+					pipeline.ForEach(p, pipeline.Buffer(p, source, tc.size), func(context.Context, int) error {
+						time.Sleep(100 * time.Millisecond)
+						return nil
+					})
+
+					if err := p.Wait(); err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+					if sentAt != tc.expectSentAt {
+						t.Errorf("上流が送信を終えた時刻が期待値と異なります: got %v, want %v", sentAt, tc.expectSentAt)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 出力はキャンセル後に1回だけ閉じられる", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			build func(p *pipeline.Pipeline) []<-chan int
+		}{
+			{name: "Map", build: func(p *pipeline.Pipeline) []<-chan int {
+				return []<-chan int{pipeline.Map(p, counter(p), func(_ context.Context, v int) (int, error) { return v, nil })}
+			}},
+			{name: "Filter", build: func(p *pipeline.Pipeline) []<-chan int {
+				return []<-chan int{pipeline.Filter(p, counter(p), func(context.Context, int) (bool, error) { return true, nil })}
+			}},
+			{name: "FlatMap", build: func(p *pipeline.Pipeline) []<-chan int {
+				return []<-chan int{pipeline.FlatMap(p, counter(p), func(_ context.Context, v int) ([]int, error) { return []int{v, v}, nil })}
+			}},
+			{name: "FanOut", build: func(p *pipeline.Pipeline) []<-chan int {
+				return pipeline.FanOut(p, counter(p), 3)
+			}},
+			{name: "FanIn", build: func(p *pipeline.Pipeline) []<-chan int {
+				return []<-chan int{pipeline.FanIn(p, counter(p), counter(p))}
+			}},
+			{name: "Tee", build: func(p *pipeline.Pipeline) []<-chan int {
+				left, right := pipeline.Tee(p, counter(p))
+				return []<-chan int{left, right}
+			}},
+			{name: "Take", build: func(p *pipeline.Pipeline) []<-chan int {
+				return []<-chan int{pipeline.Take(p, counter(p), 100)}
+			}},
+			{name: "Buffer", build: func(p *pipeline.Pipeline) []<-chan int {
+				return []<-chan int{pipeline.Buffer(p, counter(p), 2)}
+			}},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					ctx, cancel := context.WithCancel(context.Background())
+					p := pipeline.New(ctx)
+
+					outs := tc.build(p)
+					<-outs[0]
+					cancel()
+					// 閉じられていないチャネルや2回閉じたチャネルがあると、Waitが戻らないかパニックする
+					for _, out := range outs {
+						for range out {
+						}
+					}
+
+					if err := p.Wait(); !errors.Is(err, context.Canceled) {
+						t.Errorf("エラーが期待値と異なります: got %v, want %v", err, context.Canceled)
+					}
+				})
+			})
+		}
+	})
+}
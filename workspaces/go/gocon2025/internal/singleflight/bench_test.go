@@ -0,0 +1,104 @@
+package singleflight_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/singleflight"
+)
+
+// anyGroup はgolang.org/x/sync/singleflightと同じinterface{}ベースの実装で、比較の基準に使う
+type anyGroup struct {
+	mu sync.Mutex
+	m  map[string]*anyCall
+}
+
+type anyCall struct {
+	wg   sync.WaitGroup
+	val  any
+	err  error
+	dups int
+}
+
+func (g *anyGroup) Do(key string, fn func() (any, error)) (v any, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*anyCall)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := new(anyCall)
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+	c.wg.Done()
+
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+	return c.val, c.err, c.dups > 0
+}
+
+// payload はベンチマークで返す値。interface{}に変換するとヒープに確保される大きさにする
+type payload struct {
+	id    int
+	score float64
+	name  string
+}
+
+func BenchmarkDo(b *testing.B) {
+	b.Run("generics", func(b *testing.B) {
+		var g singleflight.Group[string, payload]
+		b.ReportAllocs()
+		for i := 0; b.Loop(); i++ {
+			v, _, _ := g.Do("key", func() (payload, error) { return payload{id: i}, nil })
+			_ = v.id
+		}
+	})
+
+	b.Run("interface", func(b *testing.B) {
+		var g anyGroup
+		b.ReportAllocs()
+		for i := 0; b.Loop(); i++ {
+			v, _, _ := g.Do("key", func() (any, error) { return payload{id: i}, nil })
+			_ = v.(payload).id
+		}
+	})
+}
+
+func BenchmarkDoParallel(b *testing.B) {
+	// 少数のキーに呼び出しが集中し、重複した呼び出しがまとめられる状況
+	keys := make([]string, 8)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+
+	b.Run("generics", func(b *testing.B) {
+		var g singleflight.Group[string, payload]
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				v, _, _ := g.Do(keys[i%len(keys)], func() (payload, error) { return payload{id: i}, nil })
+				_ = v.id
+			}
+		})
+	})
+
+	b.Run("interface", func(b *testing.B) {
+		var g anyGroup
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				v, _, _ := g.Do(keys[i%len(keys)], func() (any, error) { return payload{id: i}, nil })
+				_ = v.(payload).id
+			}
+		})
+	})
+}
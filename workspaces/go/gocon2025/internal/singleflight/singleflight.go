@@ -0,0 +1,273 @@
+// Package singleflight は同じキーに対する重複した関数呼び出しを1回にまとめる仕組みを提供する
+//
+// golang.org/x/sync/singleflightをGenericsで書き直したもので、キーと戻り値の型を指定できるため
+// interface{}への変換や型アサーションが不要になる
+package singleflight
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// errGoexit は関数の中でruntime.Goexitが呼ばれたことを表す
+var errGoexit = errors.New("runtime.Goexit was called")
+
+// panicError は関数の中で発生したパニックを、待機している呼び出し元に伝えるためのエラー
+type panicError struct {
+	value any
+	stack []byte
+}
+
+// Error はパニックの値とスタックトレースを返す
+func (p *panicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// Unwrap はパニックの値がエラーだった場合にそのエラーを返す
+func (p *panicError) Unwrap() error {
+	err, _ := p.value.(error)
+	return err
+}
+
+// newPanicError はパニックの値とスタックトレースからpanicErrorを作成する
+func newPanicError(v any) error {
+	stack := debug.Stack()
+	// 1行目の "goroutine N [status]:" は再パニックする時点では正しくないため取り除く
+	if line := bytes.IndexByte(stack, '\n'); line >= 0 {
+		stack = stack[line+1:]
+	}
+	return &panicError{value: v, stack: stack}
+}
+
+// Result はDoChanで受信する関数の結果
+type Result[V any] struct {
+	Val    V     // 関数が返した値
+	Err    error // 関数が返したエラー
+	Shared bool  // 結果を複数の呼び出し元で共有したかどうか
+}
+
+// call は実行中または完了した関数呼び出し
+type call[V any] struct {
+	wg sync.WaitGroup
+
+	// wg.Doneの前に書き込まれ、wg.Waitの後は読み取りのみ行う
+	val V
+	err error
+
+	// Groupのmuで保護する
+	dups    int                // 後から合流した呼び出し元の数
+	waiters int                // 結果を待っている呼び出し元の数
+	chans   []chan<- Result[V] // DoChanで合流した呼び出し元のチャネル
+	// done はDoContextで待機する呼び出し元がいる場合だけ作成し、関数が完了すると閉じる
+	// Doだけの呼び出しではチャネルを確保せず、割り当てを呼び出しごとに1回に抑える
+	done   chan struct{}
+	cancel context.CancelFunc // DoContextで開始した場合に関数のコンテキストをキャンセルする
+}
+
+// Group は同じキーに対する関数呼び出しをまとめる単位。ゼロ値で利用できる
+type Group[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]*call[V]
+}
+
+// Do はキーに対して関数を実行し、その結果を返す
+// 同じキーの呼び出しが実行中の場合は新たに実行せず、実行中の呼び出しが完了するのを待って同じ結果を返す
+// sharedは結果を複数の呼び出し元で共有したかどうかを表す
+// 関数がパニックした場合は、待機していたすべての呼び出し元で同じ値のパニックが発生する
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		c.waiters++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.result()
+	}
+	c := newCall[V]()
+	g.m[key] = c
+	g.mu.Unlock()
+
+	g.doCall(c, key, fn, true)
+	return c.val, c.err, c.dups > 0
+}
+
+// DoChan はDoと同様に関数を実行し、結果を受信するチャネルを返す
+// チャネルには結果が1つだけ送信され、閉じられない
+func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	ch := make(chan Result[V], 1)
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		c.waiters++
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+		return ch
+	}
+	c := newCall[V]()
+	c.chans = append(c.chans, ch)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	go g.doCall(c, key, fn, false)
+	return ch
+}
+
+// DoContext はDoと同様に関数を実行し、ctxが終了した場合は結果を待たずにctx.Err()を返す
+// 関数は呼び出し元のキャンセルから切り離したコンテキストで実行されるため、
+// 呼び出し元の1人がキャンセルしても、他の呼び出し元が待っている間は実行を続ける
+// 待っている呼び出し元がすべてキャンセルした場合は関数のコンテキストもキャンセルし、
+// 以降の同じキーの呼び出しは新たに関数を実行する
+// 関数がパニックした場合は待っている呼び出し元でパニックが発生するが、
+// すべての呼び出し元が戻った後のパニックは伝える先がないため、プロセスを終了させずに破棄する
+func (g *Group[K, V]) DoContext(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (v V, err error, shared bool) {
+	if err := ctx.Err(); err != nil {
+		return v, err, false
+	}
+
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	c, ok := g.m[key]
+	if ok {
+		c.dups++
+		c.waiters++
+		if c.done == nil {
+			c.done = make(chan struct{})
+		}
+	} else {
+		c = newCall[V]()
+		c.done = make(chan struct{})
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c.cancel = cancel
+		g.m[key] = c
+		go func() {
+			defer cancel()
+			g.doCall(c, key, func() (V, error) { return fn(callCtx) }, false)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.result()
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.waiters--
+	if c.waiters == 0 && c.cancel != nil {
+		select {
+		case <-c.done:
+		default:
+			// 結果を待つ呼び出し元がいなくなったため実行を打ち切る
+			c.cancel()
+			if g.m[key] == c {
+				delete(g.m, key)
+			}
+		}
+	}
+	return v, ctx.Err(), c.dups > 0
+}
+
+// Forget はキーの実行中の呼び出しを忘れる
+// 以降の同じキーの呼び出しは、実行中の呼び出しの完了を待たずに新たに関数を実行する
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.m, key)
+}
+
+// newCall は実行を開始する呼び出しを作成する
+func newCall[V any]() *call[V] {
+	c := &call[V]{waiters: 1}
+	c.wg.Add(1)
+	return c
+}
+
+// result は完了した呼び出しの結果を返す。関数がパニックやGoexitで終了した場合は同じように終了する
+func (c *call[V]) result() (V, error, bool) {
+	if e, ok := c.err.(*panicError); ok {
+		panic(e)
+	}
+	if c.err == errGoexit {
+		runtime.Goexit()
+	}
+	return c.val, c.err, c.dups > 0
+}
+
+// doCall は関数を実行して結果を記録し、待機している呼び出し元に通知する
+// inCallerは呼び出し元のゴルーチンで実行しているかどうかを表し、パニックの伝え方を決める
+func (g *Group[K, V]) doCall(c *call[V], key K, fn func() (V, error), inCaller bool) {
+	normalReturn := false
+	recovered := false
+
+	// パニックとruntime.Goexitを区別するために二重のdeferを使う
+	defer func() {
+		// 関数が正常に終了せず、パニックも回復していない場合はGoexitが呼ばれた
+		if !normalReturn && !recovered {
+			c.err = errGoexit
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		c.wg.Done()
+		if c.done != nil {
+			close(c.done)
+		}
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+
+		if e, ok := c.err.(*panicError); ok {
+			switch {
+			case len(c.chans) > 0:
+				// DoChanの呼び出し元は回復できないため、パニックを握りつぶさずにプロセスを終了させる
+				go panic(e)
+				select {} // クラッシュダンプに残すためにこのゴルーチンを止めておく
+			case inCaller:
+				panic(e)
+			}
+			// 待機している呼び出し元がresultで再パニックする
+			// DoContextの呼び出し元がすべて戻った後であれば、パニックを伝える先がないため破棄する
+			return
+		}
+		if c.err == errGoexit {
+			// Goexitの途中のため何もしない
+			return
+		}
+		for _, ch := range c.chans {
+			ch <- Result[V]{Val: c.val, Err: c.err, Shared: c.dups > 0}
+		}
+	}()
+
+	func() {
+		defer func() {
+			if !normalReturn {
+				// Goexitの場合はrecoverがnilを返す
+				if r := recover(); r != nil {
+					c.err = newPanicError(r)
+				}
+			}
+		}()
+
+		c.val, c.err = fn()
+		normalReturn = true
+	}()
+
+	if !normalReturn {
+		recovered = true
+	}
+}
@@ -0,0 +1,325 @@
+package singleflight_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/singleflight"
+)
+
+func TestDo(t *testing.T) {
+	t.Run("関数の結果を返す", func(t *testing.T) {
+		var g singleflight.Group[string, int]
+
+		v, err, shared := g.Do("key", func() (int, error) { return 42, nil })
+
+		if v != 42 || err != nil || shared {
+			t.Errorf("結果が期待値と異なります: got (%v, %v, %v)", v, err, shared)
+		}
+	})
+
+	t.Run("関数のエラーを返す", func(t *testing.T) {
+		var g singleflight.Group[string, int]
+		errFailed := errors.New("失敗")
+
+		_, err, _ := g.Do("key", func() (int, error) { return 0, errFailed })
+
+		if !errors.Is(err, errFailed) {
+			t.Errorf("エラーが期待値と異なります: got %v", err)
+		}
+	})
+
+	t.Run("同じキーの同時呼び出しは1回にまとめられる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var g singleflight.Group[string, int]
+			var calls atomic.Int32
+			release := make(chan struct{})
+			fn := func() (int, error) {
+				calls.Add(1)
+				<-release
+				return 42, nil
+			}
+
+			const n = 10
+			var wg sync.WaitGroup
+			results := make(chan int, n)
+			sharedCount := make(chan bool, n)
+			for range n {
+				wg.Go(func() {
+					v, _, shared := g.Do("key", fn)
+					results <- v
+					sharedCount <- shared
+				})
+			}
+			synctest.Wait()
+			close(release)
+			wg.Wait()
+			close(results)
+			close(sharedCount)
+
+			if got := calls.Load(); got != 1 {
+				t.Errorf("関数の実行回数が期待値と異なります: got %d, want 1", got)
+			}
+			for v := range results {
+				if v != 42 {
+					t.Errorf("結果が期待値と異なります: got %d", v)
+				}
+			}
+			for shared := range sharedCount {
+				if !shared {
+					t.Error("共有された結果がsharedになっていません")
+				}
+			}
+		})
+	})
+
+	t.Run("異なるキーは別々に実行される", func(t *testing.T) {
+		var g singleflight.Group[int, int]
+
+		for i := range 3 {
+			v, _, _ := g.Do(i, func() (int, error) { return i * 10, nil })
+			if v != i*10 {
+				t.Errorf("キー%dの結果が期待値と異なります: got %d", i, v)
+			}
+		}
+	})
+
+	t.Run("パニックは待機していたすべての呼び出し元に伝わる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var g singleflight.Group[string, int]
+			errBoom := errors.New("壊れた")
+			release := make(chan struct{})
+
+			recovered := make(chan any, 2)
+			var wg sync.WaitGroup
+			for range 2 {
+				wg.Go(func() {
+					defer func() { recovered <- recover() }()
+					_, _, _ = g.Do("key", func() (int, error) {
+						<-release
+						panic(errBoom)
+					})
+				})
+			}
+			synctest.Wait()
+			close(release)
+			wg.Wait()
+			close(recovered)
+
+			for v := range recovered {
+				err, ok := v.(error)
+				if !ok || !errors.Is(err, errBoom) {
+					t.Errorf("パニックの値が期待値と異なります: got %v", v)
+				}
+			}
+		})
+	})
+
+	t.Run("Goexitは呼び出し元のゴルーチンを終了させる", func(t *testing.T) {
+		var g singleflight.Group[string, int]
+		returned := false
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			_, _, _ = g.Do("key", func() (int, error) {
+				runtime.Goexit()
+				return 0, nil
+			})
+			returned = true
+		}()
+		<-done
+
+		if returned {
+			t.Error("Goexit後にDoが戻りました")
+		}
+	})
+}
+
+func TestDoChan(t *testing.T) {
+	t.Run("結果をチャネルで受信する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var g singleflight.Group[string, string]
+			release := make(chan struct{})
+			fn := func() (string, error) {
+				<-release
+				return "結果", nil
+			}
+
+			first := g.DoChan("key", fn)
+			second := g.DoChan("key", fn)
+			close(release)
+
+			for _, ch := range []<-chan singleflight.Result[string]{first, second} {
+				r := <-ch
+				if r.Val != "結果" || r.Err != nil || !r.Shared {
+					t.Errorf("結果が期待値と異なります: %+v", r)
+				}
+			}
+		})
+	})
+}
+
+func TestForget(t *testing.T) {
+	t.Run("忘れたキーは新たに実行される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var g singleflight.Group[string, int]
+			release := make(chan struct{})
+			first := g.DoChan("key", func() (int, error) {
+				<-release
+				return 1, nil
+			})
+			synctest.Wait()
+
+			g.Forget("key")
+			v, _, shared := g.Do("key", func() (int, error) { return 2, nil })
+
+			if v != 2 || shared {
+				t.Errorf("Forget後の結果が期待値と異なります: got (%v, %v)", v, shared)
+			}
+			close(release)
+			if r := <-first; r.Val != 1 {
+				t.Errorf("実行中だった呼び出しの結果が期待値と異なります: got %d", r.Val)
+			}
+		})
+	})
+}
+
+func TestDoContext(t *testing.T) {
+	t.Run("キャンセルした呼び出し元だけが先に戻り、共有の実行は続く", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var g singleflight.Group[string, int]
+			start := time.Now()
+			var callErr error
+			fn := func(ctx context.Context) (int, error) {
+				select {
+				case <-time.After(time.Second):
+					return 42, nil
+				case <-ctx.Done():
+					callErr = ctx.Err()
+					return 0, ctx.Err()
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(500*time.Millisecond, cancel)
+			type outcome struct {
+				v       int
+				err     error
+				elapsed time.Duration
+			}
+			canceled := make(chan outcome, 1)
+			go func() {
+				v, err, _ := g.DoContext(ctx, "key", fn)
+				canceled <- outcome{v, err, time.Since(start)}
+			}()
+			synctest.Wait()
+
+			v, err, shared := g.DoContext(context.Background(), "key", fn)
+
+			if v != 42 || err != nil || !shared {
+				t.Errorf("待ち続けた呼び出し元の結果が期待値と異なります: got (%v, %v, %v)", v, err, shared)
+			}
+			if elapsed := time.Since(start); elapsed != time.Second {
+				t.Errorf("待ち続けた呼び出し元の経過時間が期待値と異なります: got %v, want 1s", elapsed)
+			}
+			got := <-canceled
+			if !errors.Is(got.err, context.Canceled) || got.elapsed != 500*time.Millisecond {
+				t.Errorf("キャンセルした呼び出し元の結果が期待値と異なります: got %+v", got)
+			}
+			if callErr != nil {
+				t.Errorf("共有の実行がキャンセルされました: %v", callErr)
+			}
+		})
+	})
+
+	t.Run("すべての呼び出し元がキャンセルすると実行も打ち切られる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var g singleflight.Group[string, int]
+			var calls atomic.Int32
+			callErr := make(chan error, 1)
+			fn := func(ctx context.Context) (int, error) {
+				calls.Add(1)
+				<-ctx.Done()
+				callErr <- ctx.Err()
+				return 0, ctx.Err()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			var wg sync.WaitGroup
+			for range 2 {
+				wg.Go(func() { _, _, _ = g.DoContext(ctx, "key", fn) })
+			}
+			wg.Wait()
+
+			if err := <-callErr; !errors.Is(err, context.Canceled) {
+				t.Errorf("共有の実行のエラーが期待値と異なります: got %v", err)
+			}
+			// 打ち切られた実行は忘れられ、次の呼び出しは新たに実行する
+			ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+			defer cancel2()
+			_, _, _ = g.DoContext(ctx2, "key", fn)
+			if got := calls.Load(); got != 2 {
+				t.Errorf("関数の実行回数が期待値と異なります: got %d, want 2", got)
+			}
+		})
+	})
+
+	t.Run("すべての呼び出し元が戻った後のパニックではプロセスを終了させない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var g singleflight.Group[string, int]
+			fn := func(ctx context.Context) (int, error) {
+				<-ctx.Done()
+				panic("打ち切られた後のパニック")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err, _ := g.DoContext(ctx, "key", fn)
+			// 切り離されたゴルーチンでパニックが発生し、破棄されるのを待つ
+			synctest.Wait()
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("エラーが期待値と異なります: got %v", err)
+			}
+		})
+	})
+
+	t.Run("呼び出し元のコンテキストの値は引き継がれる", func(t *testing.T) {
+		type ctxKey struct{}
+		var g singleflight.Group[string, string]
+		ctx := context.WithValue(context.Background(), ctxKey{}, "テナントA")
+
+		v, _, _ := g.DoContext(ctx, "key", func(ctx context.Context) (string, error) {
+			s, _ := ctx.Value(ctxKey{}).(string)
+			return s, nil
+		})
+
+		if v != "テナントA" {
+			t.Errorf("コンテキストの値が期待値と異なります: got %q", v)
+		}
+	})
+
+	t.Run("終了済みのコンテキストでは実行しない", func(t *testing.T) {
+		var g singleflight.Group[string, int]
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		called := false
+
+		_, err, _ := g.DoContext(ctx, "key", func(context.Context) (int, error) {
+			called = true
+			return 0, nil
+		})
+
+		if !errors.Is(err, context.Canceled) || called {
+			t.Errorf("結果が期待値と異なります: err %v, called %v", err, called)
+		}
+	})
+}
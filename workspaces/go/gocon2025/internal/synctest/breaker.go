@@ -0,0 +1,319 @@
+package synctest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen はサーキットブレーカーが開いているため処理を実行しなかったことを表す
+	ErrCircuitOpen = errors.New("synctest: circuit breaker is open")
+	// ErrTooManyProbes は半開状態で同時に試行できる数を超えたため処理を実行しなかったことを表す
+	ErrTooManyProbes = errors.New("synctest: too many probes in half-open state")
+)
+
+// CircuitState はサーキットブレーカーの状態
+type CircuitState int
+
+const (
+	// CircuitClosed は処理をそのまま実行し、失敗を数えている状態
+	CircuitClosed CircuitState = iota
+	// CircuitOpen は失敗が閾値を超えたため、クールダウンが終わるまで処理を実行しない状態
+	CircuitOpen
+	// CircuitHalfOpen はクールダウン後に限られた数の処理を試行し、復旧したかを確認している状態
+	CircuitHalfOpen
+)
+
+// String はCircuitStateの文字列表現を返す
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig はサーキットブレーカーの設定
+type BreakerConfig struct {
+	// ConsecutiveFailures は連続して失敗するとブレーカーを開く回数。0以下の場合は判定しない
+	ConsecutiveFailures int
+	// FailureRatio は失敗率がこの値以上になるとブレーカーを開く割合(0, 1]。0以下の場合は判定しない
+	FailureRatio float64
+	// MinRequests は失敗率を判定するのに必要な最小の実行回数
+	MinRequests int
+	// Interval は閉じた状態で失敗の数をリセットする間隔。0以下の場合は状態が変わるまでリセットしない
+	Interval time.Duration
+	// Cooldown は開いてから半開状態に移るまでの時間。0以下の場合は1分
+	Cooldown time.Duration
+	// ProbeLimit は半開状態で同時に試行できる数。この数だけ連続して成功するとブレーカーを閉じる。0以下の場合は1
+	ProbeLimit int
+	// IsFailure は処理が返したエラーを失敗として数えるかを判定する
+	// nilの場合はcontext.Canceled以外のエラーを失敗として数える
+	// 失敗として数えないcontext.Canceledは成功としても数えず、実行しなかったものとして扱う
+	IsFailure func(err error) bool
+	// OnStateChange は状態が変わったときに呼び出される
+	// ブレーカーのロックを保持したまま呼び出されるため、ブレーカーのメソッドを呼び出してはならない
+	OnStateChange func(from, to CircuitState)
+}
+
+// BreakerCounts は現在の状態になってから(閉じた状態ではIntervalごとに)数えた実行結果
+// キャンセルで中断した実行は数えない
+type BreakerCounts struct {
+	Requests             int // 実行した回数
+	Successes            int // 成功した回数
+	Failures             int // 失敗した回数
+	ConsecutiveSuccesses int // 連続して成功した回数
+	ConsecutiveFailures  int // 連続して失敗した回数
+}
+
+// CircuitBreaker は失敗が続く処理の実行を一時的に止めるインターフェース
+type CircuitBreaker interface {
+	// Execute はブレーカーが許可した場合に処理を実行し、結果を数える
+	// 開いている場合はErrCircuitOpen、半開状態で試行数を超えた場合はErrTooManyProbesを返し、処理を実行しない
+	Execute(ctx context.Context, fn func(ctx context.Context) error) error
+	// State は現在の状態を返す
+	State() CircuitState
+	// Counts は現在の状態で数えた実行結果を返す
+	Counts() BreakerCounts
+}
+
+// circuitBreaker はCircuitBreakerの具象実装
+// 開いた状態から半開状態への移行は、時刻を参照するメソッドの呼び出し時に行う
+type circuitBreaker struct {
+	options
+	cfg        BreakerConfig
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64 // 状態が変わるごとに増える世代。前の世代で開始した処理の結果は数えない
+	counts     BreakerCounts
+	inFlight   int       // 半開状態で実行中の試行数
+	expiry     time.Time // 閉じた状態では数をリセットする時刻、開いた状態では半開状態に移る時刻
+}
+
+// NewCircuitBreaker はサーキットブレーカーを作成する
+// ConsecutiveFailuresとFailureRatioのどちらも指定しない場合は、5回連続で失敗すると開く
+func NewCircuitBreaker(cfg BreakerConfig, opts ...Option) CircuitBreaker {
+	if cfg.ConsecutiveFailures <= 0 && cfg.FailureRatio <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = time.Minute
+	}
+	cfg.ProbeLimit = max(cfg.ProbeLimit, 1)
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+
+	b := &circuitBreaker{options: newOptions(opts), cfg: cfg}
+	b.resetCounts(b.clock.Now())
+	return b
+}
+
+// outcome はブレーカーが数える実行結果の種類
+type outcome int
+
+const (
+	outcomeSuccess  outcome = iota // 成功として数える
+	outcomeFailure                 // 失敗として数える
+	outcomeCanceled                // どちらとしても数えず、実行枠だけを解放する
+)
+
+// Execute はブレーカーが許可した場合に処理を実行し、結果を数える
+// 処理がパニックした場合は失敗として数えてからパニックを伝播する
+// 失敗として数えないcontext.Canceledで中断した場合は、半開状態の試行枠を解放するだけで成功とも失敗とも数えない
+func (b *circuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	generation, err := b.before()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			b.after(generation, outcomeFailure)
+			panic(v)
+		}
+	}()
+
+	err = fn(ctx)
+	b.after(generation, b.classify(err))
+	return err
+}
+
+// classify は処理が返したエラーを実行結果の種類に分類する
+func (b *circuitBreaker) classify(err error) outcome {
+	switch {
+	case b.cfg.IsFailure(err):
+		return outcomeFailure
+	case errors.Is(err, context.Canceled):
+		return outcomeCanceled
+	default:
+		return outcomeSuccess
+	}
+}
+
+// State は現在の状態を返す
+func (b *circuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.clock.Now())
+	return b.state
+}
+
+// Counts は現在の状態で数えた実行結果を返す
+func (b *circuitBreaker) Counts() BreakerCounts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.clock.Now())
+	return b.counts
+}
+
+// before は処理を実行してよいかを判定し、実行を開始した世代を返す
+func (b *circuitBreaker) before() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.clock.Now())
+
+	switch b.state {
+	case CircuitOpen:
+		return 0, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.inFlight >= b.cfg.ProbeLimit {
+			return 0, ErrTooManyProbes
+		}
+		b.inFlight++
+	}
+	b.counts.Requests++
+	return b.generation, nil
+}
+
+// after は処理の結果を数え、閾値に応じて状態を変える
+func (b *circuitBreaker) after(generation uint64, o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.advance(now)
+	if generation != b.generation {
+		return
+	}
+
+	c := &b.counts
+	if o == outcomeCanceled {
+		c.Requests--
+		if b.state == CircuitHalfOpen {
+			b.inFlight--
+		}
+		return
+	}
+	success := o == outcomeSuccess
+	if success {
+		c.Successes++
+		c.ConsecutiveSuccesses++
+		c.ConsecutiveFailures = 0
+	} else {
+		c.Failures++
+		c.ConsecutiveFailures++
+		c.ConsecutiveSuccesses = 0
+	}
+
+	switch b.state {
+	case CircuitClosed:
+		if !success && b.shouldTrip() {
+			b.setState(now, CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		b.inFlight--
+		switch {
+		case !success:
+			b.setState(now, CircuitOpen)
+		case c.ConsecutiveSuccesses >= b.cfg.ProbeLimit:
+			b.setState(now, CircuitClosed)
+		}
+	}
+}
+
+// shouldTrip は閉じた状態の実行結果が閾値を超えたかを判定する
+func (b *circuitBreaker) shouldTrip() bool {
+	c := b.counts
+	if b.cfg.ConsecutiveFailures > 0 && c.ConsecutiveFailures >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	if b.cfg.FailureRatio > 0 && c.Requests >= b.cfg.MinRequests && c.Requests > 0 {
+		return float64(c.Failures)/float64(c.Requests) >= b.cfg.FailureRatio
+	}
+	return false
+}
+
+// advance は時刻の経過による状態の変化を反映する
+func (b *circuitBreaker) advance(now time.Time) {
+	switch b.state {
+	case CircuitClosed:
+		if !b.expiry.IsZero() && !now.Before(b.expiry) {
+			b.resetCounts(now)
+		}
+	case CircuitOpen:
+		if !now.Before(b.expiry) {
+			b.setState(now, CircuitHalfOpen)
+		}
+	}
+}
+
+// setState は状態を変えて世代を進め、OnStateChangeを呼び出す
+func (b *circuitBreaker) setState(now time.Time, to CircuitState) {
+	from := b.state
+	b.state = to
+	b.inFlight = 0
+	b.resetCounts(now)
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
+
+// resetCounts は数をリセットして世代を進め、現在の状態で次に時刻を確認する時刻を設定する
+func (b *circuitBreaker) resetCounts(now time.Time) {
+	b.generation++
+	b.counts = BreakerCounts{}
+	b.expiry = time.Time{}
+	switch b.state {
+	case CircuitClosed:
+		if b.cfg.Interval > 0 {
+			b.expiry = now.Add(b.cfg.Interval)
+		}
+	case CircuitOpen:
+		b.expiry = now.Add(b.cfg.Cooldown)
+	}
+}
+
+// Guard は処理をbの下で実行する関数を返す。bがnilの場合はfnをそのまま返す
+// RunやRunStreamに渡すと、依存先の障害でブレーカーが開いた後の入力はErrCircuitOpenで即座に失敗する
+func Guard[T, R any](b CircuitBreaker, fn func(context.Context, T) (R, error)) func(context.Context, T) (R, error) {
+	if b == nil {
+		return fn
+	}
+	return func(ctx context.Context, input T) (R, error) {
+		var value R
+		err := b.Execute(ctx, func(ctx context.Context) error {
+			var err error
+			value, err = fn(ctx, input)
+			return err
+		})
+		return value, err
+	}
+}
+
+// WithCircuitBreaker はプロセッサのタスクとポーリングの試行をbの下で実行する
+// ProcessWithGoroutineとProcessWithPoolではタスクの実行を、Poll・ProcessWithPolling・ProcessWithRetryでは判定関数の呼び出しを
+// ブレーカーで保護し、ブレーカーが開いている場合はポーリングをStopReasonCircuitOpenで打ち切る
+func WithCircuitBreaker(b CircuitBreaker) Option {
+	return func(o *options) {
+		o.breaker = b
+	}
+}
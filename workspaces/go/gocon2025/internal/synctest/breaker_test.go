@@ -0,0 +1,430 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// returnErr は指定したエラーを返す処理を作成する
+func returnErr(err error) func(context.Context) error {
+	return func(context.Context) error { return err }
+}
+
+// transitionRecorder はOnStateChangeで受け取った状態の変化を記録する
+type transitionRecorder struct {
+	mu          sync.Mutex
+	transitions []string
+}
+
+func (r *transitionRecorder) record(from, to synctestpkg.CircuitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transitions = append(r.transitions, fmt.Sprintf("%v->%v", from, to))
+}
+
+func (r *transitionRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.transitions)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	errDown := errors.New("依存先が停止しています")
+
+	t.Run("Table Driven Test - ブレーカーが開く条件", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			cfg          synctestpkg.BreakerConfig
+			outcomes     []error
+			expectOpenAt int // 何回目の実行の後に開くか。0の場合は開かない
+		}{
+			{
+				name:         "連続失敗回数",
+				cfg:          synctestpkg.BreakerConfig{ConsecutiveFailures: 3},
+				outcomes:     []error{errDown, errDown, nil, errDown, errDown, errDown},
+				expectOpenAt: 6,
+			},
+			{
+				name:         "失敗率",
+				cfg:          synctestpkg.BreakerConfig{FailureRatio: 0.5, MinRequests: 4},
+				outcomes:     []error{errDown, nil, nil, errDown},
+				expectOpenAt: 4,
+			},
+			{
+				name:     "失敗率が閾値未満",
+				cfg:      synctestpkg.BreakerConfig{FailureRatio: 0.5, MinRequests: 4},
+				outcomes: []error{errDown, nil, nil, nil, errDown, nil},
+			},
+			{
+				name:     "キャンセルは失敗として数えない",
+				cfg:      synctestpkg.BreakerConfig{ConsecutiveFailures: 2},
+				outcomes: []error{context.Canceled, context.Canceled, errDown},
+			},
+			{
+				name: "IsFailureで失敗とするエラーを選べる",
+				cfg: synctestpkg.BreakerConfig{
+					ConsecutiveFailures: 2,
+					IsFailure:           func(err error) bool { return errors.Is(err, errDown) },
+				},
+				outcomes:     []error{context.DeadlineExceeded, errDown, errDown},
+				expectOpenAt: 3,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					b := synctestpkg.NewCircuitBreaker(tc.cfg)
+
+					openAt := 0
+					for i, outcome := range tc.outcomes {
+						if err := b.Execute(context.Background(), returnErr(outcome)); err != outcome {
+							t.Fatalf("%d回目の実行のエラーが期待値と異なります: got %v, want %v", i+1, err, outcome)
+						}
+						if b.State() == synctestpkg.CircuitOpen {
+							openAt = i + 1
+							break
+						}
+					}
+
+					if openAt != tc.expectOpenAt {
+						t.Errorf("ブレーカーが開いた実行が期待値と異なります: got %d, want %d", openAt, tc.expectOpenAt)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("開いてからクールダウン後に試行して閉じる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			recorder := &transitionRecorder{}
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{
+				ConsecutiveFailures: 2,
+				Cooldown:            10 * time.Second,
+				ProbeLimit:          2,
+				OnStateChange:       recorder.record,
+			})
+			for range 2 {
+				_ = b.Execute(context.Background(), returnErr(errDown))
+			}
+
+			// 開いている間は処理を実行しない
+			called := false
+			err := b.Execute(context.Background(), func(context.Context) error {
+				called = true
+				return nil
+			})
+			if !errors.Is(err, synctestpkg.ErrCircuitOpen) || called {
+				t.Fatalf("開いたブレーカーが処理を実行しました: err %v, called %v", err, called)
+			}
+
+			time.Sleep(10*time.Second - time.Nanosecond)
+			if s := b.State(); s != synctestpkg.CircuitOpen {
+				t.Fatalf("クールダウン中の状態が期待値と異なります: got %v", s)
+			}
+			time.Sleep(time.Nanosecond)
+			if s := b.State(); s != synctestpkg.CircuitHalfOpen {
+				t.Fatalf("クールダウン後の状態が期待値と異なります: got %v", s)
+			}
+
+			// 半開状態ではProbeLimitの数まで同時に試行できる
+			var wg sync.WaitGroup
+			for range 2 {
+				wg.Go(func() { _ = b.Execute(context.Background(), sleepTask(time.Second)) })
+			}
+			synctest.Wait()
+			if err := b.Execute(context.Background(), returnErr(nil)); !errors.Is(err, synctestpkg.ErrTooManyProbes) {
+				t.Errorf("試行数を超えた実行のエラーが期待値と異なります: got %v", err)
+			}
+			wg.Wait()
+
+			if s := b.State(); s != synctestpkg.CircuitClosed {
+				t.Errorf("試行が成功した後の状態が期待値と異なります: got %v", s)
+			}
+			want := []string{"closed->open", "open->half-open", "half-open->closed"}
+			if got := recorder.get(); !slices.Equal(got, want) {
+				t.Errorf("状態の変化が期待値と異なります: got %v, want %v", got, want)
+			}
+		})
+	})
+
+	t.Run("半開状態で失敗すると再び開く", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			recorder := &transitionRecorder{}
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{
+				ConsecutiveFailures: 1,
+				Cooldown:            10 * time.Second,
+				OnStateChange:       recorder.record,
+			})
+			_ = b.Execute(context.Background(), returnErr(errDown))
+			time.Sleep(10 * time.Second)
+
+			_ = b.Execute(context.Background(), returnErr(errDown))
+
+			// クールダウンは再び開いた時点から数え直す
+			time.Sleep(5 * time.Second)
+			if s := b.State(); s != synctestpkg.CircuitOpen {
+				t.Errorf("再び開いた後の状態が期待値と異なります: got %v", s)
+			}
+			want := []string{"closed->open", "open->half-open", "half-open->open"}
+			if got := recorder.get(); !slices.Equal(got, want) {
+				t.Errorf("状態の変化が期待値と異なります: got %v, want %v", got, want)
+			}
+		})
+	})
+
+	t.Run("閉じた状態の数はIntervalごとにリセットされる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 2, Interval: time.Minute})
+			_ = b.Execute(context.Background(), returnErr(errDown))
+			time.Sleep(time.Minute)
+
+			_ = b.Execute(context.Background(), returnErr(errDown))
+
+			if s := b.State(); s != synctestpkg.CircuitClosed {
+				t.Errorf("状態が期待値と異なります: got %v", s)
+			}
+			if c := b.Counts(); c.Requests != 1 || c.ConsecutiveFailures != 1 {
+				t.Errorf("リセット後の数が期待値と異なります: %+v", c)
+			}
+		})
+	})
+
+	t.Run("状態が変わる前に開始した処理の結果は数えない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Second})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_ = b.Execute(context.Background(), func(context.Context) error {
+					time.Sleep(2 * time.Second)
+					return errDown
+				})
+			}()
+			synctest.Wait()
+
+			// 遅い処理が終わる前に開き、半開状態を経て閉じる
+			_ = b.Execute(context.Background(), returnErr(errDown))
+			time.Sleep(time.Second)
+			_ = b.Execute(context.Background(), returnErr(nil))
+			<-done
+
+			if s := b.State(); s != synctestpkg.CircuitClosed {
+				t.Errorf("古い処理の失敗で状態が変わりました: got %v", s)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - キャンセルは成功とも失敗とも数えない", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			cfg          synctestpkg.BreakerConfig
+			prepare      func(b synctestpkg.CircuitBreaker) // キャンセルする前の状態を作る
+			expectState  synctestpkg.CircuitState
+			expectCounts synctestpkg.BreakerCounts
+		}{
+			{
+				name: "キャンセルした試行は半開状態のまま枠を解放する",
+				cfg:  synctestpkg.BreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Second},
+				prepare: func(b synctestpkg.CircuitBreaker) {
+					_ = b.Execute(context.Background(), returnErr(errDown))
+					time.Sleep(time.Second)
+				},
+				expectState: synctestpkg.CircuitHalfOpen,
+			},
+			{
+				name: "閉じた状態では連続した失敗をリセットしない",
+				cfg:  synctestpkg.BreakerConfig{ConsecutiveFailures: 3},
+				prepare: func(b synctestpkg.CircuitBreaker) {
+					_ = b.Execute(context.Background(), returnErr(errDown))
+					_ = b.Execute(context.Background(), returnErr(errDown))
+				},
+				expectState:  synctestpkg.CircuitClosed,
+				expectCounts: synctestpkg.BreakerCounts{Requests: 2, Failures: 2, ConsecutiveFailures: 2},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					b := synctestpkg.NewCircuitBreaker(tc.cfg)
+					tc.prepare(b)
+
+					err := b.Execute(context.Background(), returnErr(fmt.Errorf("中断: %w", context.Canceled)))
+
+					if !errors.Is(err, context.Canceled) {
+						t.Errorf("処理のエラーがそのまま返されていません: %v", err)
+					}
+					if s := b.State(); s != tc.expectState {
+						t.Errorf("状態が期待値と異なります: got %v, want %v", s, tc.expectState)
+					}
+					if c := b.Counts(); c != tc.expectCounts {
+						t.Errorf("数が期待値と異なります: got %+v, want %+v", c, tc.expectCounts)
+					}
+					// 解放された枠で次の試行を実行できる
+					if err := b.Execute(context.Background(), returnErr(nil)); err != nil {
+						t.Errorf("キャンセルの後の試行が実行されませんでした: %v", err)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("パニックは失敗として数えてから伝播する", func(t *testing.T) {
+		b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 1})
+
+		func() {
+			defer func() {
+				if v := recover(); v != "壊れた" {
+					t.Errorf("パニックが伝播していません: %v", v)
+				}
+			}()
+			_ = b.Execute(context.Background(), func(context.Context) error { panic("壊れた") })
+		}()
+
+		if s := b.State(); s != synctestpkg.CircuitOpen {
+			t.Errorf("状態が期待値と異なります: got %v", s)
+		}
+	})
+}
+
+func TestCircuitStateString(t *testing.T) {
+	testCases := []struct {
+		state synctestpkg.CircuitState
+		want  string
+	}{
+		{synctestpkg.CircuitClosed, "closed"},
+		{synctestpkg.CircuitOpen, "open"},
+		{synctestpkg.CircuitHalfOpen, "half-open"},
+		{synctestpkg.CircuitState(-1), "unknown"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := tc.state.String(); got != tc.want {
+				t.Errorf("文字列表現が期待値と異なります: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessorsWithCircuitBreaker(t *testing.T) {
+	errDown := errors.New("依存先が停止しています")
+
+	t.Run("Guardしたタスクはブレーカーが開くと実行されない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 3})
+			calls := 0
+			fn := synctestpkg.Guard(b, func(context.Context, int) (int, error) {
+				calls++
+				return 0, errDown
+			})
+
+			var errs []error
+			for r := range synctestpkg.Run(context.Background(), make([]int, 6), fn, synctestpkg.WithWorkerPool(1, 0)) {
+				errs = append(errs, r.Err)
+			}
+
+			if calls != 3 {
+				t.Errorf("処理の実行回数が期待値と異なります: got %d, want 3", calls)
+			}
+			for i, err := range errs {
+				want := errDown
+				if i >= 3 {
+					want = synctestpkg.ErrCircuitOpen
+				}
+				if !errors.Is(err, want) {
+					t.Errorf("%d番目のエラーが期待値と異なります: got %v, want %v", i, err, want)
+				}
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - ポーリングはブレーカーが開くと打ち切られる", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			poll          func(p synctestpkg.TaskProcessor, probe synctestpkg.ProbeFunc) <-chan synctestpkg.PollResult
+			expectElapsed time.Duration
+		}{
+			{
+				name: "Poll",
+				poll: func(p synctestpkg.TaskProcessor, probe synctestpkg.ProbeFunc) <-chan synctestpkg.PollResult {
+					return p.Poll(context.Background(), synctestpkg.PollConfig{Interval: time.Second, MaxAttempts: 10}, probe)
+				},
+				expectElapsed: 4 * time.Second,
+			},
+			{
+				name: "ProcessWithRetry",
+				poll: func(p synctestpkg.TaskProcessor, probe synctestpkg.ProbeFunc) <-chan synctestpkg.PollResult {
+					op := func(ctx context.Context) error {
+						_, err := probe(ctx)
+						return err
+					}
+					return p.ProcessWithRetry(context.Background(), synctestpkg.ConstantBackoff(time.Second), op)
+				},
+				expectElapsed: 3 * time.Second,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 3})
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithCircuitBreaker(b))
+
+					res := <-tc.poll(processor, succeedOn(100, errDown))
+
+					if res.Reason != synctestpkg.StopReasonCircuitOpen {
+						t.Errorf("終了理由が期待値と異なります: got %v", res.Reason)
+					}
+					if res.Attempts != 3 {
+						t.Errorf("試行回数が期待値と異なります: got %d, want 3", res.Attempts)
+					}
+					if res.Elapsed != tc.expectElapsed {
+						t.Errorf("経過時間が期待値と異なります: got %v, want %v", res.Elapsed, tc.expectElapsed)
+					}
+					if !errors.Is(res.LastErr, synctestpkg.ErrCircuitOpen) {
+						t.Errorf("最後のエラーが期待値と異なります: got %v", res.LastErr)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("ProcessWithPollingは開いたブレーカーを共有すると即座に諦める", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Minute})
+			_ = b.Execute(context.Background(), returnErr(errDown))
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithCircuitBreaker(b))
+			start := time.Now()
+
+			res := <-processor.ProcessWithPolling(context.Background(), time.Second, 3)
+			if res.Succeeded {
+				t.Error("開いたブレーカーでポーリングが成功しました")
+			}
+			if res.Reason != synctestpkg.StopReasonCircuitOpen || !errors.Is(res.Err, synctestpkg.ErrCircuitOpen) {
+				t.Errorf("ブレーカーが開いていることで終了することを期待しました: %v, %v", res.Reason, res.Err)
+			}
+			if elapsed := time.Since(start); elapsed != time.Second {
+				t.Errorf("経過時間が期待値と異なります: got %v, want 1s", elapsed)
+			}
+
+			// クールダウン後は試行が成功してブレーカーが閉じる
+			time.Sleep(time.Minute)
+			if res := <-processor.ProcessWithPolling(context.Background(), time.Second, 3); !res.Succeeded || res.Err != nil {
+				t.Errorf("クールダウン後のポーリングが失敗しました: %v", res.Err)
+			}
+			if s := b.State(); s != synctestpkg.CircuitClosed {
+				t.Errorf("状態が期待値と異なります: got %v", s)
+			}
+		})
+	})
+}
@@ -0,0 +1,295 @@
+package synctest
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCheckpointNotFound は再開するジョブのチェックポイントが存在しないことを表す
+	ErrCheckpointNotFound = errors.New("synctest: checkpoint not found")
+	// ErrInvalidJobID はジョブIDがチェックポイントのファイル名として使えないことを表す
+	ErrInvalidJobID = errors.New("synctest: invalid job id")
+)
+
+// Checkpoint はジョブの描画の進捗
+type Checkpoint struct {
+	JobID         string    `json:"job_id"`
+	TotalFrames   int       `json:"total_frames"`
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	LastFrame     int       `json:"last_frame"`               // 受信されたことを確認した最後のフレーム番号
+	RendererState []byte    `json:"renderer_state,omitempty"` // LastFrameを描画した直後のレンダラーの状態
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// checkpointConfig はチェックポイントの設定
+type checkpointConfig struct {
+	dir   string // チェックポイントのファイルを保存するディレクトリ
+	every int    // 何フレームごとに保存するか
+}
+
+// WithCheckpoints はRenderConfig.JobIDを指定したジョブの進捗をdirに保存する
+// everyフレームごとと、ジョブが中断したときに受信されたフレームまでの進捗を保存し、ジョブが完了すると削除する
+// レンダラーがencoding.BinaryMarshalerを実装している場合は、その状態も保存する
+// 状態は描画したフレームと一致している必要があるため、このレンダラーはWithWorkerPoolを指定しても並列には描画しない
+// プロセスが異常終了した場合は最後に保存した位置から再開するため、最大でevery-1フレームを再度描画する
+// everyが0以下の場合は1として扱う
+func WithCheckpoints(dir string, every int) Option {
+	return func(o *options) {
+		o.checkpoints = &checkpointConfig{dir: dir, every: max(every, 1)}
+	}
+}
+
+// ReadCheckpoint はdirに保存されたジョブのチェックポイントを読み込む
+func ReadCheckpoint(dir, jobID string) (Checkpoint, error) {
+	path, err := checkpointPath(dir, jobID)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Checkpoint{}, fmt.Errorf("%w: %s", ErrCheckpointNotFound, jobID)
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return Checkpoint{}, fmt.Errorf("synctest: decode checkpoint %s: %w", jobID, err)
+	}
+	return cp, nil
+}
+
+// writeCheckpoint はチェックポイントを一時ファイルに書き出してから置き換え、置き換えたことをストレージに書き出す
+// 書き出しの途中で異常終了しても、前回のチェックポイントが壊れることはない
+func writeCheckpoint(dir string, cp Checkpoint) error {
+	path, err := checkpointPath(dir, cp.JobID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, cp.JobID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// checkpointPath はジョブのチェックポイントのファイルパスを返す
+func checkpointPath(dir, jobID string) (string, error) {
+	if jobID == "" || strings.ContainsAny(jobID, `/\`) || !filepath.IsLocal(jobID) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidJobID, jobID)
+	}
+	return filepath.Join(dir, jobID+".json"), nil
+}
+
+// savesRendererState はジョブの進捗とともにレンダラーの状態を保存するかどうかを返す
+func savesRendererState(cfg RenderConfig) bool {
+	_, ok := cfg.Renderer.(encoding.BinaryMarshaler)
+	return cfg.JobID != "" && ok
+}
+
+// marshalRendererState はレンダラーがencoding.BinaryMarshalerを実装している場合に状態を返す
+func marshalRendererState(r FrameRenderer) ([]byte, error) {
+	m, ok := r.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, nil
+	}
+	state, err := m.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("synctest: marshal renderer state: %w", err)
+	}
+	return state, nil
+}
+
+// frameBuffer はフレームを送信するチャネルの長さを返す
+// チェックポイントには受信されたフレームまでを記録するため、バッファに残ったまま失われるフレームを作らない
+func frameBuffer(cfg RenderConfig) int {
+	if cfg.JobID != "" {
+		return 0
+	}
+	return 1
+}
+
+// streamFrames はcfg.JobIDを指定した場合は進捗を保存しながら、そうでない場合はそのままフレームを描画して送信し、
+// 送信しなかった最初のフレーム番号を返す
+func (p *videoProcessor) streamFrames(ctx context.Context, cfg RenderConfig, result chan<- Frame) int {
+	if cfg.JobID == "" {
+		return p.renderFrames(ctx, cfg, 1, result)
+	}
+	return p.renderCheckpointed(ctx, cfg, 1, result)
+}
+
+// Resume チェックポイントを保存したジョブを、受信された最後のフレームの次から描画して送信する
+// レンダラーにはWithRendererで指定したものを利用し、encoding.BinaryUnmarshalerを実装している場合は保存した状態を復元する
+// チェックポイントが存在しない場合はErrCheckpointNotFoundを設定したフレームを送信してチャネルを閉じる
+// コンテキストが終了した場合と停止処理中のため開始しなかった場合は、RenderFramesと同様に原因を設定したフレームを最後に送信する
+func (p *videoProcessor) Resume(ctx context.Context, jobID string) <-chan Frame {
+	result := make(chan Frame)
+
+	started := p.spawn(ctx, "Resume: "+jobID, func(ctx context.Context) {
+		defer close(result)
+
+		cfg, first, err := p.resumeConfig(jobID)
+		if err != nil {
+			select {
+			case result <- Frame{Err: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		next := p.renderCheckpointed(ctx, cfg, first, result)
+		sendInterrupted(ctx, cfg, next, result)
+	})
+	if !started {
+		return rejectedFrames(0)
+	}
+
+	return result
+}
+
+// resumeConfig はチェックポイントから描画の設定と再開するフレーム番号を復元する
+func (p *videoProcessor) resumeConfig(jobID string) (RenderConfig, int, error) {
+	if p.checkpoints == nil {
+		return RenderConfig{}, 0, fmt.Errorf("%w: WithCheckpoints is not set", ErrCheckpointNotFound)
+	}
+	cp, err := ReadCheckpoint(p.checkpoints.dir, jobID)
+	if err != nil {
+		return RenderConfig{}, 0, err
+	}
+
+	cfg := p.renderConfig(RenderConfig{
+		Width:       cp.Width,
+		Height:      cp.Height,
+		TotalFrames: cp.TotalFrames,
+		JobID:       cp.JobID,
+	})
+	if u, ok := cfg.Renderer.(encoding.BinaryUnmarshaler); ok && cp.RendererState != nil {
+		if err := u.UnmarshalBinary(cp.RendererState); err != nil {
+			return RenderConfig{}, 0, fmt.Errorf("synctest: restore renderer state: %w", err)
+		}
+	}
+	return cfg, cp.LastFrame + 1, nil
+}
+
+// renderCheckpointed はfirst番目以降のフレームを描画して送信し、受信されたフレームまでの進捗を保存する
+// 受信されなかった最初のフレーム番号を返す。エラーを設定したフレームを送信した場合は総フレーム数の次の番号を返す
+// resultはバッファのないチャネルでなければならない
+// 進捗の保存に失敗した場合は描画を打ち切り、エラーを設定したフレームを送信する
+func (p *videoProcessor) renderCheckpointed(ctx context.Context, cfg RenderConfig, first int, result chan<- Frame) int {
+	cp := Checkpoint{
+		JobID:       cfg.JobID,
+		TotalFrames: cfg.TotalFrames,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		LastFrame:   first - 1,
+	}
+	saved := cp.LastFrame
+	save := func() error {
+		cp.UpdatedAt = p.clock.Now()
+		if err := writeCheckpoint(p.checkpoints.dir, cp); err != nil {
+			return fmt.Errorf("synctest: save checkpoint %s: %w", cp.JobID, err)
+		}
+		saved = cp.LastFrame
+		return nil
+	}
+	// fail はエラーを設定したフレームを送信し、中断したものとしては扱わないように総フレーム数の次の番号を返す
+	fail := func(err error) int {
+		select {
+		case result <- Frame{Index: cp.LastFrame + 1, Err: err}:
+		case <-ctx.Done():
+		}
+		return cfg.TotalFrames + 1
+	}
+
+	// 新しいジョブでは開始時点の進捗を保存し、最初のチェックポイントより前に中断しても再開できるようにする
+	if first == 1 {
+		if err := save(); err != nil {
+			return fail(err)
+		}
+	}
+
+	renderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	frames := make(chan Frame)
+	go func() {
+		defer close(frames)
+		p.renderFrames(renderCtx, cfg, first, frames)
+	}()
+
+	var (
+		err    error
+		failed bool // 描画に失敗したフレームを送信したかどうか
+	)
+	for f := range frames {
+		if renderCtx.Err() != nil {
+			continue
+		}
+		select {
+		case result <- f:
+		case <-renderCtx.Done():
+			continue
+		}
+		if f.Err != nil {
+			failed = true
+			continue
+		}
+		cp.LastFrame = f.Index
+		cp.RendererState = f.state
+		if cp.LastFrame-saved >= p.checkpoints.every {
+			if err = save(); err != nil {
+				cancel()
+			}
+		}
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if cp.LastFrame >= cfg.TotalFrames {
+		path, _ := checkpointPath(p.checkpoints.dir, cp.JobID)
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fail(err)
+		}
+		return cfg.TotalFrames + 1
+	}
+	if cp.LastFrame != saved {
+		if err := save(); err != nil {
+			return fail(err)
+		}
+	}
+	if failed {
+		return cfg.TotalFrames + 1
+	}
+	return cp.LastFrame + 1
+}
@@ -0,0 +1,228 @@
+package synctest_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// countingRenderer は描画したフレーム数を状態として持ち、チェックポイントに保存・復元できるレンダラー
+// 状態が正しく復元されていない場合は、描画するフレーム番号と数が合わずにエラーを返す
+type countingRenderer struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (r *countingRenderer) Render(ctx context.Context, _ *image.RGBA, index, _ int) error {
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.count+1 != index {
+		return fmt.Errorf("%dフレーム描画済みの状態で%dフレーム目を描画しました", r.count, index)
+	}
+	r.count++
+	return nil
+}
+
+func (r *countingRenderer) MarshalBinary() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return binary.AppendUvarint(nil, uint64(r.count)), nil
+}
+
+func (r *countingRenderer) UnmarshalBinary(data []byte) error {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return errors.New("不正な状態です")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count = int(count)
+	return nil
+}
+
+// receiveFrames はn枚のフレームを受信してからcancelを呼び出し、チャネルが閉じられるまでに受信したフレーム番号を返す
+// 中断した場合に最後に送信される、次のフレーム番号とcontext.Canceledを設定したフレームは含めない
+func receiveFrames(t *testing.T, frames <-chan synctestpkg.Frame, n int, cancel context.CancelFunc) []int {
+	t.Helper()
+	var indices []int
+	interrupted := false
+	for f := range frames {
+		if interrupted {
+			t.Errorf("中断したフレームの後に%dフレーム目を受信しました", f.Index)
+		}
+		if errors.Is(f.Err, context.Canceled) {
+			interrupted = true
+			// 中断したフレームの番号は最後に受信したフレームの次になる
+			if len(indices) > 0 && f.Index != indices[len(indices)-1]+1 {
+				t.Errorf("中断したフレームの番号が期待値と異なります: got %d, want %d", f.Index, indices[len(indices)-1]+1)
+			}
+			continue
+		}
+		if f.Err != nil {
+			t.Errorf("%dフレーム目でエラーが発生しました: %v", f.Index, f.Err)
+			continue
+		}
+		indices = append(indices, f.Index)
+		if len(indices) == n {
+			cancel()
+		}
+	}
+	return indices
+}
+
+func TestCheckpoint(t *testing.T) {
+	t.Run("Table Driven Test - 中断したジョブを重複も欠落もなく再開する", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			every       int
+			stopAfter   []int // 何フレーム受信したら中断するか。中断するたびに再開する
+			expectSaved []int // 中断した後にチェックポイントに記録されている最後のフレーム番号
+			opts        []synctestpkg.Option
+		}{
+			{name: "チェックポイントの間で中断", every: 3, stopAfter: []int{4}, expectSaved: []int{4}},
+			{name: "チェックポイントの直後に中断", every: 3, stopAfter: []int{6}, expectSaved: []int{6}},
+			{name: "最初のフレームの前に中断", every: 3, stopAfter: []int{0}, expectSaved: []int{0}},
+			{name: "複数回の中断", every: 2, stopAfter: []int{3, 1, 4}, expectSaved: []int{3, 4, 8}},
+			{
+				// 状態を保存するレンダラーは並列に描画すると状態と最後のフレームが一致しないため、順に描画する
+				name: "ワーカープールを指定した場合", every: 4, stopAfter: []int{5}, expectSaved: []int{5},
+				opts: []synctestpkg.Option{synctestpkg.WithWorkerPool(3, 0)},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					dir := t.TempDir()
+					const total = 10
+					// 中断のたびにプロセスを作り直したのと同じように、新しいレンダラーとプロセッサを使う
+					newProcessor := func() synctestpkg.VideoProcessor {
+						opts := append([]synctestpkg.Option{
+							synctestpkg.WithCheckpoints(dir, tc.every),
+							synctestpkg.WithRenderer(&countingRenderer{}),
+						}, tc.opts...)
+						return synctestpkg.NewVideoProcessor(opts...)
+					}
+
+					ctx, cancel := context.WithCancel(context.Background())
+					if tc.stopAfter[0] == 0 {
+						cancel()
+					}
+					cfg := synctestpkg.RenderConfig{Width: 2, Height: 2, TotalFrames: total, JobID: "job-1"}
+					received := receiveFrames(t, newProcessor().RenderFrames(ctx, cfg), tc.stopAfter[0], cancel)
+
+					for i := range tc.stopAfter {
+						cp, err := synctestpkg.ReadCheckpoint(dir, "job-1")
+						if err != nil {
+							t.Fatalf("%d回目の中断の後にチェックポイントを読み込めません: %v", i+1, err)
+						}
+						if cp.LastFrame != tc.expectSaved[i] || cp.LastFrame != len(received) {
+							t.Errorf("%d回目の中断の後のチェックポイントが期待値と異なります: got %d, want %d (受信済み %d)", i+1, cp.LastFrame, tc.expectSaved[i], len(received))
+						}
+
+						next := total
+						if i+1 < len(tc.stopAfter) {
+							next = tc.stopAfter[i+1]
+						}
+						ctx, cancel := context.WithCancel(context.Background())
+						received = append(received, receiveFrames(t, newProcessor().Resume(ctx, "job-1"), next, cancel)...)
+						cancel()
+					}
+
+					if want := sequence(total); !slices.Equal(received, want) {
+						t.Errorf("受信したフレームが期待値と異なります: got %v, want %v", received, want)
+					}
+					if _, err := synctestpkg.ReadCheckpoint(dir, "job-1"); !errors.Is(err, synctestpkg.ErrCheckpointNotFound) {
+						t.Errorf("完了したジョブのチェックポイントが削除されていません: %v", err)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("一定のフレーム数ごとに進捗を保存する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			dir := t.TempDir()
+			processor := synctestpkg.NewVideoProcessor(
+				synctestpkg.WithCheckpoints(dir, 3),
+				synctestpkg.WithRenderer(&countingRenderer{}),
+			)
+			cfg := synctestpkg.RenderConfig{Width: 2, Height: 2, TotalFrames: 10, JobID: "periodic"}
+
+			var saved []int
+			for f := range processor.RenderFrames(context.Background(), cfg) {
+				synctest.Wait()
+				cp, err := synctestpkg.ReadCheckpoint(dir, "periodic")
+				if err != nil {
+					break
+				}
+				if f.Index%3 == 0 || f.Index == 1 {
+					saved = append(saved, cp.LastFrame)
+				}
+			}
+
+			// 1フレーム目を受信した時点では開始時の0、以降は3フレームごと
+			if want := []int{0, 3, 6, 9}; !slices.Equal(saved, want) {
+				t.Errorf("保存された進捗が期待値と異なります: got %v, want %v", saved, want)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - 再開できない場合", func(t *testing.T) {
+		testCases := []struct {
+			name      string
+			opts      func(dir string) []synctestpkg.Option
+			jobID     string
+			expectErr error
+		}{
+			{
+				name: "チェックポイントがない",
+				opts: func(dir string) []synctestpkg.Option {
+					return []synctestpkg.Option{synctestpkg.WithCheckpoints(dir, 1)}
+				},
+				jobID:     "unknown",
+				expectErr: synctestpkg.ErrCheckpointNotFound,
+			},
+			{
+				name:      "WithCheckpointsを指定していない",
+				opts:      func(string) []synctestpkg.Option { return nil },
+				jobID:     "job-1",
+				expectErr: synctestpkg.ErrCheckpointNotFound,
+			},
+			{
+				name: "ファイル名に使えないジョブID",
+				opts: func(dir string) []synctestpkg.Option {
+					return []synctestpkg.Option{synctestpkg.WithCheckpoints(dir, 1)}
+				},
+				jobID:     "../escape",
+				expectErr: synctestpkg.ErrInvalidJobID,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				processor := synctestpkg.NewVideoProcessor(tc.opts(t.TempDir())...)
+
+				frames := collectFrames(processor.Resume(context.Background(), tc.jobID))
+
+				if len(frames) != 1 || !errors.Is(frames[0].Err, tc.expectErr) {
+					t.Errorf("エラーのフレームを1つ受信することを期待しました: %+v", frames)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package synctest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock は時刻の取得と待機を抽象化するインターフェース
+// プロセッサはtimeパッケージを直接呼ばずにClockを経由することで、
+// testing/synctestのバブル外でも決定的にテストできる
+type Clock interface {
+	// Now は現在時刻を返す
+	Now() time.Time
+	// After は指定時間経過後に現在時刻を送信するチャネルを返す
+	After(d time.Duration) <-chan time.Time
+	// NewTicker は指定間隔で時刻を送信するTickerを作成する
+	NewTicker(d time.Duration) Ticker
+	// NewTimer は指定時間経過後に一度だけ時刻を送信するTimerを作成する
+	NewTimer(d time.Duration) Timer
+	// Sleep は指定時間だけ現在のゴルーチンを停止する
+	Sleep(d time.Duration)
+}
+
+// Ticker はtime.Tickerを抽象化するインターフェース
+type Ticker interface {
+	// C は時刻を受信するチャネルを返す
+	C() <-chan time.Time
+	// Stop はTickerを停止する
+	Stop()
+	// Reset は間隔を変更してTickerを再開する
+	Reset(d time.Duration)
+}
+
+// Timer はtime.Timerを抽象化するインターフェース
+type Timer interface {
+	// C は時刻を受信するチャネルを返す
+	C() <-chan time.Time
+	// Stop はTimerを停止する。発火前に停止できた場合はtrueを返す
+	Stop() bool
+	// Reset は指定時間後に発火するようにTimerを再設定する。発火前だった場合はtrueを返す
+	Reset(d time.Duration) bool
+}
+
+// realClock はtimeパッケージに委譲するClockの具象実装
+type realClock struct{}
+
+// NewRealClock はシステムクロックを利用するClockを作成する
+// testing/synctestのバブル内ではバブルの仮想クロックに従う
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+// Now は現在時刻を返す
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After は指定時間経過後に現在時刻を送信するチャネルを返す
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTicker は指定間隔で時刻を送信するTickerを作成する
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+// NewTimer は指定時間経過後に一度だけ時刻を送信するTimerを作成する
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+// Sleep は指定時間だけ現在のゴルーチンを停止する
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// realTicker はtime.TickerをラップするTickerの具象実装
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time   { return t.ticker.C }
+func (t *realTicker) Stop()                 { t.ticker.Stop() }
+func (t *realTicker) Reset(d time.Duration) { t.ticker.Reset(d) }
+
+// realTimer はtime.TimerをラップするTimerの具象実装
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time        { return t.timer.C }
+func (t *realTimer) Stop() bool                 { return t.timer.Stop() }
+func (t *realTimer) Reset(d time.Duration) bool { return t.timer.Reset(d) }
+
+// FakeClock は手動で時刻を進めるテスト用のClock
+type FakeClock interface {
+	Clock
+	// Advance は時刻を指定時間だけ進め、期限を迎えたTimer/Tickerを発火させる
+	Advance(d time.Duration)
+	// Set は時刻を指定時刻まで進める。過去の時刻を指定した場合は何もしない
+	Set(t time.Time)
+	// Waiters は発火待ちのTimer/Tickerの数を返す
+	Waiters() int
+	// BlockUntil は発火待ちのTimer/Tickerがn個以上になるまで待機する
+	BlockUntil(n int)
+}
+
+// fakeClock はFakeClockの具象実装
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter はfakeClockに登録された発火待ちのTimer/Ticker
+type fakeWaiter struct {
+	clock  *fakeClock
+	ch     chan time.Time
+	when   time.Time
+	period time.Duration // 0より大きい場合はTickerとして繰り返し発火する
+}
+
+// NewFakeClock は指定時刻から始まるFakeClockを作成する
+func NewFakeClock(start time.Time) FakeClock {
+	c := &fakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now は現在時刻を返す
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After は指定時間経過後に現在時刻を送信するチャネルを返す
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTicker は指定間隔で時刻を送信するTickerを作成する
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("synctest: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1), period: d}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w.when = c.now.Add(d)
+	c.addLocked(w)
+	return &fakeTicker{waiter: w}
+}
+
+// NewTimer は指定時間経過後に一度だけ時刻を送信するTimerを作成する
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scheduleLocked(w, d)
+	return &fakeTimer{waiter: w}
+}
+
+// Sleep は時刻が指定時間だけ進められるまで現在のゴルーチンを停止する
+func (c *fakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance は時刻を指定時間だけ進め、期限を迎えたTimer/Tickerを発火させる
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceLocked(c.now.Add(d))
+}
+
+// Set は時刻を指定時刻まで進める。過去の時刻を指定した場合は何もしない
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.Before(c.now) {
+		return
+	}
+	c.advanceLocked(t)
+}
+
+// Waiters は発火待ちのTimer/Tickerの数を返す
+func (c *fakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil は発火待ちのTimer/Tickerがn個以上になるまで待機する
+func (c *fakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// advanceLocked は期限順にwaiterを発火させながら時刻をtargetまで進める
+func (c *fakeClock) advanceLocked(target time.Time) {
+	for len(c.waiters) > 0 && !c.waiters[0].when.After(target) {
+		w := c.waiters[0]
+		c.waiters = c.waiters[1:]
+		c.now = w.when
+		w.fire(c.now)
+		if w.period > 0 {
+			w.when = w.when.Add(w.period)
+			c.addLocked(w)
+		}
+	}
+	c.now = target
+}
+
+// scheduleLocked はwaiterをd後に発火するよう登録する。dが0以下なら即座に発火する
+func (c *fakeClock) scheduleLocked(w *fakeWaiter, d time.Duration) {
+	if d <= 0 {
+		w.when = c.now
+		w.fire(c.now)
+		return
+	}
+	w.when = c.now.Add(d)
+	c.addLocked(w)
+}
+
+// addLocked はwaiterを期限順を保って登録する
+func (c *fakeClock) addLocked(w *fakeWaiter) {
+	i := sort.Search(len(c.waiters), func(i int) bool {
+		return c.waiters[i].when.After(w.when)
+	})
+	c.waiters = append(c.waiters, nil)
+	copy(c.waiters[i+1:], c.waiters[i:])
+	c.waiters[i] = w
+	c.cond.Broadcast()
+}
+
+// removeLocked はwaiterの登録を解除し、登録されていた場合はtrueを返す
+func (c *fakeClock) removeLocked(w *fakeWaiter) bool {
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fire はチャネルに時刻を送信する。受信されていない値がある場合はtime.Tickerと同様に破棄する
+func (w *fakeWaiter) fire(now time.Time) {
+	select {
+	case w.ch <- now:
+	default:
+	}
+}
+
+// fakeTimer はfakeClock上のTimer
+type fakeTimer struct {
+	waiter *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.waiter.ch }
+
+func (t *fakeTimer) Stop() bool {
+	c := t.waiter.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.removeLocked(t.waiter)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.waiter.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	active := c.removeLocked(t.waiter)
+	c.scheduleLocked(t.waiter, d)
+	return active
+}
+
+// fakeTicker はfakeClock上のTicker
+type fakeTicker struct {
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.waiter.ch }
+
+func (t *fakeTicker) Stop() {
+	c := t.waiter.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(t.waiter)
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("synctest: non-positive interval for Ticker.Reset")
+	}
+	c := t.waiter.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(t.waiter)
+	t.waiter.period = d
+	t.waiter.when = c.now.Add(d)
+	c.addLocked(t.waiter)
+}
@@ -0,0 +1,254 @@
+package synctest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// FakeClockTest は FakeClock を使ったテストに必要なデータと設定を管理する
+type FakeClockTest struct {
+	clock synctestpkg.FakeClock
+	start time.Time
+}
+
+func TestFakeClock(t *testing.T) {
+	setup := func(t *testing.T) *FakeClockTest {
+		t.Helper()
+		start := time.Date(2025, 9, 27, 10, 0, 0, 0, time.UTC)
+		return &FakeClockTest{
+			clock: synctestpkg.NewFakeClock(start),
+			start: start,
+		}
+	}
+
+	t.Run("時刻操作", func(t *testing.T) {
+		t.Run("Advanceで現在時刻が進む", func(t *testing.T) {
+			test := setup(t)
+
+			test.clock.Advance(90 * time.Second)
+
+			if got, want := test.clock.Now(), test.start.Add(90*time.Second); !got.Equal(want) {
+				t.Errorf("現在時刻が期待値と異なります: got %v, want %v", got, want)
+			}
+		})
+
+		t.Run("Setに過去の時刻を指定しても巻き戻らない", func(t *testing.T) {
+			test := setup(t)
+
+			test.clock.Set(test.start.Add(-time.Hour))
+
+			if got := test.clock.Now(); !got.Equal(test.start) {
+				t.Errorf("時刻が巻き戻りました: got %v, want %v", got, test.start)
+			}
+		})
+	})
+
+	t.Run("Timer", func(t *testing.T) {
+		t.Run("Table Driven Test - 期限前後での発火", func(t *testing.T) {
+			testCases := []struct {
+				name      string
+				delay     time.Duration
+				advance   time.Duration
+				expectHit bool
+			}{
+				{name: "ゼロ遅延は即座に発火する", delay: 0, advance: 0, expectHit: true},
+				{name: "負の遅延は即座に発火する", delay: -time.Second, advance: 0, expectHit: true},
+				{name: "期限前は発火しない", delay: time.Second, advance: 999 * time.Millisecond, expectHit: false},
+				{name: "期限ちょうどで発火する", delay: time.Second, advance: time.Second, expectHit: true},
+				{name: "期限後に発火する", delay: time.Second, advance: time.Minute, expectHit: true},
+			}
+
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					test := setup(t)
+
+					ch := test.clock.After(tc.delay)
+					test.clock.Advance(tc.advance)
+
+					select {
+					case at := <-ch:
+						if !tc.expectHit {
+							t.Errorf("期限前に発火しました: %v", at)
+						}
+						if want := test.start.Add(max(tc.delay, 0)); !at.Equal(want) {
+							t.Errorf("発火時刻が期待値と異なります: got %v, want %v", at, want)
+						}
+					default:
+						if tc.expectHit {
+							t.Error("期限を迎えたのに発火していません")
+						}
+					}
+				})
+			}
+		})
+
+		t.Run("停止したTimerは発火しない", func(t *testing.T) {
+			test := setup(t)
+			timer := test.clock.NewTimer(time.Second)
+
+			if !timer.Stop() {
+				t.Error("発火前のStopはtrueを返すべきです")
+			}
+			test.clock.Advance(time.Minute)
+
+			select {
+			case <-timer.C():
+				t.Error("停止したTimerが発火しました")
+			default:
+			}
+			if n := test.clock.Waiters(); n != 0 {
+				t.Errorf("発火待ちが残っています: got %d, want 0", n)
+			}
+		})
+
+		t.Run("Resetで期限を延長できる", func(t *testing.T) {
+			test := setup(t)
+			timer := test.clock.NewTimer(time.Second)
+
+			test.clock.Advance(500 * time.Millisecond)
+			if !timer.Reset(time.Second) {
+				t.Error("発火前のResetはtrueを返すべきです")
+			}
+			test.clock.Advance(700 * time.Millisecond)
+
+			select {
+			case <-timer.C():
+				t.Error("延長前の期限で発火しました")
+			default:
+			}
+
+			test.clock.Advance(300 * time.Millisecond)
+			select {
+			case <-timer.C():
+			default:
+				t.Error("延長後の期限で発火していません")
+			}
+		})
+	})
+
+	t.Run("Ticker", func(t *testing.T) {
+		t.Run("間隔ごとに発火し、未受信の値は破棄される", func(t *testing.T) {
+			test := setup(t)
+			ticker := test.clock.NewTicker(100 * time.Millisecond)
+			defer ticker.Stop()
+
+			test.clock.Advance(350 * time.Millisecond)
+
+			// time.Tickerと同様にバッファは1つだけ
+			at := <-ticker.C()
+			if want := test.start.Add(100 * time.Millisecond); !at.Equal(want) {
+				t.Errorf("最初の発火時刻が期待値と異なります: got %v, want %v", at, want)
+			}
+			select {
+			case extra := <-ticker.C():
+				t.Errorf("未受信の値が破棄されていません: %v", extra)
+			default:
+			}
+
+			test.clock.Advance(50 * time.Millisecond)
+			if at := <-ticker.C(); !at.Equal(test.start.Add(400 * time.Millisecond)) {
+				t.Errorf("次の発火時刻が期待値と異なります: got %v", at)
+			}
+		})
+	})
+
+	t.Run("BlockUntilで発火待ちの登録を待機できる", func(t *testing.T) {
+		test := setup(t)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			test.clock.Sleep(time.Second)
+		}()
+
+		test.clock.BlockUntil(1)
+		test.clock.Advance(time.Second)
+		<-done
+	})
+}
+
+func TestProcessorsWithFakeClock(t *testing.T) {
+	setup := func(t *testing.T) (synctestpkg.FakeClock, synctestpkg.TaskProcessor, synctestpkg.VideoProcessor) {
+		t.Helper()
+		clock := synctestpkg.NewFakeClock(time.Date(2025, 9, 27, 10, 0, 0, 0, time.UTC))
+		return clock,
+			synctestpkg.NewTaskProcessor(synctestpkg.WithClock(clock)),
+			synctestpkg.NewVideoProcessor(synctestpkg.WithClock(clock))
+	}
+
+	t.Run("遅延処理は時刻を進めるまで完了しない", func(t *testing.T) {
+		clock, processor, _ := setup(t)
+
+		result := processor.ProcessWithDelay(context.Background(), 30*time.Second, "偽クロック")
+		clock.BlockUntil(1)
+
+		clock.Advance(29 * time.Second)
+		select {
+		case got := <-result:
+			t.Fatalf("期限前に完了しました: %q", got.Value)
+		default:
+		}
+
+		clock.Advance(time.Second)
+		if got, want := <-result, "処理完了: 偽クロック"; got.Value != want || got.Err != nil {
+			t.Errorf("結果が期待値と異なります: got %q, %v, want %q", got.Value, got.Err, want)
+		}
+	})
+
+	t.Run("ポーリングは3回目のTickで成功する", func(t *testing.T) {
+		clock, processor, _ := setup(t)
+
+		result := processor.ProcessWithPolling(context.Background(), time.Second, 5)
+		clock.BlockUntil(1)
+
+		// Tickの受信はクロックと非同期なので、結果が届くまで1秒ずつ進める
+		start := clock.Now()
+		for {
+			select {
+			case res := <-result:
+				if !res.Succeeded {
+					t.Errorf("ポーリングが成功していません: %v", res.Err)
+				}
+				if elapsed := clock.Now().Sub(start); elapsed < 3*time.Second {
+					t.Errorf("3回目のTickより前に成功しました: elapsed %v", elapsed)
+				}
+				return
+			default:
+				clock.Advance(time.Second)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	})
+
+	t.Run("ゴルーチン処理は全タスクが同時に完了する", func(t *testing.T) {
+		clock, processor, _ := setup(t)
+		tasks := []string{"タスク1", "タスク2", "タスク3"}
+
+		result := processor.ProcessWithGoroutine(context.Background(), tasks)
+		clock.BlockUntil(len(tasks))
+		clock.Advance(100 * time.Millisecond)
+
+		for range tasks {
+			<-result
+		}
+	})
+
+	t.Run("フレームは1つずつ生成される", func(t *testing.T) {
+		clock, _, processor := setup(t)
+
+		result := processor.GenerateFrames(context.Background(), 3)
+		for want := 1; want <= 3; want++ {
+			clock.BlockUntil(1)
+			clock.Advance(50 * time.Millisecond)
+			if got := <-result; got.Index != want || got.Err != nil {
+				t.Errorf("フレームが期待値と異なります: got %d, %v, want %d", got.Index, got.Err, want)
+			}
+		}
+		if _, ok := <-result; ok {
+			t.Error("すべてのフレーム生成後にチャネルが閉じられていません")
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package synctest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCronSpec はcron式を解析できなかったことを表す
+var ErrInvalidCronSpec = errors.New("synctest: invalid cron spec")
+
+// Schedule は次の実行時刻を決定するインターフェース
+type Schedule interface {
+	// Next はafterより後の最初の実行時刻を返す。実行時刻が存在しない場合はゼロ値を返す
+	Next(after time.Time) time.Time
+}
+
+// cronDescriptors は@で始まる定義済みのスケジュール
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField はcron式の1フィールドの定義
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 曜日は0と7のどちらも日曜日として扱う
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronSchedule は5フィールドのcron式によるSchedule
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // 各フィールドで許可される値のビット集合
+	domStar, dowStar              bool   // 日と曜日が*で指定されたかどうか
+	loc                           *time.Location
+}
+
+// everySchedule は一定間隔で実行する@everyのSchedule
+type everySchedule struct {
+	interval time.Duration
+}
+
+// ParseCron は5フィールドのcron式または@every <duration>などの記述子を解析する
+// 時刻はafterに渡された時刻のロケーションで評価する
+// Vixie cronと同様に、*で始まる日と曜日の指定（*/2など）は*と同じく日と曜日の両方に一致する場合だけ実行し、
+// mon-sunのように日曜日で終わる曜日の範囲は日曜日を7として扱う
+func ParseCron(spec string) (Schedule, error) {
+	return ParseCronInLocation(spec, nil)
+}
+
+// ParseCronInLocation はlocのロケーションで時刻を評価するcron式を解析する
+// locがnilの場合はNextに渡された時刻のロケーションで評価する
+func ParseCronInLocation(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidCronSpec, spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%w %q: interval must be positive", ErrInvalidCronSpec, spec)
+		}
+		return everySchedule{interval: d}, nil
+	}
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("%w %q: unknown descriptor", ErrInvalidCronSpec, spec)
+		}
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w %q: expected 5 fields, got %d", ErrInvalidCronSpec, spec, len(fields))
+	}
+
+	s := &cronSchedule{
+		domStar: isCronStar(fields[2]),
+		dowStar: isCronStar(fields[4]),
+		loc:     loc,
+	}
+	targets := []struct {
+		field cronField
+		bits  *uint64
+	}{
+		{minuteField, &s.minute},
+		{hourField, &s.hour},
+		{domField, &s.dom},
+		{monthField, &s.month},
+		{dowField, &s.dow},
+	}
+	for i, target := range targets {
+		bits, err := parseCronField(fields[i], target.field)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidCronSpec, spec, err)
+		}
+		*target.bits = bits
+	}
+	// 7は日曜日の別名
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	return s, nil
+}
+
+// isCronStar は日や曜日の指定が*で始まるかどうかを返す
+func isCronStar(expr string) bool {
+	return strings.HasPrefix(expr, "*") || strings.HasPrefix(expr, "?")
+}
+
+// parseCronField はカンマ区切りの指定を解析してビット集合を返す
+func parseCronField(expr string, f cronField) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(expr, ",") {
+		b, err := parseCronRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseCronRange は*、a、a-b、およびそれらに/stepを付けた指定を解析する
+func parseCronRange(expr string, f cronField) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(expr, "/")
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepPart)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%s: invalid step %q", f.name, stepPart)
+		}
+		step = n
+	}
+
+	var lo, hi int
+	switch {
+	case rangePart == "*" || rangePart == "?":
+		lo, hi = f.min, f.max
+		if f.name == dowField.name {
+			hi = 6
+		}
+	case strings.Contains(rangePart, "-"):
+		a, b, _ := strings.Cut(rangePart, "-")
+		var err error
+		if lo, err = f.value(a); err != nil {
+			return 0, err
+		}
+		if hi, err = f.value(b); err != nil {
+			return 0, err
+		}
+		// 日曜日で終わる曜日の範囲は、日曜日を7として扱う
+		if f.name == dowField.name && hi == 0 {
+			hi = 7
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("%s: range %q is reversed", f.name, rangePart)
+		}
+	default:
+		v, err := f.value(rangePart)
+		if err != nil {
+			return 0, err
+		}
+		lo, hi = v, v
+		// a/stepはaから最大値までをstep刻みで指定する
+		if hasStep {
+			hi = f.max
+		}
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+// value は数値または名前で指定された値を解析する
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid value %q", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: value %d out of range [%d, %d]", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// cronSearchLimit は次の実行時刻を探索する期間の上限
+// 2月29日のような指定でも見つかるように閏年の周期より長くとる
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// Next はafterより後の最初の実行時刻を返す
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	origLoc := after.Location()
+	if s.loc != nil {
+		after = after.In(s.loc)
+	}
+	loc := after.Location()
+
+	// 分単位に切り上げる
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := after.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t.In(origLoc)
+	}
+	return time.Time{}
+}
+
+// dayMatches は日と曜日の指定に一致するかを判定する
+// 両方が*以外で指定された場合は、標準的なcronと同様にどちらか一方に一致すればよい
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.domStar && !s.dowStar {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+// Next はafterから間隔だけ経過した時刻を返す
+func (s everySchedule) Next(after time.Time) time.Time {
+	return after.Add(s.interval)
+}
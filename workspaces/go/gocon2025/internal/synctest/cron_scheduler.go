@@ -0,0 +1,363 @@
+package synctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateCronJob は同じ名前のジョブが既に登録されていることを表す
+var ErrDuplicateCronJob = errors.New("synctest: duplicate cron job")
+
+// OverlapPolicy は前回の実行が終わる前に次の実行時刻を迎えた場合の方針
+type OverlapPolicy int
+
+const (
+	// OverlapSkip は前回の実行中であれば今回の実行を見送る
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue は前回の実行が終わるのを待ってから今回の実行を行う
+	OverlapQueue
+	// OverlapConcurrent は前回の実行と並行して今回の実行を行う
+	OverlapConcurrent
+)
+
+// MissedFirePolicy は一時停止やクロックの飛びで実行時刻を逃した場合の方針
+type MissedFirePolicy int
+
+const (
+	// MissedFireRunOnce は逃した実行をまとめて1回だけ実行する
+	MissedFireRunOnce MissedFirePolicy = iota
+	// MissedFireRunAll は逃した実行をすべて実行する
+	MissedFireRunAll
+	// MissedFireSkip は逃した実行をすべて見送り、次の実行時刻を待つ
+	MissedFireSkip
+)
+
+// CronRunStatus はジョブの実行時刻ごとの結果の種類
+type CronRunStatus int
+
+const (
+	// CronRunCompleted はジョブを実行したことを表す
+	CronRunCompleted CronRunStatus = iota
+	// CronRunSkippedOverlap は前回の実行中だったため見送ったことを表す
+	CronRunSkippedOverlap
+	// CronRunSkippedMissed は実行時刻を逃したため見送ったことを表す
+	CronRunSkippedMissed
+)
+
+// String はCronRunStatusの文字列表現を返す
+func (s CronRunStatus) String() string {
+	switch s {
+	case CronRunCompleted:
+		return "completed"
+	case CronRunSkippedOverlap:
+		return "skipped (overlap)"
+	case CronRunSkippedMissed:
+		return "skipped (missed)"
+	default:
+		return "unknown"
+	}
+}
+
+// CronJob は定期実行するジョブの定義
+type CronJob struct {
+	Name     string                          // ジョブ名
+	Spec     string                          // cron式または@every <duration>などの記述子
+	Run      func(ctx context.Context) error // 実行する処理
+	Overlap  OverlapPolicy                   // 実行が重なった場合の方針
+	Missed   MissedFirePolicy                // 実行時刻を逃した場合の方針
+	Location *time.Location                  // cron式を評価するロケーション。nilの場合はtime.Local
+}
+
+// CronRun はジョブの実行時刻ごとの結果
+type CronRun struct {
+	Job         string        // ジョブ名
+	ScheduledAt time.Time     // 本来の実行時刻
+	StartedAt   time.Time     // 処理を開始した時刻。見送った場合はゼロ値
+	FinishedAt  time.Time     // 処理を完了した時刻。見送った場合はゼロ値
+	Status      CronRunStatus // 結果の種類
+	Err         error         // 処理が返したエラー。パニックした場合は*PanicError
+}
+
+// CronScheduler はcron式に従ってジョブを定期実行するインターフェース
+type CronScheduler interface {
+	// Add はジョブを登録する。cron式が不正な場合はErrInvalidCronSpecを返す
+	Add(job CronJob) error
+	// Start はジョブの定期実行を開始し、実行結果を受信するチャネルを返す
+	// ProcessWithDelayと同様にctxが終了すると新しい実行を止め、実行中のジョブの終了を待ってチャネルを閉じる
+	// 2回目以降の呼び出しは実行を重ねて開始せず、ctxを無視して最初の呼び出しと同じチャネルを返す
+	Start(ctx context.Context) <-chan CronRun
+	// Pause は実行を一時停止する。停止中に迎えた実行時刻はResume時にMissedFirePolicyに従って扱う
+	// OverlapQueueで前回の終了を待っている実行も、再開するまで開始しない
+	Pause()
+	// Resume は一時停止した実行を再開する
+	Resume()
+}
+
+// cronScheduler はCronSchedulerの具象実装
+type cronScheduler struct {
+	options
+	startOnce sync.Once
+	runs      chan CronRun // Startが返すチャネル
+
+	mu      sync.Mutex
+	entries []*cronEntry
+	paused  bool
+	changed chan struct{} // ジョブの登録や一時停止の状態が変わったことをループに通知する
+}
+
+// cronEntry は登録されたジョブと実行状態
+type cronEntry struct {
+	job      CronJob
+	schedule Schedule
+	next     time.Time   // 次の実行時刻
+	running  int         // 実行中の数
+	queued   []time.Time // OverlapQueueで実行を待っている実行時刻
+}
+
+// cronDone はジョブの実行が終わったことをスケジューラのループに伝える
+type cronDone struct {
+	entry *cronEntry
+}
+
+// NewCronScheduler はcron式に従ってジョブを定期実行するスケジューラを作成する
+func NewCronScheduler(opts ...Option) CronScheduler {
+	return &cronScheduler{
+		options: newOptions(opts),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Add はジョブを登録する
+func (s *cronScheduler) Add(job CronJob) error {
+	loc := job.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	schedule, err := ParseCronInLocation(job.Spec, loc)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.job.Name == job.Name {
+			return fmt.Errorf("%w: %q", ErrDuplicateCronJob, job.Name)
+		}
+	}
+	s.entries = append(s.entries, &cronEntry{job: job, schedule: schedule})
+	s.notify()
+	return nil
+}
+
+// Pause は実行を一時停止する
+func (s *cronScheduler) Pause() {
+	s.setPaused(true)
+}
+
+// Resume は一時停止した実行を再開する
+func (s *cronScheduler) Resume() {
+	s.setPaused(false)
+}
+
+// setPaused は一時停止の状態を変更してループに通知する
+func (s *cronScheduler) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+	s.notify()
+}
+
+// notify は状態の変化をループに通知する。呼び出し側でmuをロックしておく
+func (s *cronScheduler) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Start はジョブの定期実行を開始する
+func (s *cronScheduler) Start(ctx context.Context) <-chan CronRun {
+	s.startOnce.Do(func() {
+		s.runs = make(chan CronRun, 16)
+		go s.loop(ctx, s.runs)
+	})
+	return s.runs
+}
+
+// loop は次の実行時刻まで待機してジョブを起動する
+// ジョブの実行状態はこのゴルーチンだけが変更する
+func (s *cronScheduler) loop(ctx context.Context, runs chan<- CronRun) {
+	var wg sync.WaitGroup
+	done := make(chan cronDone)
+	s.mu.Lock()
+	paused := s.paused
+	s.mu.Unlock()
+
+	defer func() {
+		// 実行中のジョブの終了を待ってからチャネルを閉じる
+		wg.Wait()
+		close(runs)
+	}()
+
+	// キャンセル後の結果は送信しない
+	emit := func(r CronRun) {
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case runs <- r:
+		case <-ctx.Done():
+		}
+	}
+
+	start := func(e *cronEntry, scheduledAt time.Time) {
+		e.running++
+		wg.Go(func() {
+			r := CronRun{Job: e.job.Name, ScheduledAt: scheduledAt, StartedAt: s.clock.Now()}
+			r.Err = runCronJob(ctx, e.job.Run)
+			r.FinishedAt = s.clock.Now()
+			emit(r)
+			select {
+			case done <- cronDone{entry: e}:
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	fire := func(e *cronEntry, scheduledAt time.Time) {
+		if e.running == 0 || e.job.Overlap == OverlapConcurrent {
+			start(e, scheduledAt)
+			return
+		}
+		if e.job.Overlap == OverlapQueue {
+			e.queued = append(e.queued, scheduledAt)
+			return
+		}
+		emit(CronRun{Job: e.job.Name, ScheduledAt: scheduledAt, Status: CronRunSkippedOverlap})
+	}
+
+	// startQueued はOverlapQueueで待っている実行を、一時停止中でなく前回の実行が終わっていれば開始する
+	startQueued := func(e *cronEntry) {
+		if paused || e.running > 0 || len(e.queued) == 0 {
+			return
+		}
+		at := e.queued[0]
+		e.queued = e.queued[1:]
+		start(e, at)
+	}
+
+	// process は期限を迎えた実行時刻をMissedFirePolicyに従って処理する
+	// resumedは一時停止からの再開直後であることを表す
+	process := func(now time.Time, resumed bool) {
+		for _, e := range s.snapshot() {
+			if e.next.IsZero() {
+				e.next = e.schedule.Next(now)
+				continue
+			}
+			var due []time.Time
+			for !e.next.IsZero() && !e.next.After(now) {
+				due = append(due, e.next)
+				e.next = e.schedule.Next(e.next)
+			}
+			if len(due) == 0 {
+				continue
+			}
+
+			missed, latest := due[:len(due)-1], due[len(due)-1]
+			switch e.job.Missed {
+			case MissedFireRunAll:
+				for _, at := range due {
+					fire(e, at)
+				}
+				continue
+			case MissedFireSkip:
+				if resumed {
+					missed = due
+				}
+			}
+			for _, at := range missed {
+				emit(CronRun{Job: e.job.Name, ScheduledAt: at, Status: CronRunSkippedMissed})
+			}
+			if len(missed) < len(due) {
+				fire(e, latest)
+			}
+		}
+	}
+
+	if !paused {
+		process(s.clock.Now(), false)
+	}
+	for {
+		var timer Timer
+		var fired <-chan time.Time
+		if !paused {
+			if next, ok := s.earliest(); ok {
+				timer = s.clock.NewTimer(next.Sub(s.clock.Now()))
+				fired = timer.C()
+			}
+		}
+
+		select {
+		case <-fired:
+			process(s.clock.Now(), false)
+		case <-s.changed:
+			s.mu.Lock()
+			wasPaused := paused
+			paused = s.paused
+			s.mu.Unlock()
+			if !paused {
+				for _, e := range s.snapshot() {
+					startQueued(e)
+				}
+				process(s.clock.Now(), wasPaused)
+			}
+		case d := <-done:
+			d.entry.running--
+			startQueued(d.entry)
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// snapshot は登録されたジョブの一覧を返す
+func (s *cronScheduler) snapshot() []*cronEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*cronEntry(nil), s.entries...)
+}
+
+// earliest は最も早い次の実行時刻を返す
+func (s *cronScheduler) earliest() (time.Time, bool) {
+	var earliest time.Time
+	for _, e := range s.snapshot() {
+		if e.next.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || e.next.Before(earliest) {
+			earliest = e.next
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// runCronJob はジョブを1回実行し、パニックをエラーとして返す
+func runCronJob(ctx context.Context, run func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return run(ctx)
+}
@@ -0,0 +1,327 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// describeCronRuns は実行結果を本来の実行時刻の順に並べ、baseからの経過時間で表した文字列にする
+func describeCronRuns(base time.Time, runs <-chan synctestpkg.CronRun) []string {
+	var collected []synctestpkg.CronRun
+	for r := range runs {
+		collected = append(collected, r)
+	}
+	slices.SortStableFunc(collected, func(a, b synctestpkg.CronRun) int {
+		return a.ScheduledAt.Compare(b.ScheduledAt)
+	})
+
+	described := make([]string, len(collected))
+	for i, r := range collected {
+		described[i] = fmt.Sprintf("%v %v", r.ScheduledAt.Sub(base), r.Status)
+		if r.Status == synctestpkg.CronRunCompleted {
+			described[i] += fmt.Sprintf(" at %v", r.StartedAt.Sub(base))
+		}
+	}
+	return described
+}
+
+// noop は何もしないジョブの処理
+func noop(context.Context) error { return nil }
+
+func TestCronScheduler(t *testing.T) {
+	t.Run("cron式の実行時刻に実行される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			base := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Hour+time.Second)
+			defer cancel()
+			s := synctestpkg.NewCronScheduler()
+			if err := s.Add(synctestpkg.CronJob{Name: "15分ごと", Spec: "*/15 * * * *", Run: noop, Location: time.UTC}); err != nil {
+				t.Fatal(err)
+			}
+
+			got := describeCronRuns(base, s.Start(ctx))
+
+			want := []string{
+				"15m0s completed at 15m0s",
+				"30m0s completed at 30m0s",
+				"45m0s completed at 45m0s",
+				"1h0m0s completed at 1h0m0s",
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("実行結果が期待値と異なります:\n got  %v\n want %v", got, want)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - 実行が重なった場合の方針", func(t *testing.T) {
+		// 1秒ごとに2.5秒かかるジョブを実行し、6.2秒でキャンセルする
+		testCases := []struct {
+			name    string
+			overlap synctestpkg.OverlapPolicy
+			expect  []string
+		}{
+			{
+				name:    "実行中は見送る",
+				overlap: synctestpkg.OverlapSkip,
+				expect: []string{
+					"1s completed at 1s",
+					"2s skipped (overlap)",
+					"3s skipped (overlap)",
+					"5s skipped (overlap)",
+					"6s skipped (overlap)",
+				},
+			},
+			{
+				name:    "前回の終了を待って実行する",
+				overlap: synctestpkg.OverlapQueue,
+				expect: []string{
+					"1s completed at 1s",
+					"2s completed at 3.5s",
+				},
+			},
+			{
+				name:    "並行に実行する",
+				overlap: synctestpkg.OverlapConcurrent,
+				expect: []string{
+					"1s completed at 1s",
+					"2s completed at 2s",
+					"3s completed at 3s",
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					base := time.Now()
+					ctx, cancel := context.WithTimeout(context.Background(), 6200*time.Millisecond)
+					defer cancel()
+					s := synctestpkg.NewCronScheduler()
+					job := synctestpkg.CronJob{Name: "重いジョブ", Spec: "@every 1s", Run: sleepTask(2500 * time.Millisecond), Overlap: tc.overlap}
+					if err := s.Add(job); err != nil {
+						t.Fatal(err)
+					}
+
+					got := describeCronRuns(base, s.Start(ctx))
+
+					if !slices.Equal(got, tc.expect) {
+						t.Errorf("実行結果が期待値と異なります:\n got  %v\n want %v", got, tc.expect)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 一時停止中に逃した実行の方針", func(t *testing.T) {
+		// 1秒ごとのジョブを2.5秒で一時停止して5.5秒で再開し、7.2秒でキャンセルする
+		testCases := []struct {
+			name    string
+			missed  synctestpkg.MissedFirePolicy
+			overlap synctestpkg.OverlapPolicy
+			expect  []string
+		}{
+			{
+				name:   "まとめて1回だけ実行する",
+				missed: synctestpkg.MissedFireRunOnce,
+				expect: []string{
+					"1s completed at 1s",
+					"2s completed at 2s",
+					"3s skipped (missed)",
+					"4s skipped (missed)",
+					"5s completed at 5.5s",
+					"6s completed at 6s",
+					"7s completed at 7s",
+				},
+			},
+			{
+				name:    "すべて実行する",
+				missed:  synctestpkg.MissedFireRunAll,
+				overlap: synctestpkg.OverlapConcurrent,
+				expect: []string{
+					"1s completed at 1s",
+					"2s completed at 2s",
+					"3s completed at 5.5s",
+					"4s completed at 5.5s",
+					"5s completed at 5.5s",
+					"6s completed at 6s",
+					"7s completed at 7s",
+				},
+			},
+			{
+				name:   "すべて見送る",
+				missed: synctestpkg.MissedFireSkip,
+				expect: []string{
+					"1s completed at 1s",
+					"2s completed at 2s",
+					"3s skipped (missed)",
+					"4s skipped (missed)",
+					"5s skipped (missed)",
+					"6s completed at 6s",
+					"7s completed at 7s",
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					base := time.Now()
+					ctx, cancel := context.WithTimeout(context.Background(), 7200*time.Millisecond)
+					defer cancel()
+					s := synctestpkg.NewCronScheduler()
+					job := synctestpkg.CronJob{Name: "毎秒", Spec: "@every 1s", Run: noop, Missed: tc.missed, Overlap: tc.overlap}
+					if err := s.Add(job); err != nil {
+						t.Fatal(err)
+					}
+					time.AfterFunc(2500*time.Millisecond, s.Pause)
+					time.AfterFunc(5500*time.Millisecond, s.Resume)
+
+					got := describeCronRuns(base, s.Start(ctx))
+
+					if !slices.Equal(got, tc.expect) {
+						t.Errorf("実行結果が期待値と異なります:\n got  %v\n want %v", got, tc.expect)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("一時停止中は前回の終了を待っている実行を開始しない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// 1秒の実行は2.5秒に終わるが、2.2秒から4秒まで一時停止しているため、2秒の実行は再開してから開始する
+			base := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), 6200*time.Millisecond)
+			defer cancel()
+			s := synctestpkg.NewCronScheduler()
+			job := synctestpkg.CronJob{
+				Name: "重いジョブ", Spec: "@every 1s", Run: sleepTask(1500 * time.Millisecond),
+				Overlap: synctestpkg.OverlapQueue, Missed: synctestpkg.MissedFireSkip,
+			}
+			if err := s.Add(job); err != nil {
+				t.Fatal(err)
+			}
+			time.AfterFunc(2200*time.Millisecond, s.Pause)
+			time.AfterFunc(4*time.Second, s.Resume)
+
+			got := describeCronRuns(base, s.Start(ctx))
+
+			want := []string{
+				"1s completed at 1s",
+				"2s completed at 4s",
+				"3s skipped (missed)",
+				"4s skipped (missed)",
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("実行結果が期待値と異なります:\n got  %v\n want %v", got, want)
+			}
+		})
+	})
+
+	t.Run("Startを複数回呼び出しても実行は重ならない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3500*time.Millisecond)
+			defer cancel()
+			s := synctestpkg.NewCronScheduler()
+			if err := s.Add(synctestpkg.CronJob{Name: "毎秒", Spec: "@every 1s", Run: noop}); err != nil {
+				t.Fatal(err)
+			}
+
+			runs := s.Start(ctx)
+			if again := s.Start(context.Background()); again != runs {
+				t.Error("2回目のStartが最初と異なるチャネルを返しました")
+			}
+
+			count := 0
+			for range runs {
+				count++
+			}
+			if count != 3 {
+				t.Errorf("実行回数が期待値と異なります: got %d, want 3", count)
+			}
+		})
+	})
+
+	t.Run("クロックが飛んだ場合も逃した実行として扱う", func(t *testing.T) {
+		clock := synctestpkg.NewFakeClock(time.Date(2025, 9, 27, 10, 0, 0, 0, time.UTC))
+		base := clock.Now()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := synctestpkg.NewCronScheduler(synctestpkg.WithClock(clock))
+		if err := s.Add(synctestpkg.CronJob{Name: "毎秒", Spec: "@every 1s", Run: noop}); err != nil {
+			t.Fatal(err)
+		}
+		runs := s.Start(ctx)
+		clock.BlockUntil(1)
+
+		clock.Advance(3500 * time.Millisecond)
+
+		var got []string
+		for r := range runs {
+			got = append(got, fmt.Sprintf("%v %v", r.ScheduledAt.Sub(base), r.Status))
+			if len(got) == 3 {
+				cancel()
+			}
+		}
+		want := []string{"1s skipped (missed)", "2s skipped (missed)", "3s completed"}
+		if !slices.Equal(got, want) {
+			t.Errorf("実行結果が期待値と異なります:\n got  %v\n want %v", got, want)
+		}
+	})
+
+	t.Run("エラーとパニックは結果に記録される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+			defer cancel()
+			errFailed := errors.New("失敗")
+			s := synctestpkg.NewCronScheduler()
+			_ = s.Add(synctestpkg.CronJob{Name: "失敗", Spec: "@every 1s", Run: func(context.Context) error { return errFailed }})
+			_ = s.Add(synctestpkg.CronJob{Name: "パニック", Spec: "@every 1s", Run: func(context.Context) error { panic("壊れた") }})
+
+			errs := make(map[string]error)
+			for r := range s.Start(ctx) {
+				errs[r.Job] = r.Err
+			}
+
+			if !errors.Is(errs["失敗"], errFailed) {
+				t.Errorf("エラーが期待値と異なります: got %v", errs["失敗"])
+			}
+			var panicErr *synctestpkg.PanicError
+			if !errors.As(errs["パニック"], &panicErr) {
+				t.Errorf("パニックがPanicErrorとして記録されていません: %v", errs["パニック"])
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - 登録できないジョブ", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			job    synctestpkg.CronJob
+			expect error
+		}{
+			{name: "不正なcron式", job: synctestpkg.CronJob{Name: "不正", Spec: "* * *", Run: noop}, expect: synctestpkg.ErrInvalidCronSpec},
+			{name: "重複したジョブ名", job: synctestpkg.CronJob{Name: "毎秒", Spec: "@every 2s", Run: noop}, expect: synctestpkg.ErrDuplicateCronJob},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				s := synctestpkg.NewCronScheduler()
+				if err := s.Add(synctestpkg.CronJob{Name: "毎秒", Spec: "@every 1s", Run: noop}); err != nil {
+					t.Fatal(err)
+				}
+
+				err := s.Add(tc.job)
+
+				if !errors.Is(err, tc.expect) {
+					t.Errorf("エラーが期待値と異なります: got %v, want %v", err, tc.expect)
+				}
+			})
+		}
+	})
+}
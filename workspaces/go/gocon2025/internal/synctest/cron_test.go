@@ -0,0 +1,174 @@
+package synctest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute, sec int) time.Time {
+		return time.Date(year, month, day, hour, minute, sec, 0, time.UTC)
+	}
+
+	t.Run("Table Driven Test - 次の実行時刻", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			spec   string
+			from   time.Time
+			expect []time.Time
+		}{
+			{
+				name:   "毎日0時",
+				spec:   "0 0 * * *",
+				from:   at(2025, 9, 27, 10, 0, 0),
+				expect: []time.Time{at(2025, 9, 28, 0, 0, 0), at(2025, 9, 29, 0, 0, 0)},
+			},
+			{
+				name:   "起点ちょうどの時刻は含まない",
+				spec:   "15 10 * * *",
+				from:   at(2025, 9, 27, 10, 15, 0),
+				expect: []time.Time{at(2025, 9, 28, 10, 15, 0)},
+			},
+			{
+				name: "範囲とステップ",
+				spec: "*/20 8-9 * * *",
+				from: at(2025, 9, 27, 7, 59, 0),
+				expect: []time.Time{
+					at(2025, 9, 27, 8, 0, 0), at(2025, 9, 27, 8, 20, 0), at(2025, 9, 27, 8, 40, 0),
+					at(2025, 9, 27, 9, 0, 0), at(2025, 9, 27, 9, 20, 0), at(2025, 9, 27, 9, 40, 0),
+					at(2025, 9, 28, 8, 0, 0),
+				},
+			},
+			{
+				name:   "開始値付きのステップは最大値まで",
+				spec:   "5/20 * * * *",
+				from:   at(2025, 9, 27, 10, 0, 0),
+				expect: []time.Time{at(2025, 9, 27, 10, 5, 0), at(2025, 9, 27, 10, 25, 0), at(2025, 9, 27, 10, 45, 0), at(2025, 9, 27, 11, 5, 0)},
+			},
+			{
+				name:   "曜日の名前による範囲",
+				spec:   "30 9 * * mon-fri",
+				from:   at(2025, 9, 26, 10, 0, 0), // 金曜日
+				expect: []time.Time{at(2025, 9, 29, 9, 30, 0), at(2025, 9, 30, 9, 30, 0)},
+			},
+			{
+				name:   "月の名前によるリスト",
+				spec:   "0 0 1 jan,JUL *",
+				from:   at(2025, 3, 1, 0, 0, 0),
+				expect: []time.Time{at(2025, 7, 1, 0, 0, 0), at(2026, 1, 1, 0, 0, 0)},
+			},
+			{
+				name:   "曜日の7は日曜日",
+				spec:   "0 0 * * 7",
+				from:   at(2025, 9, 27, 0, 0, 0), // 土曜日
+				expect: []time.Time{at(2025, 9, 28, 0, 0, 0), at(2025, 10, 5, 0, 0, 0)},
+			},
+			{
+				name:   "日と曜日の両方を指定するとどちらかに一致すればよい",
+				spec:   "0 12 1 * sun",
+				from:   at(2025, 9, 27, 0, 0, 0), // 土曜日
+				expect: []time.Time{at(2025, 9, 28, 12, 0, 0), at(2025, 10, 1, 12, 0, 0), at(2025, 10, 5, 12, 0, 0)},
+			},
+			{
+				name:   "日曜日で終わる曜日の範囲",
+				spec:   "0 9 * * fri-sun",
+				from:   at(2025, 9, 27, 10, 0, 0), // 土曜日
+				expect: []time.Time{at(2025, 9, 28, 9, 0, 0), at(2025, 10, 3, 9, 0, 0), at(2025, 10, 4, 9, 0, 0)},
+			},
+			{
+				// */2は*と同様に扱うため、奇数日の月曜日だけに一致する
+				name:   "*で始まる日の指定は曜日の両方に一致すればよい",
+				spec:   "0 0 */2 * mon",
+				from:   at(2025, 9, 27, 0, 0, 0), // 土曜日
+				expect: []time.Time{at(2025, 9, 29, 0, 0, 0), at(2025, 10, 13, 0, 0, 0)},
+			},
+			{
+				name:   "閏年の2月29日",
+				spec:   "0 0 29 2 *",
+				from:   at(2025, 1, 1, 0, 0, 0),
+				expect: []time.Time{at(2028, 2, 29, 0, 0, 0), at(2032, 2, 29, 0, 0, 0)},
+			},
+			{
+				name:   "@hourly",
+				spec:   "@hourly",
+				from:   at(2025, 9, 27, 10, 30, 0),
+				expect: []time.Time{at(2025, 9, 27, 11, 0, 0), at(2025, 9, 27, 12, 0, 0)},
+			},
+			{
+				name:   "@everyは起点からの間隔",
+				spec:   "@every 90s",
+				from:   at(2025, 9, 27, 10, 0, 10),
+				expect: []time.Time{at(2025, 9, 27, 10, 1, 40), at(2025, 9, 27, 10, 3, 10)},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				schedule, err := synctestpkg.ParseCron(tc.spec)
+				if err != nil {
+					t.Fatalf("解析に失敗しました: %v", err)
+				}
+
+				next := tc.from
+				for i, want := range tc.expect {
+					next = schedule.Next(next)
+					if !next.Equal(want) {
+						t.Fatalf("%d回目の実行時刻が期待値と異なります: got %v, want %v", i+1, next, want)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("指定したロケーションで評価する", func(t *testing.T) {
+		jst := time.FixedZone("JST", 9*60*60)
+		schedule, err := synctestpkg.ParseCronInLocation("0 9 * * *", jst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 日本時間の9時はUTCの0時
+		next := schedule.Next(at(2025, 9, 26, 23, 0, 0))
+
+		if want := at(2025, 9, 27, 0, 0, 0); !next.Equal(want) {
+			t.Errorf("実行時刻が期待値と異なります: got %v, want %v", next, want)
+		}
+		if next.Location() != time.UTC {
+			t.Errorf("起点と同じロケーションで返されていません: %v", next.Location())
+		}
+	})
+
+	t.Run("Table Driven Test - 不正なcron式", func(t *testing.T) {
+		specs := []string{
+			"",
+			"* * * *",
+			"* * * * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"* * * * 8",
+			"5-1 * * * *",
+			"* * * * sat-mon",
+			"*/0 * * * *",
+			"a * * * *",
+			"* * * * funday",
+			"@unknown",
+			"@every abc",
+			"@every -1s",
+		}
+
+		for _, spec := range specs {
+			t.Run(spec, func(t *testing.T) {
+				_, err := synctestpkg.ParseCron(spec)
+
+				if !errors.Is(err, synctestpkg.ErrInvalidCronSpec) {
+					t.Errorf("エラーが期待値と異なります: got %v", err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package synctest
+
+import (
+	"time"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/singleflight"
+)
+
+// delayKey はProcessWithDelayの呼び出しが同一であるかを判定するキー
+type delayKey struct {
+	delay   time.Duration
+	message string
+}
+
+// WithDeduplication は同じ遅延とメッセージでProcessWithDelayを同時に呼び出した場合に、処理を1回にまとめる
+// まとめられた呼び出しは同じ結果を受け取る。呼び出し元の1つがキャンセルしても、他の呼び出し元が待っている間は処理を続ける
+func WithDeduplication() Option {
+	return func(o *options) {
+		o.dedup = &singleflight.Group[delayKey, string]{}
+	}
+}
@@ -0,0 +1,77 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+func TestProcessWithDelayDeduplication(t *testing.T) {
+	t.Run("Table Driven Test - 同時呼び出しの待機数", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			opts          []synctestpkg.Option
+			expectWaiters int
+		}{
+			{name: "重複をまとめない", expectWaiters: 4},
+			// 同じ遅延とメッセージの3件が1件にまとめられる
+			{name: "重複をまとめる", opts: []synctestpkg.Option{synctestpkg.WithDeduplication()}, expectWaiters: 2},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					clock := synctestpkg.NewFakeClock(time.Now())
+					processor := synctestpkg.NewTaskProcessor(append(tc.opts, synctestpkg.WithClock(clock))...)
+					ctx := context.Background()
+
+					results := []<-chan synctestpkg.Result[string]{
+						processor.ProcessWithDelay(ctx, time.Second, "同じ"),
+						processor.ProcessWithDelay(ctx, time.Second, "同じ"),
+						processor.ProcessWithDelay(ctx, time.Second, "同じ"),
+						processor.ProcessWithDelay(ctx, time.Second, "別"),
+					}
+					synctest.Wait()
+					if n := clock.Waiters(); n != tc.expectWaiters {
+						t.Errorf("待機している処理の数が期待値と異なります: got %d, want %d", n, tc.expectWaiters)
+					}
+
+					clock.Advance(time.Second)
+
+					want := []string{"処理完了: 同じ", "処理完了: 同じ", "処理完了: 同じ", "処理完了: 別"}
+					for i, result := range results {
+						if got := <-result; got.Value != want[i] || got.Err != nil {
+							t.Errorf("%d件目の結果が期待値と異なります: got %q, %v, want %q", i+1, got.Value, got.Err, want[i])
+						}
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("まとめられた呼び出し元の1つがキャンセルしても他は結果を受け取る", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithDeduplication())
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(500*time.Millisecond, cancel)
+			start := time.Now()
+
+			canceled := processor.ProcessWithDelay(ctx, time.Second, "共有")
+			kept := processor.ProcessWithDelay(context.Background(), time.Second, "共有")
+
+			if got := <-canceled; got.Value != "" || !errors.Is(got.Err, context.Canceled) {
+				t.Errorf("キャンセルした呼び出し元にキャンセルのエラーが届くことを期待しました: %q, %v", got.Value, got.Err)
+			}
+			if got := <-kept; got.Value != "処理完了: 共有" || got.Err != nil {
+				t.Errorf("結果が期待値と異なります: got %q, %v", got.Value, got.Err)
+			}
+			if elapsed := time.Since(start); elapsed != time.Second {
+				t.Errorf("経過時間が期待値と異なります: got %v, want 1s", elapsed)
+			}
+		})
+	})
+}
@@ -0,0 +1,113 @@
+package synctest
+
+import (
+	"context"
+	"time"
+)
+
+// frameDuration は1フレームの生成にかかる時間
+const frameDuration = 50 * time.Millisecond
+
+// FrameResult はGenerateFramesが送信するフレーム
+type FrameResult struct {
+	Index int   // フレーム番号（1始まり）
+	Err   error // 生成を中断した場合の原因。Errを設定した結果はチャネルを閉じる直前に1つだけ送信される
+}
+
+// generateFrame はindex番目のフレームを生成する（各フレームの生成に50ms必要）
+// コンテキストが終了した場合は生成を取りやめ、その原因を設定した結果を返す
+func (p *videoProcessor) generateFrame(ctx context.Context, index int) FrameResult {
+	timer := p.clock.NewTimer(frameDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return FrameResult{Index: index}
+	case <-ctx.Done():
+		return FrameResult{Index: index, Err: context.Cause(ctx)}
+	}
+}
+
+// frameIndices はfirstからtotalFramesまでのフレーム番号を順に送信する
+func frameIndices(ctx context.Context, first, totalFrames int) <-chan int {
+	indices := make(chan int)
+
+	go func() {
+		defer close(indices)
+		for i := first; i <= totalFrames; i++ {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return indices
+}
+
+// frameOptions はフレームを並列に生成するための設定を返す
+// フレームは常に番号順に送信するため、WithOrderedResultsを指定していない場合も既定の先行数で並べ替える
+func (p *videoProcessor) frameOptions() options {
+	o := p.options
+	if o.order == nil {
+		o.order = &orderConfig{}
+	}
+	return o
+}
+
+// generateFramesParallel はワーカープールでフレームを並列に生成して番号順に送信し、送信できなかった最初のフレーム番号を返す
+// キャンセルされた場合も送信済みのフレームは1からの連番で、途中のフレームが抜けることはない
+func (p *videoProcessor) generateFramesParallel(ctx context.Context, totalFrames int, result chan<- FrameResult) int {
+	generate := func(ctx context.Context, index int) (int, error) {
+		_, span := p.startSpan(ctx, "frame", Attr("index", index), Attr("kind", frameKindGenerate))
+		defer span.End()
+		f := p.generateFrame(ctx, index)
+		span.RecordError(f.Err)
+		return f.Index, f.Err
+	}
+
+	next := 1
+	for r := range runStream(ctx, p.frameOptions(), frameIndices(ctx, 1, totalFrames), generate, 0) {
+		if r.Err != nil || ctx.Err() != nil {
+			continue
+		}
+		result <- FrameResult{Index: r.Value}
+		p.metrics.frame(frameKindGenerate)
+		next = r.Value + 1
+	}
+	return next
+}
+
+// renderFramesParallel はワーカープールでfirst番目以降のフレームを並列に描画して番号順に送信し、送信しなかった最初のフレーム番号を返す
+// 描画に失敗した場合はそのフレームまでを送信し、描画中の残りのフレームをキャンセルする
+func (p *videoProcessor) renderFramesParallel(ctx context.Context, cfg RenderConfig, first int, result chan<- Frame) int {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	render := func(ctx context.Context, index int) (Frame, error) {
+		return p.renderFrameTraced(ctx, cfg, index), nil
+	}
+
+	next := first
+	frames := runStream(ctx, p.frameOptions(), frameIndices(ctx, first, cfg.TotalFrames), render, 0)
+	for r := range frames {
+		if ctx.Err() != nil {
+			continue
+		}
+		select {
+		case result <- r.Value:
+		case <-ctx.Done():
+			continue
+		}
+		next = r.Value.Index + 1
+		if r.Value.Err != nil {
+			// 失敗したフレームが終了の原因となるため、中断したものとしては扱わない
+			next = cfg.TotalFrames + 1
+			cancel()
+			continue
+		}
+		p.metrics.frame(frameKindRender)
+	}
+	return next
+}
@@ -0,0 +1,189 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// sequence は1からnまでの連番を返す
+func sequence(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i + 1
+	}
+	return s
+}
+
+func TestParallelFrames(t *testing.T) {
+	t.Run("Table Driven Test - 並列生成したフレームは番号順に届く", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			totalFrames   int
+			opts          []synctestpkg.Option
+			expectElapsed time.Duration
+		}{
+			{
+				name:          "逐次生成",
+				totalFrames:   8,
+				expectElapsed: 400 * time.Millisecond,
+			},
+			{
+				name:          "4ワーカー",
+				totalFrames:   8,
+				opts:          []synctestpkg.Option{synctestpkg.WithWorkerPool(4, 0)},
+				expectElapsed: 100 * time.Millisecond,
+			},
+			{
+				name:          "ワーカー数が割り切れない",
+				totalFrames:   10,
+				opts:          []synctestpkg.Option{synctestpkg.WithWorkerPool(3, 0)},
+				expectElapsed: 200 * time.Millisecond,
+			},
+			{
+				name:          "先行数1は逐次生成と同じ",
+				totalFrames:   4,
+				opts:          []synctestpkg.Option{synctestpkg.WithWorkerPool(4, 0), synctestpkg.WithOrderedResults(1)},
+				expectElapsed: 200 * time.Millisecond,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					processor := synctestpkg.NewVideoProcessor(tc.opts...)
+					start := time.Now()
+
+					var frames []int
+					for f := range processor.GenerateFrames(context.Background(), tc.totalFrames) {
+						if f.Err != nil {
+							t.Errorf("フレーム %d の生成が失敗しました: %v", f.Index, f.Err)
+						}
+						frames = append(frames, f.Index)
+					}
+
+					if want := sequence(tc.totalFrames); !slices.Equal(frames, want) {
+						t.Errorf("フレームの順序が期待値と異なります: got %v, want %v", frames, want)
+					}
+					if elapsed := time.Since(start); elapsed != tc.expectElapsed {
+						t.Errorf("経過時間が期待値と異なります: got %v, want %v", elapsed, tc.expectElapsed)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - キャンセル時は連番の途中までを送信する", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			cancelAfter  time.Duration
+			expectFrames int
+		}{
+			{name: "生成前", cancelAfter: 0, expectFrames: 0},
+			{name: "1巡目の後", cancelAfter: 75 * time.Millisecond, expectFrames: 4},
+			{name: "2巡目の後", cancelAfter: 125 * time.Millisecond, expectFrames: 8},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					if tc.cancelAfter > 0 {
+						time.AfterFunc(tc.cancelAfter, cancel)
+					} else {
+						cancel()
+					}
+					processor := synctestpkg.NewVideoProcessor(synctestpkg.WithWorkerPool(4, 0))
+
+					var frames []int
+					var last synctestpkg.FrameResult
+					for f := range processor.GenerateFrames(ctx, 20) {
+						if last = f; f.Err == nil {
+							frames = append(frames, f.Index)
+						}
+					}
+
+					if want := sequence(tc.expectFrames); !slices.Equal(frames, want) {
+						t.Errorf("送信されたフレームが期待値と異なります: got %v, want %v", frames, want)
+					}
+					// 最後に生成できなかった最初のフレーム番号とキャンセルのエラーを送信する
+					if last.Index != tc.expectFrames+1 || !errors.Is(last.Err, context.Canceled) {
+						t.Errorf("中断した結果が期待値と異なります: got %d, %v, want %d, %v", last.Index, last.Err, tc.expectFrames+1, context.Canceled)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("先頭のフレームが遅い間は先行数を超えて描画しない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			release := make(chan struct{})
+			var rendered atomic.Int32
+			renderer := synctestpkg.FrameRendererFunc(func(ctx context.Context, _ *image.RGBA, index, _ int) error {
+				rendered.Add(1)
+				if index == 1 {
+					<-release
+				}
+				return nil
+			})
+			processor := synctestpkg.NewVideoProcessor(synctestpkg.WithWorkerPool(4, 0), synctestpkg.WithOrderedResults(3))
+
+			frames := processor.RenderFrames(context.Background(), synctestpkg.RenderConfig{Width: 4, Height: 4, TotalFrames: 10, Renderer: renderer})
+			synctest.Wait()
+
+			// 1フレーム目の完了を待つ間は、並べ替えのために保持するフレームを含めて3枚までしか描画しない
+			if n := rendered.Load(); n != 3 {
+				t.Errorf("描画を開始したフレーム数が期待値と異なります: got %d, want 3", n)
+			}
+
+			close(release)
+			var indices []int
+			for f := range frames {
+				indices = append(indices, f.Index)
+			}
+			if want := sequence(10); !slices.Equal(indices, want) {
+				t.Errorf("フレームの順序が期待値と異なります: got %v, want %v", indices, want)
+			}
+		})
+	})
+
+	t.Run("描画に失敗したフレームで並列描画を打ち切る", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			errBroken := errors.New("描画に失敗")
+			renderer := synctestpkg.FrameRendererFunc(func(ctx context.Context, _ *image.RGBA, index, _ int) error {
+				if index == 3 {
+					return errBroken
+				}
+				// 失敗したフレームより後のフレームはキャンセルされるまで終わらない
+				if index > 3 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			})
+			processor := synctestpkg.NewVideoProcessor(synctestpkg.WithWorkerPool(4, 0))
+
+			frames := collectFrames(processor.RenderFrames(context.Background(), synctestpkg.RenderConfig{Width: 4, Height: 4, TotalFrames: 10, Renderer: renderer}))
+
+			if len(frames) != 3 {
+				t.Fatalf("フレーム数が期待値と異なります: got %d, want 3", len(frames))
+			}
+			for i, f := range frames {
+				if f.Index != i+1 {
+					t.Errorf("フレーム番号が期待値と異なります: got %d, want %d", f.Index, i+1)
+				}
+			}
+			if !errors.Is(frames[2].Err, errBroken) {
+				t.Errorf("3フレーム目のエラーが期待値と異なります: %v", frames[2].Err)
+			}
+		})
+	})
+}
@@ -0,0 +1,275 @@
+package synctest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// jobEventBuffer はジョブのイベントを保持するチャネルの長さ
+const jobEventBuffer = 64
+
+// JobState はジョブの状態
+type JobState int
+
+const (
+	// JobRunning はフレームを描画している状態
+	JobRunning JobState = iota
+	// JobCompleted はすべてのフレームを書き出した状態
+	JobCompleted
+	// JobCanceled はコンテキストのキャンセルまたはCancelで中断した状態
+	JobCanceled
+	// JobFailed は描画または書き出しに失敗した状態
+	JobFailed
+)
+
+// String はJobStateの文字列表現を返す
+func (s JobState) String() string {
+	switch s {
+	case JobRunning:
+		return "running"
+	case JobCompleted:
+		return "completed"
+	case JobCanceled:
+		return "canceled"
+	case JobFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Done はジョブが終了した状態かどうかを返す
+func (s JobState) Done() bool {
+	return s != JobRunning
+}
+
+// JobEventKind はジョブのイベントの種類
+type JobEventKind int
+
+const (
+	// EventStarted はジョブを開始したことを表す
+	EventStarted JobEventKind = iota
+	// EventFrameCompleted はフレームを1つ書き出したことを表す
+	EventFrameCompleted
+	// EventCompleted はすべてのフレームを書き出してジョブが完了したことを表す
+	EventCompleted
+	// EventCanceled はジョブがキャンセルされたことを表す
+	EventCanceled
+	// EventFailed はジョブが失敗したことを表す
+	EventFailed
+)
+
+// String はJobEventKindの文字列表現を返す
+func (k JobEventKind) String() string {
+	switch k {
+	case EventStarted:
+		return "started"
+	case EventFrameCompleted:
+		return "frame completed"
+	case EventCompleted:
+		return "completed"
+	case EventCanceled:
+		return "canceled"
+	case EventFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// JobStatus はある時点のジョブの進捗
+type JobStatus struct {
+	State      JobState
+	Completed  int           // 書き出したフレーム数
+	Total      int           // 総フレーム数
+	StartedAt  time.Time     // ジョブを開始した時刻
+	FinishedAt time.Time     // ジョブが終了した時刻。実行中はゼロ値
+	Elapsed    time.Duration // 開始から現在（終了した場合は終了時刻）までの経過時間
+	Throughput float64       // 1秒あたりに書き出したフレーム数
+	ETA        time.Duration // 残りのフレームを書き出すまでの推定時間。推定できない場合や終了した場合は0
+	Err        error         // キャンセルまたは失敗した原因
+}
+
+// JobEvent はジョブの進捗を知らせるイベント
+type JobEvent struct {
+	Kind   JobEventKind
+	Frame  int       // EventFrameCompletedで書き出したフレーム番号
+	Time   time.Time // イベントが発生した時刻
+	Status JobStatus // イベント発生時点の進捗
+}
+
+// RenderJob はStartRenderで開始した描画ジョブのハンドル
+type RenderJob interface {
+	// Events はジョブのイベントを受信するチャネルを返す。終了のイベントを送信すると閉じられる
+	// チャネルが満杯の場合は最も古いイベントを捨てるため、受信しなくても描画は止まらない
+	Events() <-chan JobEvent
+	// Status は現在の進捗を返す
+	Status() JobStatus
+	// Cancel はジョブを中断する。終了したジョブに対しては何もしない
+	Cancel()
+	// Done はジョブが終了すると閉じられるチャネルを返す
+	Done() <-chan struct{}
+}
+
+// renderJob はRenderJobの具象実装
+type renderJob struct {
+	clock  Clock
+	cancel context.CancelFunc
+	events chan JobEvent
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// StartRender フレームを描画してsinkに書き出すジョブを開始し、進捗を確認できるハンドルを返す
+// sinkがnilの場合はフレームを書き出さずに捨てる。ジョブの終了時にsinkを閉じる
+// フレームの描画・書き出しに失敗した場合はJobFailed、ctxが終了した場合やCancelした場合はJobCanceledで終了する
+func (p *videoProcessor) StartRender(ctx context.Context, cfg RenderConfig, sink FrameSink) RenderJob {
+	cfg = p.renderConfig(cfg)
+	ctx, cancel := context.WithCancel(ctx)
+	j := &renderJob{
+		clock:  p.clock,
+		cancel: cancel,
+		events: make(chan JobEvent, jobEventBuffer),
+		done:   make(chan struct{}),
+		status: JobStatus{State: JobRunning, Total: max(cfg.TotalFrames, 0), StartedAt: p.clock.Now()},
+	}
+	j.emit(EventStarted, 0)
+
+	started := p.spawn(ctx, "StartRender", func(ctx context.Context) {
+		frames := make(chan Frame, frameBuffer(cfg))
+		go func() {
+			defer close(frames)
+			p.streamFrames(ctx, cfg, frames)
+		}()
+		j.run(ctx, frames, sink)
+	})
+	if !started {
+		j.finish(ErrShuttingDown, sink)
+	}
+
+	return j
+}
+
+// run はフレームをsinkに書き出しながら進捗を記録する
+func (j *renderJob) run(ctx context.Context, frames <-chan Frame, sink FrameSink) {
+	var err error
+	for f := range frames {
+		if err != nil {
+			continue
+		}
+		if f.Err != nil {
+			err = f.Err
+			j.cancel()
+			continue
+		}
+		if sink != nil {
+			if err = sink.WriteFrame(f); err != nil {
+				j.cancel()
+				continue
+			}
+		}
+		j.mu.Lock()
+		j.status.Completed++
+		j.mu.Unlock()
+		j.emit(EventFrameCompleted, f.Index)
+	}
+
+	j.mu.Lock()
+	completed := j.status.Completed == j.status.Total
+	j.mu.Unlock()
+	if err == nil && !completed {
+		err = context.Cause(ctx)
+	}
+	j.finish(err, sink)
+}
+
+// finish はsinkを閉じて終了の状態を記録し、終了のイベントを送信する
+func (j *renderJob) finish(err error, sink FrameSink) {
+	if sink != nil {
+		if closeErr := sink.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	j.cancel()
+
+	kind, state := EventCompleted, JobCompleted
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		kind, state = EventCanceled, JobCanceled
+	case err != nil:
+		kind, state = EventFailed, JobFailed
+	}
+
+	j.mu.Lock()
+	j.status.State = state
+	j.status.Err = err
+	j.status.FinishedAt = j.clock.Now()
+	j.mu.Unlock()
+
+	j.emit(kind, 0)
+	close(j.events)
+	close(j.done)
+}
+
+// Events はジョブのイベントを受信するチャネルを返す
+func (j *renderJob) Events() <-chan JobEvent {
+	return j.events
+}
+
+// Status は現在の進捗を返す
+func (j *renderJob) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.snapshotLocked(j.clock.Now())
+}
+
+// Cancel はジョブを中断する
+func (j *renderJob) Cancel() {
+	j.cancel()
+}
+
+// Done はジョブが終了すると閉じられるチャネルを返す
+func (j *renderJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// emit は現在の進捗を含むイベントを送信する。チャネルが満杯の場合は最も古いイベントを捨てる
+// イベントを送信するのはジョブのゴルーチンだけなので、古いイベントを捨てた後の送信はブロックしない
+func (j *renderJob) emit(kind JobEventKind, frame int) {
+	j.mu.Lock()
+	now := j.clock.Now()
+	e := JobEvent{Kind: kind, Frame: frame, Time: now, Status: j.snapshotLocked(now)}
+	j.mu.Unlock()
+
+	select {
+	case j.events <- e:
+		return
+	default:
+	}
+	select {
+	case <-j.events:
+	default:
+	}
+	j.events <- e
+}
+
+// snapshotLocked はnow時点の経過時間・スループット・残り時間を計算した進捗を返す
+func (j *renderJob) snapshotLocked(now time.Time) JobStatus {
+	s := j.status
+	if s.State.Done() {
+		now = s.FinishedAt
+	}
+	s.Elapsed = now.Sub(s.StartedAt)
+	if s.Elapsed > 0 && s.Completed > 0 {
+		s.Throughput = float64(s.Completed) / s.Elapsed.Seconds()
+		if !s.State.Done() {
+			remaining := s.Total - s.Completed
+			s.ETA = time.Duration(float64(remaining) / s.Throughput * float64(time.Second))
+		}
+	}
+	return s
+}
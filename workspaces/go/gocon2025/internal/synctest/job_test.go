@@ -0,0 +1,196 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// slowRenderer は1フレームの描画に100msかかるレンダラー
+var slowRenderer = synctestpkg.FrameRendererFunc(func(ctx context.Context, _ *image.RGBA, _, _ int) error {
+	select {
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+})
+
+// collectEvents はイベントのチャネルが閉じられるまで受信して返す
+func collectEvents(job synctestpkg.RenderJob) []synctestpkg.JobEvent {
+	var events []synctestpkg.JobEvent
+	for e := range job.Events() {
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestRenderJob(t *testing.T) {
+	cfg := synctestpkg.RenderConfig{Width: 4, Height: 4, TotalFrames: 5, Renderer: slowRenderer}
+
+	t.Run("完了までのイベントと進捗", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewVideoProcessor()
+
+			job := processor.StartRender(context.Background(), cfg, nil)
+			events := collectEvents(job)
+
+			if len(events) != 7 {
+				t.Fatalf("イベント数が期待値と異なります: got %d, want 7", len(events))
+			}
+			if events[0].Kind != synctestpkg.EventStarted || events[6].Kind != synctestpkg.EventCompleted {
+				t.Errorf("最初と最後のイベントが期待値と異なります: %v, %v", events[0].Kind, events[6].Kind)
+			}
+			for i, e := range events[1:6] {
+				if e.Kind != synctestpkg.EventFrameCompleted || e.Frame != i+1 || e.Status.Completed != i+1 {
+					t.Errorf("%d件目のフレームのイベントが期待値と異なります: %+v", i+1, e)
+				}
+				// 1フレーム100msなので10fpsで、残りのフレーム数×100msが残り時間となる
+				if e.Status.Throughput != 10 {
+					t.Errorf("スループットが期待値と異なります: got %v, want 10", e.Status.Throughput)
+				}
+				if want := time.Duration(4-i) * 100 * time.Millisecond; e.Status.ETA != want {
+					t.Errorf("残り時間が期待値と異なります: got %v, want %v", e.Status.ETA, want)
+				}
+			}
+
+			status := job.Status()
+			if status.State != synctestpkg.JobCompleted || status.Err != nil {
+				t.Errorf("状態が期待値と異なります: %v, %v", status.State, status.Err)
+			}
+			if status.Elapsed != 500*time.Millisecond || status.ETA != 0 {
+				t.Errorf("経過時間と残り時間が期待値と異なります: %v, %v", status.Elapsed, status.ETA)
+			}
+		})
+	})
+
+	t.Run("実行中の進捗を取得できる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewVideoProcessor()
+
+			job := processor.StartRender(context.Background(), cfg, nil)
+			time.Sleep(250 * time.Millisecond)
+			status := job.Status()
+
+			// 250msで2フレームなので8fps、残り3フレームは375ms
+			if status.State != synctestpkg.JobRunning || status.Completed != 2 || status.Total != 5 {
+				t.Errorf("進捗が期待値と異なります: %+v", status)
+			}
+			if status.Throughput != 8 || status.ETA != 375*time.Millisecond {
+				t.Errorf("スループットと残り時間が期待値と異なります: %v, %v", status.Throughput, status.ETA)
+			}
+			<-job.Done()
+		})
+	})
+
+	t.Run("Table Driven Test - 終了の理由", func(t *testing.T) {
+		errBroken := errors.New("描画に失敗")
+		errDisk := errors.New("ディスクがいっぱいです")
+
+		testCases := []struct {
+			name            string
+			renderer        synctestpkg.FrameRenderer
+			sink            synctestpkg.FrameSink
+			timeout         time.Duration
+			cancelAfter     time.Duration
+			expectState     synctestpkg.JobState
+			expectKind      synctestpkg.JobEventKind
+			expectErr       error
+			expectCompleted int
+		}{
+			{
+				name:            "Cancelで中断",
+				renderer:        slowRenderer,
+				cancelAfter:     250 * time.Millisecond,
+				expectState:     synctestpkg.JobCanceled,
+				expectKind:      synctestpkg.EventCanceled,
+				expectErr:       context.Canceled,
+				expectCompleted: 2,
+			},
+			{
+				name:            "コンテキストの期限切れ",
+				renderer:        slowRenderer,
+				timeout:         350 * time.Millisecond,
+				expectState:     synctestpkg.JobCanceled,
+				expectKind:      synctestpkg.EventCanceled,
+				expectErr:       context.DeadlineExceeded,
+				expectCompleted: 3,
+			},
+			{
+				name: "描画の失敗",
+				renderer: synctestpkg.FrameRendererFunc(func(_ context.Context, _ *image.RGBA, index, _ int) error {
+					if index == 3 {
+						return errBroken
+					}
+					return nil
+				}),
+				expectState:     synctestpkg.JobFailed,
+				expectKind:      synctestpkg.EventFailed,
+				expectErr:       errBroken,
+				expectCompleted: 2,
+			},
+			{
+				name:            "書き出しの失敗",
+				renderer:        slowRenderer,
+				sink:            &failingSink{failAt: 4, err: errDisk},
+				expectState:     synctestpkg.JobFailed,
+				expectKind:      synctestpkg.EventFailed,
+				expectErr:       errDisk,
+				expectCompleted: 3,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					ctx := context.Background()
+					if tc.timeout > 0 {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+						defer cancel()
+					}
+					processor := synctestpkg.NewVideoProcessor()
+
+					job := processor.StartRender(ctx, synctestpkg.RenderConfig{Width: 4, Height: 4, TotalFrames: 5, Renderer: tc.renderer}, tc.sink)
+					if tc.cancelAfter > 0 {
+						time.AfterFunc(tc.cancelAfter, job.Cancel)
+					}
+					events := collectEvents(job)
+
+					if last := events[len(events)-1]; last.Kind != tc.expectKind {
+						t.Errorf("最後のイベントが期待値と異なります: got %v, want %v", last.Kind, tc.expectKind)
+					}
+					status := job.Status()
+					if status.State != tc.expectState || !errors.Is(status.Err, tc.expectErr) {
+						t.Errorf("状態が期待値と異なります: got %v (%v), want %v (%v)", status.State, status.Err, tc.expectState, tc.expectErr)
+					}
+					if status.Completed != tc.expectCompleted {
+						t.Errorf("書き出したフレーム数が期待値と異なります: got %d, want %d", status.Completed, tc.expectCompleted)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("イベントを受信しなくても描画は止まらない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewVideoProcessor()
+
+			job := processor.StartRender(context.Background(), synctestpkg.RenderConfig{Width: 2, Height: 2, TotalFrames: 200}, nil)
+			<-job.Done()
+			events := collectEvents(job)
+
+			if len(events) > 64 {
+				t.Errorf("保持するイベント数が上限を超えています: %d", len(events))
+			}
+			if last := events[len(events)-1]; last.Kind != synctestpkg.EventCompleted || last.Status.Completed != 200 {
+				t.Errorf("最後のイベントが期待値と異なります: %+v", last)
+			}
+		})
+	})
+}
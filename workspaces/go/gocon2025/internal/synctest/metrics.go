@@ -0,0 +1,374 @@
+package synctest
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets はタスクの処理時間のヒストグラムの既定のバケットの上限（秒）
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// フレームの種類を表すラベルの値
+const (
+	frameKindGenerate = "generate" // GenerateFramesで生成したフレーム
+	frameKindRender   = "render"   // RenderFrames・StartRender・Resumeで描画したフレーム
+	frameKindPlayback = "playback" // Playで表示したフレーム
+)
+
+// MetricsConfig はメトリクスの設定
+type MetricsConfig struct {
+	// Namespace はメトリクス名の接頭辞。空の場合は"synctest"
+	Namespace string
+	// LatencyBuckets はタスクの処理時間のヒストグラムのバケットの上限（秒）。空の場合はDefaultLatencyBuckets
+	// +Infのバケットは常に出力するため、+InfとNaNを指定した場合は取り除く
+	LatencyBuckets []float64
+	// FPSWindow は1秒あたりのフレーム数を計算する直近の期間。0以下の場合は1秒
+	FPSWindow time.Duration
+}
+
+// HistogramSnapshot はある時点のヒストグラムの値
+type HistogramSnapshot struct {
+	Buckets []float64 `json:"buckets"` // バケットの上限（秒）
+	Counts  []uint64  `json:"counts"`  // 各バケットの上限以下だった観測数の累計
+	Sum     float64   `json:"sum"`     // 観測値の合計（秒）
+	Count   uint64    `json:"count"`   // 観測数
+}
+
+// TaskMetrics はある時点の処理ごとのタスクの集計
+// Submittedは常にCompleted・Canceled・Failed・InFlightの合計と一致する
+type TaskMetrics struct {
+	Submitted int64             `json:"submitted"` // 受け付けたタスク数
+	Completed int64             `json:"completed"` // 成功したタスク数
+	Canceled  int64             `json:"canceled"`  // コンテキストの終了で中断したか、開始せずに打ち切ったタスク数
+	Failed    int64             `json:"failed"`    // エラーを返したかパニックしたタスク数
+	InFlight  int64             `json:"in_flight"` // 受け付けてまだ終了していないタスク数
+	Latency   HistogramSnapshot `json:"latency"`   // 実行したタスクの処理時間
+}
+
+// FrameMetrics はある時点のフレームの種類ごとの集計
+type FrameMetrics struct {
+	Total int64   `json:"total"` // 送信したフレーム数
+	FPS   float64 `json:"fps"`   // 直近のFPSWindowで送信した1秒あたりのフレーム数
+}
+
+// MetricsSnapshot はある時点のメトリクスの値
+type MetricsSnapshot struct {
+	Tasks  map[string]TaskMetrics  `json:"tasks"`  // 処理名ごとのタスクの集計
+	Frames map[string]FrameMetrics `json:"frames"` // フレームの種類ごとの集計
+}
+
+// Metrics はプロセッサのタスクとフレームを集計する
+// WithMetricsで複数のプロセッサに指定でき、expvar.Varとhttp.Handlerを実装する
+//
+//	m := synctest.NewMetrics(synctest.MetricsConfig{})
+//	expvar.Publish("synctest", m)
+//	http.Handle("/metrics", m)
+type Metrics struct {
+	clock     Clock
+	namespace string
+	buckets   []float64
+	window    time.Duration
+
+	mu     sync.Mutex
+	tasks  map[string]*taskMetrics
+	frames map[string]*frameMetrics
+}
+
+// taskMetrics は処理1つ分のタスクの集計
+// nilの場合は何も記録しないため、メトリクスを指定していないプロセッサでもそのまま呼び出せる
+type taskMetrics struct {
+	m *Metrics
+
+	submitted, completed, canceled, failed, inFlight int64
+	counts                                           []uint64 // 各バケットに入った観測数。最後は+Inf
+	sum                                              float64
+}
+
+// frameMetrics はフレームの種類1つ分の集計
+type frameMetrics struct {
+	total int64
+	times []time.Time // FPSWindow内に送信した時刻
+}
+
+// NewMetrics はメトリクスを作成する
+// WithClockを指定した場合はその時刻で1秒あたりのフレーム数を計算する。それ以外のOptionは無視する
+func NewMetrics(cfg MetricsConfig, opts ...Option) *Metrics {
+	o := newOptions(opts)
+	m := &Metrics{
+		clock:     o.clock,
+		namespace: cmp.Or(cfg.Namespace, "synctest"),
+		buckets:   slices.Clone(cfg.LatencyBuckets),
+		window:    cfg.FPSWindow,
+		tasks:     make(map[string]*taskMetrics),
+		frames:    make(map[string]*frameMetrics),
+	}
+	if len(m.buckets) == 0 {
+		m.buckets = slices.Clone(DefaultLatencyBuckets)
+	}
+	m.buckets = slices.DeleteFunc(m.buckets, func(b float64) bool { return math.IsInf(b, 1) || math.IsNaN(b) })
+	slices.Sort(m.buckets)
+	m.buckets = slices.Compact(m.buckets)
+	if m.window <= 0 {
+		m.window = time.Second
+	}
+	return m
+}
+
+// WithMetrics はプロセッサのタスクとフレームをmに集計する
+func WithMetrics(m *Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// operation は処理名を設定したオプションを返す。タスクはこの処理名で集計される
+func (o options) operation(name string) options {
+	o.op = name
+	return o
+}
+
+// taskMetrics は処理名のタスクの集計を返す。メトリクスまたは処理名が指定されていない場合はnilを返す
+func (o options) taskMetrics() *taskMetrics {
+	if o.metrics == nil || o.op == "" {
+		return nil
+	}
+	return o.metrics.task(o.op)
+}
+
+// task は処理名のタスクの集計を返す
+func (m *Metrics) task(name string) *taskMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[name]
+	if !ok {
+		t = &taskMetrics{m: m, counts: make([]uint64, len(m.buckets)+1)}
+		m.tasks[name] = t
+	}
+	return t
+}
+
+// submit はタスクを受け付けたことを記録する
+func (t *taskMetrics) submit() {
+	if t == nil {
+		return
+	}
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	t.submitted++
+	t.inFlight++
+}
+
+// skip は受け付けたタスクを開始せずに打ち切ったことを記録する
+func (t *taskMetrics) skip() {
+	if t == nil {
+		return
+	}
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	t.canceled++
+	t.inFlight--
+}
+
+// finish は実行したタスクの結果と処理時間を記録する
+func (t *taskMetrics) finish(err error, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	switch {
+	case err == nil:
+		t.completed++
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		t.canceled++
+	default:
+		t.failed++
+	}
+	t.inFlight--
+
+	seconds := d.Seconds()
+	i, _ := slices.BinarySearch(t.m.buckets, seconds)
+	t.counts[i]++
+	t.sum += seconds
+}
+
+// frame はkindのフレームを1つ送信したことを記録する
+func (m *Metrics) frame(kind string) {
+	if m == nil {
+		return
+	}
+	now := m.clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.frames[kind]
+	if !ok {
+		f = &frameMetrics{}
+		m.frames[kind] = f
+	}
+	f.total++
+	f.times = append(m.pruneLocked(f.times, now), now)
+}
+
+// pruneLocked はFPSWindowより前の時刻を取り除く
+func (m *Metrics) pruneLocked(times []time.Time, now time.Time) []time.Time {
+	from := now.Add(-m.window)
+	i := 0
+	for i < len(times) && !times[i].After(from) {
+		i++
+	}
+	return times[i:]
+}
+
+// Snapshot は現在のメトリクスの値を返す
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	now := m.clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := MetricsSnapshot{
+		Tasks:  make(map[string]TaskMetrics, len(m.tasks)),
+		Frames: make(map[string]FrameMetrics, len(m.frames)),
+	}
+	for name, t := range m.tasks {
+		h := HistogramSnapshot{Buckets: slices.Clone(m.buckets), Counts: make([]uint64, len(m.buckets)), Sum: t.sum}
+		for i, c := range t.counts {
+			h.Count += c
+			if i < len(h.Counts) {
+				h.Counts[i] = h.Count
+			}
+		}
+		s.Tasks[name] = TaskMetrics{
+			Submitted: t.submitted,
+			Completed: t.completed,
+			Canceled:  t.canceled,
+			Failed:    t.failed,
+			InFlight:  t.inFlight,
+			Latency:   h,
+		}
+	}
+	for kind, f := range m.frames {
+		f.times = m.pruneLocked(f.times, now)
+		s.Frames[kind] = FrameMetrics{Total: f.total, FPS: float64(len(f.times)) / m.window.Seconds()}
+	}
+	return s
+}
+
+// String は現在のメトリクスの値をJSONで返す。expvar.Varを実装する
+func (m *Metrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// ServeHTTP は現在のメトリクスの値をPrometheusのテキスト形式で返す
+// 書き出しに失敗した場合に途中までの値を正常な応答として返さないよう、すべて書き出してから送信する
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+	if err := m.WritePrometheus(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	// 送信に失敗するのはクライアントが切断した場合で、Content-Lengthから途中で切れたことが分かる
+	_, _ = buf.WriteTo(w)
+}
+
+// WritePrometheus は現在のメトリクスの値をPrometheusのテキスト形式でwに書き出す
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+	bw := bufio.NewWriter(w)
+	ops := slices.Sorted(maps.Keys(s.Tasks))
+	kinds := slices.Sorted(maps.Keys(s.Frames))
+
+	counter := func(name, help string, value func(TaskMetrics) int64) {
+		m.writeHeader(bw, name, "counter", help)
+		for _, op := range ops {
+			writeSample(bw, m.namespace+"_"+name, "operation", op, "", "", float64(value(s.Tasks[op])))
+		}
+	}
+	counter("tasks_submitted_total", "Number of tasks submitted to the processor.", func(t TaskMetrics) int64 { return t.Submitted })
+	counter("tasks_completed_total", "Number of tasks that completed successfully.", func(t TaskMetrics) int64 { return t.Completed })
+	counter("tasks_canceled_total", "Number of tasks canceled before or while running.", func(t TaskMetrics) int64 { return t.Canceled })
+	counter("tasks_failed_total", "Number of tasks that returned an error or panicked.", func(t TaskMetrics) int64 { return t.Failed })
+
+	m.writeHeader(bw, "tasks_in_flight", "gauge", "Number of tasks submitted but not yet finished.")
+	for _, op := range ops {
+		writeSample(bw, m.namespace+"_tasks_in_flight", "operation", op, "", "", float64(s.Tasks[op].InFlight))
+	}
+
+	m.writeHeader(bw, "task_duration_seconds", "histogram", "Time spent running a task.")
+	for _, op := range ops {
+		h := s.Tasks[op].Latency
+		name := m.namespace + "_task_duration_seconds"
+		for i, le := range h.Buckets {
+			writeSample(bw, name+"_bucket", "operation", op, "le", formatFloat(le), float64(h.Counts[i]))
+		}
+		writeSample(bw, name+"_bucket", "operation", op, "le", "+Inf", float64(h.Count))
+		writeSample(bw, name+"_sum", "operation", op, "", "", h.Sum)
+		writeSample(bw, name+"_count", "operation", op, "", "", float64(h.Count))
+	}
+
+	m.writeHeader(bw, "frames_total", "counter", "Number of frames emitted.")
+	for _, kind := range kinds {
+		writeSample(bw, m.namespace+"_frames_total", "kind", kind, "", "", float64(s.Frames[kind].Total))
+	}
+	m.writeHeader(bw, "frames_per_second", "gauge", "Frames emitted per second over the recent window.")
+	for _, kind := range kinds {
+		writeSample(bw, m.namespace+"_frames_per_second", "kind", kind, "", "", s.Frames[kind].FPS)
+	}
+
+	return bw.Flush()
+}
+
+// writeHeader はメトリクスのHELPとTYPEの行を書き出す
+func (m *Metrics) writeHeader(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n", m.namespace, name, help)
+	fmt.Fprintf(w, "# TYPE %s_%s %s\n", m.namespace, name, typ)
+}
+
+// writeSample はラベルを1つまたは2つ持つサンプルの行を書き出す。key2が空の場合は2つ目のラベルを省略する
+func writeSample(w *bufio.Writer, name, key1, value1, key2, value2 string, v float64) {
+	fmt.Fprintf(w, "%s{%s=\"%s\"", name, key1, escapeLabel(value1))
+	if key2 != "" {
+		fmt.Fprintf(w, ",%s=\"%s\"", key2, escapeLabel(value2))
+	}
+	fmt.Fprintf(w, "} %s\n", formatFloat(v))
+}
+
+// labelEscaper はラベルの値に含められない文字をエスケープする
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel はラベルの値をエスケープする
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// formatFloat はPrometheusのテキスト形式で数値を書き出す
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
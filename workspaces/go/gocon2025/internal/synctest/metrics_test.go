@@ -0,0 +1,296 @@
+package synctest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// published はexpvarに公開した回数
+var published atomic.Int32
+
+// drain はチャネルが閉じられるまで受信して捨てる
+func drain[T any](ch <-chan T) {
+	for range ch {
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("Table Driven Test - タスクの集計", func(t *testing.T) {
+		testCases := []struct {
+			name      string
+			operation string
+			run       func(ctx context.Context, m *synctestpkg.Metrics)
+			timeout   time.Duration
+			expect    synctestpkg.TaskMetrics
+		}{
+			{
+				name:      "すべて完了",
+				operation: "ProcessWithGoroutine",
+				run: func(ctx context.Context, m *synctestpkg.Metrics) {
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithMetrics(m))
+					drain(processor.ProcessWithGoroutine(ctx, []string{"a", "b", "c"}))
+				},
+				expect: synctestpkg.TaskMetrics{Submitted: 3, Completed: 3},
+			},
+			{
+				// 2並列で100msのタスクを処理するため、150msの時点で2つが実行中、
+				// 5つ目は受け付けてキューに積む前にキャンセルされる
+				name:      "実行中と未開始のタスクのキャンセル",
+				operation: "ProcessWithGoroutine",
+				run: func(ctx context.Context, m *synctestpkg.Metrics) {
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithMetrics(m), synctestpkg.WithWorkerPool(2, 0))
+					drain(processor.ProcessWithGoroutine(ctx, []string{"a", "b", "c", "d", "e"}))
+				},
+				timeout: 150 * time.Millisecond,
+				expect:  synctestpkg.TaskMetrics{Submitted: 5, Completed: 2, Canceled: 3},
+			},
+			{
+				name:      "ワーカープール",
+				operation: "ProcessWithPool",
+				run: func(ctx context.Context, m *synctestpkg.Metrics) {
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithMetrics(m), synctestpkg.WithWorkerPool(2, 0))
+					tasks := make(chan string, 3)
+					tasks <- "a"
+					tasks <- "b"
+					tasks <- "c"
+					close(tasks)
+					drain(processor.ProcessWithPool(ctx, tasks))
+				},
+				expect: synctestpkg.TaskMetrics{Submitted: 3, Completed: 3},
+			},
+			{
+				name:      "遅延処理のキャンセル",
+				operation: "ProcessWithDelay",
+				run: func(ctx context.Context, m *synctestpkg.Metrics) {
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithMetrics(m))
+					drain(processor.ProcessWithDelay(ctx, 10*time.Millisecond, "done"))
+					drain(processor.ProcessWithDelay(ctx, time.Second, "canceled"))
+				},
+				timeout: 500 * time.Millisecond,
+				expect:  synctestpkg.TaskMetrics{Submitted: 2, Completed: 1, Canceled: 1},
+			},
+			{
+				name:      "エラーとパニック",
+				operation: "Run",
+				run: func(ctx context.Context, m *synctestpkg.Metrics) {
+					fn := func(_ context.Context, n int) (int, error) {
+						switch n {
+						case 2:
+							return 0, errors.New("失敗")
+						case 3:
+							panic("パニック")
+						}
+						return n, nil
+					}
+					drain(synctestpkg.Run(ctx, []int{1, 2, 3, 4}, fn, synctestpkg.WithMetrics(m)))
+				},
+				expect: synctestpkg.TaskMetrics{Submitted: 4, Completed: 2, Failed: 2},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					ctx := context.Background()
+					if tc.timeout > 0 {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+						defer cancel()
+					}
+					m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{})
+
+					tc.run(ctx, m)
+					synctest.Wait()
+					got := m.Snapshot().Tasks[tc.operation]
+					got.Latency = synctestpkg.HistogramSnapshot{}
+
+					if !reflect.DeepEqual(got, tc.expect) {
+						t.Errorf("集計が期待値と異なります: got %+v, want %+v", got, tc.expect)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("実行中のタスク数", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{})
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithMetrics(m), synctestpkg.WithWorkerPool(2, 0))
+
+			results := processor.ProcessWithGoroutine(context.Background(), []string{"a", "b", "c"})
+			time.Sleep(50 * time.Millisecond)
+			synctest.Wait()
+
+			// 2つが実行中で、3つ目はキューに積む前に受け付けて待っている
+			if got := m.Snapshot().Tasks["ProcessWithGoroutine"]; got.Submitted != 3 || got.InFlight != 3 {
+				t.Errorf("実行中の集計が期待値と異なります: %+v", got)
+			}
+			drain(results)
+			if got := m.Snapshot().Tasks["ProcessWithGoroutine"]; got.InFlight != 0 {
+				t.Errorf("完了後の実行中のタスク数が期待値と異なります: %+v", got)
+			}
+		})
+	})
+
+	t.Run("処理時間のヒストグラム", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{LatencyBuckets: []float64{0.5, 0.1, 0.2}})
+			sleep := func(ctx context.Context, d time.Duration) (struct{}, error) {
+				time.Sleep(d)
+				return struct{}{}, nil
+			}
+
+			durations := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 150 * time.Millisecond, 400 * time.Millisecond, time.Second}
+			drain(synctestpkg.Run(context.Background(), durations, sleep, synctestpkg.WithMetrics(m)))
+
+			// バケットは昇順に並べ替えられ、上限ちょうどの値はそのバケットに入る
+			got := m.Snapshot().Tasks["Run"].Latency
+			if want := []float64{0.1, 0.2, 0.5}; !reflect.DeepEqual(got.Buckets, want) {
+				t.Errorf("バケットが期待値と異なります: got %v, want %v", got.Buckets, want)
+			}
+			if want := []uint64{2, 3, 4}; !reflect.DeepEqual(got.Counts, want) || got.Count != 5 {
+				t.Errorf("観測数が期待値と異なります: got %v (%d), want %v (5)", got.Counts, got.Count, want)
+			}
+			if math.Abs(got.Sum-1.7) > 1e-9 {
+				t.Errorf("観測値の合計が期待値と異なります: got %v, want 1.7", got.Sum)
+			}
+		})
+	})
+
+	t.Run("フレーム数と1秒あたりのフレーム数", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{})
+			processor := synctestpkg.NewVideoProcessor(synctestpkg.WithMetrics(m))
+
+			// 50msごとに10フレームを生成し、500msに完了する
+			drain(processor.GenerateFrames(context.Background(), 10))
+			if got := m.Snapshot().Frames["generate"]; got.Total != 10 || got.FPS != 10 {
+				t.Errorf("完了時点の集計が期待値と異なります: %+v", got)
+			}
+
+			// 1100msの時点では、直近1秒の150ms以降に生成した8フレームだけを数える
+			time.Sleep(600 * time.Millisecond)
+			if got := m.Snapshot().Frames["generate"]; got.Total != 10 || got.FPS != 8 {
+				t.Errorf("時間が経過した後の集計が期待値と異なります: %+v", got)
+			}
+
+			drain(processor.RenderFrames(context.Background(), synctestpkg.RenderConfig{Width: 2, Height: 2, TotalFrames: 3}))
+			pb := processor.Play(context.Background(), synctestpkg.PlaybackConfig{TotalFrames: 4})
+			drain(pb.Frames())
+			frames := m.Snapshot().Frames
+			if frames["render"].Total != 3 || frames["playback"].Total != 4 {
+				t.Errorf("描画と再生のフレーム数が期待値と異なります: %+v", frames)
+			}
+		})
+	})
+
+	t.Run("Prometheusのテキスト形式", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{Namespace: "demo", LatencyBuckets: []float64{0.1, 1}})
+			task := synctestpkg.NewTaskProcessor(synctestpkg.WithMetrics(m))
+			video := synctestpkg.NewVideoProcessor(synctestpkg.WithMetrics(m))
+
+			drain(task.ProcessWithGoroutine(context.Background(), []string{"a", "b"}))
+			drain(video.GenerateFrames(context.Background(), 2))
+
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+			if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+				t.Errorf("Content-Typeが期待値と異なります: %q", ct)
+			}
+			want := `# HELP demo_tasks_submitted_total Number of tasks submitted to the processor.
+# TYPE demo_tasks_submitted_total counter
+demo_tasks_submitted_total{operation="ProcessWithGoroutine"} 2
+# HELP demo_tasks_completed_total Number of tasks that completed successfully.
+# TYPE demo_tasks_completed_total counter
+demo_tasks_completed_total{operation="ProcessWithGoroutine"} 2
+# HELP demo_tasks_canceled_total Number of tasks canceled before or while running.
+# TYPE demo_tasks_canceled_total counter
+demo_tasks_canceled_total{operation="ProcessWithGoroutine"} 0
+# HELP demo_tasks_failed_total Number of tasks that returned an error or panicked.
+# TYPE demo_tasks_failed_total counter
+demo_tasks_failed_total{operation="ProcessWithGoroutine"} 0
+# HELP demo_tasks_in_flight Number of tasks submitted but not yet finished.
+# TYPE demo_tasks_in_flight gauge
+demo_tasks_in_flight{operation="ProcessWithGoroutine"} 0
+# HELP demo_task_duration_seconds Time spent running a task.
+# TYPE demo_task_duration_seconds histogram
+demo_task_duration_seconds_bucket{operation="ProcessWithGoroutine",le="0.1"} 2
+demo_task_duration_seconds_bucket{operation="ProcessWithGoroutine",le="1"} 2
+demo_task_duration_seconds_bucket{operation="ProcessWithGoroutine",le="+Inf"} 2
+demo_task_duration_seconds_sum{operation="ProcessWithGoroutine"} 0.2
+demo_task_duration_seconds_count{operation="ProcessWithGoroutine"} 2
+# HELP demo_frames_total Number of frames emitted.
+# TYPE demo_frames_total counter
+demo_frames_total{kind="generate"} 2
+# HELP demo_frames_per_second Frames emitted per second over the recent window.
+# TYPE demo_frames_per_second gauge
+demo_frames_per_second{kind="generate"} 2
+`
+			if got := rec.Body.String(); got != want {
+				t.Errorf("出力が期待値と異なります:\n got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	})
+
+	t.Run("+Infのバケットを指定しても+Infの行は1つだけ出力する", func(t *testing.T) {
+		m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{LatencyBuckets: []float64{math.Inf(1), 1, math.NaN()}})
+		fn := func(_ context.Context, n int) (int, error) { return n, nil }
+		drain(synctestpkg.Run(context.Background(), []int{1}, fn, synctestpkg.WithMetrics(m)))
+
+		if got := m.Snapshot().Tasks["Run"].Latency.Buckets; !reflect.DeepEqual(got, []float64{1}) {
+			t.Errorf("バケットが期待値と異なります: got %v, want [1]", got)
+		}
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if n := strings.Count(rec.Body.String(), `le="+Inf"`); n != 1 {
+			t.Errorf("+Infのバケットの行数が期待値と異なります: got %d, want 1\n%s", n, rec.Body.String())
+		}
+	})
+
+	t.Run("既定の名前空間", func(t *testing.T) {
+		m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{})
+		fn := func(_ context.Context, n int) (int, error) { return n, nil }
+		drain(synctestpkg.Run(context.Background(), []int{1}, fn, synctestpkg.WithMetrics(m)))
+
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if body := rec.Body.String(); !strings.Contains(body, `synctest_tasks_submitted_total{operation="Run"} 1`) {
+			t.Errorf("既定の名前空間で出力されていません:\n%s", body)
+		}
+	})
+
+	t.Run("expvarで公開する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			m := synctestpkg.NewMetrics(synctestpkg.MetricsConfig{})
+			processor := synctestpkg.NewVideoProcessor(synctestpkg.WithMetrics(m))
+			drain(processor.GenerateFrames(context.Background(), 3))
+
+			// expvarの名前は再利用できないため、-countで繰り返し実行しても重複しない名前にする
+			name := fmt.Sprintf("synctest_metrics_test_%d", published.Add(1))
+			expvar.Publish(name, m)
+			var got synctestpkg.MetricsSnapshot
+			if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+				t.Fatalf("JSONとして読み込めません: %v", err)
+			}
+
+			if want := m.Snapshot(); !reflect.DeepEqual(got, want) {
+				t.Errorf("公開した値が期待値と異なります: got %+v, want %+v", got, want)
+			}
+		})
+	})
+}
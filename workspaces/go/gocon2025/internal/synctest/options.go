@@ -0,0 +1,45 @@
+package synctest
+
+import "github.com/connect0459/connect-lab/go/gocon2025/internal/singleflight"
+
+// Option はプロセッサの設定を変更する関数
+type Option func(*options)
+
+// options はプロセッサの設定値を保持する
+type options struct {
+	clock       Clock
+	pool        *poolConfig                           // nilの場合はタスクごとにゴルーチンを起動する
+	order       *orderConfig                          // nilの場合は完了順に結果を送信する
+	limiter     Limiter                               // nilの場合は流量制限しない
+	breaker     CircuitBreaker                        // nilの場合はサーキットブレーカーを利用しない
+	dedup       *singleflight.Group[delayKey, string] // nilの場合は重複した呼び出しをまとめない
+	supervisor  Supervisor                            // nilの場合はゴルーチンを監視しない
+	renderer    FrameRenderer                         // nilの場合はGradientRendererで描画する
+	checkpoints *checkpointConfig                     // nilの場合は描画の進捗を保存しない
+	metrics     *Metrics                              // nilの場合は集計しない
+	tracer      Tracer                                // nilの場合はスパンを記録しない
+	registry    *TaskRegistry                         // nilの場合はタスクを登録しない
+	op          string                                // タスクを集計する処理名。空の場合はタスクを集計しない
+}
+
+// newOptions は既定値にOptionを適用した設定を作成する
+func newOptions(opts []Option) options {
+	o := options{
+		clock: NewRealClock(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock はプロセッサが利用するClockを指定する
+// nilを指定した場合はシステムクロックを利用する
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		if c == nil {
+			c = NewRealClock()
+		}
+		o.clock = c
+	}
+}
@@ -0,0 +1,53 @@
+package synctest
+
+import "context"
+
+// orderConfig は結果を入力の順序で送信するモードの設定
+type orderConfig struct {
+	maxAhead int // 未送信の最も古い入力から数えて開始できる入力数の上限
+}
+
+// WithOrderedResults は並行実行した結果を完了順ではなく入力の順序で送信する
+// maxAheadは未送信の最も古い入力から数えて何件先まで開始してよいかの上限で、
+// 並べ替えのために保持する結果の数もmaxAhead件に収まる。
+// 0以下の場合はワーカープールの同時実行数の2倍を上限とする
+// VideoProcessorのフレームは常に番号順に送信され、maxAheadは並べ替えのために保持するフレーム数の上限となる
+// RenderFramesでは描画中と並べ替え待ちの画像がmaxAhead枚に収まるため、メモリ使用量の上限として利用できる
+func WithOrderedResults(maxAhead int) Option {
+	return func(o *options) {
+		o.order = &orderConfig{maxAhead: maxAhead}
+	}
+}
+
+// window は先行できる入力数の上限を返す
+func (c *orderConfig) window(pool *poolConfig) int {
+	if c.maxAhead > 0 {
+		return c.maxAhead
+	}
+	return 2 * pool.normalized().concurrency
+}
+
+// reorder は完了順に届く結果を入力の順序に並べ替えて送信し、送信するたびにslotsを1つ解放する
+// inが閉じられると、送信できなかった結果を破棄してoutを閉じる
+func reorder[R any](ctx context.Context, in <-chan Result[R], out chan<- Result[R], slots <-chan struct{}) {
+	defer close(out)
+
+	pending := make(map[int]Result[R])
+	next := 0
+	for r := range in {
+		pending[r.Index] = r
+		for {
+			head, ok := pending[next]
+			if !ok || ctx.Err() != nil {
+				break
+			}
+			delete(pending, next)
+			select {
+			case out <- head:
+			case <-ctx.Done():
+			}
+			<-slots
+			next++
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package synctest_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// durationsInSeconds は入力の秒数だけ待機してから入力をそのまま返す処理
+func durationsInSeconds(ctx context.Context, seconds int) (int, error) {
+	select {
+	case <-time.After(time.Duration(seconds) * time.Second):
+		return seconds, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func TestOrderedResults(t *testing.T) {
+	t.Run("Table Driven Test - 結果は入力の順序で届く", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			inputs []int
+			opts   []synctestpkg.Option
+		}{
+			{
+				name:   "無制限モード",
+				inputs: []int{5, 3, 1, 4, 2},
+				opts:   []synctestpkg.Option{synctestpkg.WithOrderedResults(10)},
+			},
+			{
+				name:   "ワーカープールモード",
+				inputs: []int{2, 1, 3, 1, 1, 2, 5, 1},
+				opts:   []synctestpkg.Option{synctestpkg.WithWorkerPool(3, 2), synctestpkg.WithOrderedResults(0)},
+			},
+			{
+				name:   "先行数1は逐次実行と同じ",
+				inputs: []int{3, 1, 2},
+				opts:   []synctestpkg.Option{synctestpkg.WithWorkerPool(4, 4), synctestpkg.WithOrderedResults(1)},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					var indices []int
+					for r := range synctestpkg.Run(context.Background(), tc.inputs, durationsInSeconds, tc.opts...) {
+						indices = append(indices, r.Index)
+						if r.Value != tc.inputs[r.Index] {
+							t.Errorf("入力%dの値が期待値と異なります: got %d, want %d", r.Index, r.Value, tc.inputs[r.Index])
+						}
+					}
+
+					want := make([]int, len(tc.inputs))
+					for i := range want {
+						want[i] = i
+					}
+					if !slices.Equal(indices, want) {
+						t.Errorf("結果の順序が期待値と異なります: got %v, want %v", indices, want)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 先頭の遅いタスクと先行数の上限", func(t *testing.T) {
+		// 先頭だけ5秒かかり、残りは1秒で終わる
+		inputs := []int{5, 1, 1, 1, 1, 1}
+
+		testCases := []struct {
+			name          string
+			maxAhead      int
+			expectStarts  []time.Duration
+			expectElapsed time.Duration
+		}{
+			{
+				name:          "先行数の上限で後続の開始が待たされる",
+				maxAhead:      3,
+				expectStarts:  []time.Duration{0, 0, 0, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+				expectElapsed: 6 * time.Second,
+			},
+			{
+				name:          "先行数に余裕があれば後続は先に進む",
+				maxAhead:      10,
+				expectStarts:  []time.Duration{0, 0, 0, time.Second, time.Second, 2 * time.Second},
+				expectElapsed: 5 * time.Second,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					start := time.Now()
+					opts := []synctestpkg.Option{synctestpkg.WithWorkerPool(3, 10), synctestpkg.WithOrderedResults(tc.maxAhead)}
+
+					var received []synctestpkg.Result[int]
+					var arrivals []time.Duration
+					for r := range synctestpkg.Run(context.Background(), inputs, durationsInSeconds, opts...) {
+						received = append(received, r)
+						arrivals = append(arrivals, time.Since(start))
+					}
+
+					if len(received) != len(inputs) {
+						t.Fatalf("結果の数が期待値と異なります: got %d, want %d", len(received), len(inputs))
+					}
+					for i, r := range received {
+						if r.Index != i {
+							t.Errorf("%d番目の結果のインデックスが期待値と異なります: got %d", i, r.Index)
+						}
+						if got := r.StartedAt.Sub(start); got != tc.expectStarts[i] {
+							t.Errorf("入力%dの開始時刻が期待値と異なります: got %v, want %v", i, got, tc.expectStarts[i])
+						}
+					}
+					// 先頭が終わるまではどの結果も届かない
+					if arrivals[0] != 5*time.Second {
+						t.Errorf("最初の結果が届いた時刻が期待値と異なります: got %v, want 5s", arrivals[0])
+					}
+					if elapsed := time.Since(start); elapsed != tc.expectElapsed {
+						t.Errorf("経過時間が期待値と異なります: got %v, want %v", elapsed, tc.expectElapsed)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("先頭が終わる前にキャンセルするとチャネルは空のまま閉じられる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			time.AfterFunc(3*time.Second, cancel)
+
+			count := 0
+			for range synctestpkg.Run(ctx, []int{5, 1, 1, 1}, durationsInSeconds, synctestpkg.WithOrderedResults(2)) {
+				count++
+			}
+
+			if count != 0 {
+				t.Errorf("キャンセル後に結果が届きました: got %d", count)
+			}
+		})
+	})
+
+	t.Run("ProcessWithGoroutineも入力の順序で結果を返す", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(3, 3), synctestpkg.WithOrderedResults(3))
+			tasks := taskNames(10)
+
+			var got []string
+			for r := range processor.ProcessWithGoroutine(context.Background(), tasks) {
+				got = append(got, r.Value)
+			}
+
+			want := make([]string, len(tasks))
+			for i, task := range tasks {
+				want[i] = "タスク完了: " + task
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("結果の順序が期待値と異なります:\n got  %v\n want %v", got, want)
+			}
+		})
+	})
+}
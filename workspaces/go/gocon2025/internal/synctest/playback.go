@@ -0,0 +1,406 @@
+package synctest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPlaybackStopped はStopで再生を終了したことを表す
+var ErrPlaybackStopped = errors.New("synctest: playback stopped")
+
+// defaultPlaybackFPS は再生のフレームレートの既定値。フレームの生成速度と同じ
+const defaultPlaybackFPS = float64(time.Second / frameDuration)
+
+// PlaybackConfig は再生の設定
+type PlaybackConfig struct {
+	TotalFrames int           // 総フレーム数
+	FPS         float64       // 1秒あたりに表示するフレーム数。0以下の場合は20fps
+	MaxLateness time.Duration // 表示予定時刻からこれ以上遅れたフレームは捨てる。0以下の場合は1フレームの表示時間
+}
+
+// interval は1フレームの表示時間を返す
+func (c PlaybackConfig) interval() time.Duration {
+	return time.Duration(float64(time.Second) / c.FPS)
+}
+
+// normalized は既定値を補った設定を返す
+func (c PlaybackConfig) normalized() PlaybackConfig {
+	if c.FPS <= 0 {
+		c.FPS = defaultPlaybackFPS
+	}
+	if c.MaxLateness <= 0 {
+		c.MaxLateness = c.interval()
+	}
+	return c
+}
+
+// PlaybackStats はある時点の再生の統計
+type PlaybackStats struct {
+	Position    int           // 最後に表示したフレーム番号。まだ表示していない場合は0
+	Presented   int           // 表示したフレーム数
+	Dropped     int           // 生成が間に合わずに捨てたフレーム数。Seekで飛ばしたフレームは含まない
+	Late        int           // 表示予定時刻より遅れて表示したフレーム数
+	MaxLateness time.Duration // 表示したフレームの最大の遅れ
+	Paused      bool          // 一時停止しているかどうか
+}
+
+// Playback はPlayで開始した再生のハンドル
+type Playback interface {
+	// Frames は表示予定時刻になったフレーム番号を受信するチャネルを返す。再生が終了すると閉じられる
+	Frames() <-chan int
+	// Pause は再生を一時停止する。生成中のフレームは生成を終えて再開を待つ
+	Pause()
+	// Resume は一時停止した位置から再生を再開する
+	Resume()
+	// Seek は指定したフレームに移動する。範囲外の場合は最初または最後のフレームに移動する
+	Seek(frame int)
+	// Stats は現在の統計を返す
+	Stats() PlaybackStats
+	// Stop は再生を終了する
+	Stop()
+	// Done は再生が終了すると閉じられるチャネルを返す
+	Done() <-chan struct{}
+	// Err は再生が終了した理由を返す。Doneが閉じられる前と、最後のフレームまで再生した場合はnil
+	// Stopで終了した場合はErrPlaybackStopped、コンテキストが終了した場合はその原因、
+	// 停止処理中のため開始しなかった場合はErrShuttingDownとなる
+	Err() error
+}
+
+// playback はPlaybackの具象実装
+type playback struct {
+	clock    Clock
+	metrics  *Metrics
+	tracer   Tracer
+	cfg      PlaybackConfig
+	generate func(ctx context.Context, index int) FrameResult // GenerateFramesと同じ方法で1フレームを生成する
+	cancel   context.CancelCauseFunc
+	frames   chan int
+	notify   chan struct{} // 一時停止や移動の要求を再生のゴルーチンに知らせる
+	done     chan struct{}
+
+	mu     sync.Mutex
+	paused bool // 要求された一時停止の状態
+	seekTo int  // 要求された移動先。0の場合は移動しない
+	stats  PlaybackStats
+	err    error // 再生が終了した理由
+}
+
+// generation は生成中のフレーム
+type generation struct {
+	result <-chan FrameResult
+	cancel context.CancelFunc
+}
+
+// stop は生成を取りやめる
+func (g *generation) stop() {
+	if g != nil {
+		g.cancel()
+	}
+}
+
+// playhead は再生のゴルーチンだけが扱う再生位置
+type playhead struct {
+	next       int           // 次に生成するフレーム番号
+	generating int           // 生成中のフレーム番号。0の場合は生成していない
+	genStarted time.Time     // 生成を開始した時刻
+	genCost    time.Duration // 直前のフレームの生成にかかった時間
+	pending    int           // 生成を終えて表示を待っているフレーム番号。0の場合はない
+	anchor     int           // 表示予定時刻の基準にするフレーム番号。0の場合は次に生成したフレームを基準にする
+	base       time.Time     // anchorを表示する時刻
+	paused     bool
+	pausedAt   time.Time
+	span       Span // 生成中または表示を待っているフレームのスパン
+}
+
+// due はフレームの表示予定時刻を返す
+func (h *playhead) due(frame int, interval time.Duration) time.Time {
+	return h.base.Add(time.Duration(frame-h.anchor) * interval)
+}
+
+// position は時刻tに表示しているはずのフレーム番号を返す
+func (h *playhead) position(t time.Time, interval time.Duration) int {
+	if t.Before(h.base) {
+		return h.anchor
+	}
+	return h.anchor + int(t.Sub(h.base)/interval)
+}
+
+// Play はGenerateFramesと同じ方法でフレームを生成し、指定したフレームレートに合わせて送信する
+// 最初のフレームを生成した時刻を基準に各フレームの表示予定時刻を決め、それまで送信を待つ
+// 生成が表示に追いつかない場合は間に合わないフレームの生成を飛ばし、MaxLatenessより遅れたフレームは捨てる
+// 受信が遅れてMaxLatenessを過ぎた場合も同様に捨てる
+// 再生が終了した理由は、Doneが閉じられた後にErrで確認できる
+func (p *videoProcessor) Play(ctx context.Context, cfg PlaybackConfig) Playback {
+	ctx, cancel := context.WithCancelCause(ctx)
+	pb := &playback{
+		clock:    p.clock,
+		metrics:  p.metrics,
+		tracer:   p.tracer,
+		cfg:      cfg.normalized(),
+		generate: p.generateFrame,
+		cancel:   cancel,
+		frames:   make(chan int),
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	started := p.spawn(ctx, "Play", pb.run)
+	if !started {
+		cancel(ErrShuttingDown)
+		pb.err = ErrShuttingDown
+		close(pb.frames)
+		close(pb.done)
+	}
+
+	return pb
+}
+
+// run はフレームの生成と表示を1つのゴルーチンで進める
+func (pb *playback) run(ctx context.Context) {
+	defer close(pb.done)
+	defer close(pb.frames)
+	defer pb.cancel(nil)
+
+	interval := pb.cfg.interval()
+	h := &playhead{next: 1, genCost: frameDuration, span: noopSpan{}}
+	var gen *generation
+	defer func() {
+		gen.stop()
+		h.span.End()
+		if ctx.Err() != nil {
+			pb.mu.Lock()
+			pb.err = context.Cause(ctx)
+			pb.mu.Unlock()
+		}
+	}()
+
+	for {
+		now := pb.clock.Now()
+		if !h.paused && h.generating == 0 && h.pending == 0 {
+			if h.next > pb.cfg.TotalFrames {
+				return
+			}
+			// 生成を終える時刻に表示しているはずのフレームまで飛ばす
+			frame, skipped := h.next, 0
+			if h.anchor != 0 {
+				if target := min(h.position(now.Add(h.genCost), interval), pb.cfg.TotalFrames); target > frame {
+					skipped = target - frame
+					pb.record(func(s *PlaybackStats) { s.Dropped += skipped })
+					frame = target
+				}
+			}
+			h.span = pb.startFrame(ctx, frame, skipped)
+			h.generating, h.genStarted = frame, now
+			gen = pb.start(ctx, frame)
+		}
+
+		var genC <-chan FrameResult
+		var dueC <-chan time.Time
+		if gen != nil {
+			genC = gen.result
+		}
+		var due Timer
+		if !h.paused && h.pending != 0 {
+			wait := h.due(h.pending, interval).Sub(now)
+			if wait <= 0 {
+				if !pb.present(ctx, h, now, interval) {
+					return
+				}
+				continue
+			}
+			due = pb.clock.NewTimer(wait)
+			dueC = due.C()
+		}
+
+		select {
+		case f := <-genC:
+			gen.stop()
+			gen = nil
+			if f.Err != nil {
+				// 生成を取りやめるのは再生を終了する場合だけ
+				h.span.RecordError(f.Err)
+				return
+			}
+			now := pb.clock.Now()
+			h.pending, h.generating = h.generating, 0
+			h.next = h.pending + 1
+			h.genCost = now.Sub(h.genStarted)
+			if h.anchor == 0 {
+				h.anchor, h.base = h.pending, now
+			}
+		case <-dueC:
+		case <-pb.notify:
+			gen = pb.apply(h, gen)
+		case <-ctx.Done():
+		}
+		if due != nil {
+			due.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// present は表示を待っているフレームを送信する
+// 表示予定時刻からMaxLatenessより遅れたフレームは、生成と受信のどちらが遅れた場合も送信せずに捨てる
+// 受信を待っている間に一時停止や移動を要求された場合は送信を取りやめる
+// コンテキストが終了した場合はfalseを返す
+func (pb *playback) present(ctx context.Context, h *playhead, now time.Time, interval time.Duration) bool {
+	frame := h.pending
+	deadline := h.due(frame, interval).Add(pb.cfg.MaxLateness)
+	if !now.Before(deadline) {
+		h.pending = 0
+		h.span.AddEvent("dropped")
+		h.span.End()
+		pb.record(func(s *PlaybackStats) { s.Dropped++ })
+		return true
+	}
+	expired := pb.clock.NewTimer(deadline.Sub(now))
+	defer expired.Stop()
+
+	select {
+	case pb.frames <- frame:
+		h.pending = 0
+		pb.metrics.frame(frameKindPlayback)
+		late := pb.clock.Now().Sub(h.due(frame, interval))
+		h.span.AddEvent("presented", Attr("late", late.String()))
+		h.span.End()
+		pb.record(func(s *PlaybackStats) {
+			s.Position = frame
+			s.Presented++
+			if late > 0 {
+				s.Late++
+				s.MaxLateness = max(s.MaxLateness, late)
+			}
+		})
+		return true
+	case <-expired.C():
+		h.pending = 0
+		h.span.AddEvent("dropped")
+		h.span.End()
+		pb.record(func(s *PlaybackStats) { s.Dropped++ })
+		return true
+	case <-pb.notify:
+		pb.apply(h, nil)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// start はframeの生成を開始する
+func (pb *playback) start(ctx context.Context, frame int) *generation {
+	ctx, cancel := context.WithCancel(ctx)
+	result := make(chan FrameResult, 1)
+	go func() {
+		result <- pb.generate(ctx, frame)
+	}()
+	return &generation{result: result, cancel: cancel}
+}
+
+// apply は要求された一時停止と移動を再生位置に反映する
+// 移動した場合は生成中のフレームを取りやめてnilを返し、そうでない場合はgenをそのまま返す
+func (pb *playback) apply(h *playhead, gen *generation) *generation {
+	pb.mu.Lock()
+	paused, seekTo := pb.paused, pb.seekTo
+	pb.seekTo = 0
+	pb.mu.Unlock()
+
+	now := pb.clock.Now()
+	if seekTo != 0 {
+		gen.stop()
+		gen = nil
+		if h.generating != 0 || h.pending != 0 {
+			h.span.AddEvent("seeked", Attr("to", seekTo))
+			h.span.End()
+		}
+		h.generating, h.pending = 0, 0
+		h.next, h.anchor = seekTo, 0
+	}
+	switch {
+	case paused && !h.paused:
+		h.paused, h.pausedAt = true, now
+	case !paused && h.paused:
+		h.paused = false
+		// 一時停止していた時間だけ表示予定時刻を遅らせる
+		h.base = h.base.Add(now.Sub(h.pausedAt))
+	}
+	return gen
+}
+
+// startFrame はframeを生成するスパンを開始する。skippedは生成を飛ばしたフレーム数
+func (pb *playback) startFrame(ctx context.Context, frame, skipped int) Span {
+	if pb.tracer == nil {
+		return noopSpan{}
+	}
+	_, span := pb.tracer.Start(ctx, "frame", Attr("index", frame), Attr("kind", frameKindPlayback), Attr("skipped", skipped))
+	return span
+}
+
+// record は統計を更新する
+func (pb *playback) record(update func(s *PlaybackStats)) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	update(&pb.stats)
+}
+
+// request は一時停止や移動の要求を記録して再生のゴルーチンに知らせる
+func (pb *playback) request(update func()) {
+	pb.mu.Lock()
+	update()
+	pb.mu.Unlock()
+
+	select {
+	case pb.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Frames は表示予定時刻になったフレーム番号を受信するチャネルを返す
+func (pb *playback) Frames() <-chan int {
+	return pb.frames
+}
+
+// Pause は再生を一時停止する
+func (pb *playback) Pause() {
+	pb.request(func() { pb.paused = true })
+}
+
+// Resume は一時停止した位置から再生を再開する
+func (pb *playback) Resume() {
+	pb.request(func() { pb.paused = false })
+}
+
+// Seek は指定したフレームに移動する
+func (pb *playback) Seek(frame int) {
+	pb.request(func() { pb.seekTo = min(max(frame, 1), max(pb.cfg.TotalFrames, 1)) })
+}
+
+// Stats は現在の統計を返す
+func (pb *playback) Stats() PlaybackStats {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	s := pb.stats
+	s.Paused = pb.paused
+	return s
+}
+
+// Stop は再生を終了する
+func (pb *playback) Stop() {
+	pb.cancel(ErrPlaybackStopped)
+}
+
+// Done は再生が終了すると閉じられるチャネルを返す
+func (pb *playback) Done() <-chan struct{} {
+	return pb.done
+}
+
+// Err は再生が終了した理由を返す
+func (pb *playback) Err() error {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.err
+}
@@ -0,0 +1,243 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// shownFrame は受信したフレーム番号と、再生を開始してから受信するまでの時間
+type shownFrame struct {
+	Index int
+	At    time.Duration
+}
+
+// watchPlayback はチャネルが閉じられるまでフレームを受信して返す
+func watchPlayback(pb synctestpkg.Playback) []shownFrame {
+	start := time.Now()
+	var shown []shownFrame
+	for i := range pb.Frames() {
+		shown = append(shown, shownFrame{Index: i, At: time.Since(start)})
+	}
+	return shown
+}
+
+// at は受信までの時間をミリ秒で指定したshownFrameを作成する
+func at(index int, ms int) shownFrame {
+	return shownFrame{Index: index, At: time.Duration(ms) * time.Millisecond}
+}
+
+func TestPlayback(t *testing.T) {
+	t.Run("Table Driven Test - フレームレートに合わせた送信とフレームの破棄", func(t *testing.T) {
+		// フレームの生成には50msかかり、最初のフレームを生成した50ms後を基準に表示予定時刻を決める
+		testCases := []struct {
+			name        string
+			cfg         synctestpkg.PlaybackConfig
+			expectShown []shownFrame
+			expectStats synctestpkg.PlaybackStats
+		}{
+			{
+				name:        "生成が表示より速い場合は表示予定時刻まで待つ",
+				cfg:         synctestpkg.PlaybackConfig{TotalFrames: 4, FPS: 10},
+				expectShown: []shownFrame{at(1, 50), at(2, 150), at(3, 250), at(4, 350)},
+				expectStats: synctestpkg.PlaybackStats{Position: 4, Presented: 4},
+			},
+			{
+				name:        "生成と表示が同じ速度",
+				cfg:         synctestpkg.PlaybackConfig{TotalFrames: 3},
+				expectShown: []shownFrame{at(1, 50), at(2, 100), at(3, 150)},
+				expectStats: synctestpkg.PlaybackStats{Position: 3, Presented: 3},
+			},
+			{
+				// 40fpsでは1フレームの表示時間が25msのため、生成を1フレームおきに飛ばす
+				// 10フレーム目は飛ばす先がなく、表示予定時刻から25ms遅れるため捨てる
+				name:        "生成が追いつかない場合は間に合わないフレームを飛ばす",
+				cfg:         synctestpkg.PlaybackConfig{TotalFrames: 10, FPS: 40},
+				expectShown: []shownFrame{at(1, 50), at(3, 100), at(5, 150), at(7, 200), at(9, 250)},
+				expectStats: synctestpkg.PlaybackStats{Position: 9, Presented: 5, Dropped: 5},
+			},
+			{
+				// 25fpsでは1フレームの表示時間が40msのため、生成のたびに10msずつ遅れが増える
+				name: "許容範囲内の遅れは遅延として表示する",
+				cfg:  synctestpkg.PlaybackConfig{TotalFrames: 8, FPS: 25},
+				expectShown: []shownFrame{
+					at(1, 50), at(2, 100), at(3, 150), at(4, 200), at(6, 250), at(7, 300), at(8, 350),
+				},
+				expectStats: synctestpkg.PlaybackStats{Position: 8, Presented: 7, Dropped: 1, Late: 5, MaxLateness: 30 * time.Millisecond},
+			},
+			{
+				name:        "許容範囲を超えて遅れたフレームは捨てる",
+				cfg:         synctestpkg.PlaybackConfig{TotalFrames: 8, FPS: 25, MaxLateness: 15 * time.Millisecond},
+				expectShown: []shownFrame{at(1, 50), at(2, 100), at(6, 250), at(7, 300)},
+				expectStats: synctestpkg.PlaybackStats{Position: 7, Presented: 4, Dropped: 4, Late: 2, MaxLateness: 10 * time.Millisecond},
+			},
+			{
+				name:        "フレームがない",
+				cfg:         synctestpkg.PlaybackConfig{TotalFrames: 0},
+				expectShown: nil,
+				expectStats: synctestpkg.PlaybackStats{},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					processor := synctestpkg.NewVideoProcessor()
+
+					pb := processor.Play(context.Background(), tc.cfg)
+					shown := watchPlayback(pb)
+
+					if !slices.Equal(shown, tc.expectShown) {
+						t.Errorf("受信したフレームが期待値と異なります:\n got %v\nwant %v", shown, tc.expectShown)
+					}
+					if stats := pb.Stats(); stats != tc.expectStats {
+						t.Errorf("統計が期待値と異なります: got %+v, want %+v", stats, tc.expectStats)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 一時停止と移動", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			control     func(pb synctestpkg.Playback)
+			expectShown []shownFrame
+			expectStats synctestpkg.PlaybackStats
+			expectErr   error
+		}{
+			{
+				// 生成中の3フレーム目は200msに生成を終え、再開するまで表示を待つ
+				name: "一時停止した時間だけ表示予定時刻が遅れる",
+				control: func(pb synctestpkg.Playback) {
+					time.Sleep(175 * time.Millisecond)
+					pb.Pause()
+					time.Sleep(time.Second)
+					pb.Resume()
+				},
+				expectShown: []shownFrame{at(1, 50), at(2, 150), at(3, 1250), at(4, 1350), at(5, 1450)},
+				expectStats: synctestpkg.PlaybackStats{Position: 5, Presented: 5},
+			},
+			{
+				// 生成中の3フレーム目を取りやめ、移動先のフレームを生成した時刻を新しい基準にする
+				name: "移動した位置から再生する",
+				control: func(pb synctestpkg.Playback) {
+					time.Sleep(175 * time.Millisecond)
+					pb.Seek(4)
+				},
+				expectShown: []shownFrame{at(1, 50), at(2, 150), at(4, 225), at(5, 325)},
+				expectStats: synctestpkg.PlaybackStats{Position: 5, Presented: 4},
+			},
+			{
+				name: "一時停止中に移動した場合は再開してから移動先を生成する",
+				control: func(pb synctestpkg.Playback) {
+					time.Sleep(175 * time.Millisecond)
+					pb.Pause()
+					pb.Seek(1)
+					time.Sleep(time.Second)
+					pb.Resume()
+				},
+				expectShown: []shownFrame{
+					at(1, 50), at(2, 150),
+					at(1, 1225), at(2, 1325), at(3, 1425), at(4, 1525), at(5, 1625),
+				},
+				expectStats: synctestpkg.PlaybackStats{Position: 5, Presented: 7},
+			},
+			{
+				name:        "範囲外への移動は最後のフレームに移動する",
+				control:     func(pb synctestpkg.Playback) { pb.Seek(100) },
+				expectShown: []shownFrame{at(5, 50)},
+				expectStats: synctestpkg.PlaybackStats{Position: 5, Presented: 1},
+			},
+			{
+				name: "Stopで終了する",
+				control: func(pb synctestpkg.Playback) {
+					time.Sleep(175 * time.Millisecond)
+					pb.Stop()
+				},
+				expectShown: []shownFrame{at(1, 50), at(2, 150)},
+				expectStats: synctestpkg.PlaybackStats{Position: 2, Presented: 2},
+				expectErr:   synctestpkg.ErrPlaybackStopped,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					processor := synctestpkg.NewVideoProcessor()
+
+					pb := processor.Play(context.Background(), synctestpkg.PlaybackConfig{TotalFrames: 5, FPS: 10})
+					go tc.control(pb)
+					shown := watchPlayback(pb)
+					<-pb.Done()
+
+					// 最後のフレームまで再生した場合は終了の理由はnil
+					if err := pb.Err(); !errors.Is(err, tc.expectErr) {
+						t.Errorf("終了の理由が期待値と異なります: got %v, want %v", err, tc.expectErr)
+					}
+					if !slices.Equal(shown, tc.expectShown) {
+						t.Errorf("受信したフレームが期待値と異なります:\n got %v\nwant %v", shown, tc.expectShown)
+					}
+					if stats := pb.Stats(); stats != tc.expectStats {
+						t.Errorf("統計が期待値と異なります: got %+v, want %+v", stats, tc.expectStats)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("一時停止中の統計", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewVideoProcessor()
+			ctx, cancel := context.WithCancel(context.Background())
+
+			pb := processor.Play(ctx, synctestpkg.PlaybackConfig{TotalFrames: 5, FPS: 10})
+			<-pb.Frames()
+			pb.Pause()
+			time.Sleep(time.Second)
+			synctest.Wait()
+
+			if stats := pb.Stats(); !stats.Paused || stats.Position != 1 || stats.Presented != 1 {
+				t.Errorf("一時停止中の統計が期待値と異なります: %+v", stats)
+			}
+
+			cancel()
+			if _, ok := <-pb.Frames(); ok {
+				t.Error("コンテキストを終了した後にフレームを受信しました")
+			}
+			<-pb.Done()
+			if err := pb.Err(); !errors.Is(err, context.Canceled) {
+				t.Errorf("終了の理由が期待値と異なります: got %v, want %v", err, context.Canceled)
+			}
+		})
+	})
+
+	t.Run("受信が遅れたフレームは捨てる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewVideoProcessor()
+
+			pb := processor.Play(context.Background(), synctestpkg.PlaybackConfig{TotalFrames: 5, FPS: 10})
+			var received []int
+			for i := range pb.Frames() {
+				received = append(received, i)
+				if i == 2 {
+					// 3フレーム目の表示予定時刻の250msから1フレーム分以上遅れた400msに受信を再開する
+					// 4フレーム目は350msの表示予定時刻より50ms遅れて表示する
+					time.Sleep(250 * time.Millisecond)
+				}
+			}
+
+			if want := []int{1, 2, 4, 5}; !slices.Equal(received, want) {
+				t.Errorf("受信したフレームが期待値と異なります: got %v, want %v", received, want)
+			}
+			if stats := pb.Stats(); stats.Dropped != 1 || stats.Presented != 4 || stats.Late != 1 {
+				t.Errorf("統計が期待値と異なります: %+v", stats)
+			}
+		})
+	})
+}
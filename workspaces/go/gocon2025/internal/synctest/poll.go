@@ -0,0 +1,203 @@
+package synctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRetriesExhausted は試行回数の上限に達したか、RetryPolicyがリトライを打ち切ったことを表す
+// PollResult.Errでは判定関数が最後に返したエラーと合わせて包む
+var ErrRetriesExhausted = errors.New("synctest: retries exhausted")
+
+// ProbeFunc はポーリングの各試行で呼び出される判定関数
+// doneがtrueを返すとポーリングは成功として終了する。errは失敗した試行の原因として記録される
+type ProbeFunc func(ctx context.Context) (done bool, err error)
+
+// StopReason はポーリングが終了した理由
+type StopReason int
+
+const (
+	// StopReasonSucceeded は判定関数が完了を返したことを表す
+	StopReasonSucceeded StopReason = iota
+	// StopReasonCanceled はコンテキストがキャンセルされたことを表す
+	StopReasonCanceled
+	// StopReasonDeadlineExceeded はコンテキストの期限を超過したことを表す
+	StopReasonDeadlineExceeded
+	// StopReasonRetriesExhausted は試行回数の上限に達したか、RetryPolicyがリトライを打ち切ったことを表す
+	StopReasonRetriesExhausted
+	// StopReasonCircuitOpen はWithCircuitBreakerで指定したサーキットブレーカーが試行を許可しなかったことを表す
+	StopReasonCircuitOpen
+)
+
+// String はStopReasonの文字列表現を返す
+func (r StopReason) String() string {
+	switch r {
+	case StopReasonSucceeded:
+		return "succeeded"
+	case StopReasonCanceled:
+		return "canceled"
+	case StopReasonDeadlineExceeded:
+		return "deadline exceeded"
+	case StopReasonRetriesExhausted:
+		return "retries exhausted"
+	case StopReasonCircuitOpen:
+		return "circuit open"
+	default:
+		return "unknown"
+	}
+}
+
+// PollConfig はポーリングの設定
+type PollConfig struct {
+	Interval    time.Duration // 試行の間隔。ゼロ以下の場合は最小値に設定される
+	MaxAttempts int           // 試行回数の上限。ゼロ以下の場合は無制限
+	Immediate   bool          // trueの場合は最初の試行を待機せずに行う
+	Backoff     RetryPolicy   // 失敗した試行の後の待機時間。nilの場合はIntervalで一定
+}
+
+// PollResult はポーリングの結果
+type PollResult struct {
+	Succeeded bool          // 判定関数が完了を返したかどうか
+	Attempts  int           // 判定関数を呼び出した回数
+	Elapsed   time.Duration // ポーリング開始から終了までの経過時間
+	LastErr   error         // 判定関数が最後に返したエラー
+	Reason    StopReason    // ポーリングが終了した理由
+	// Err は成功しなかった原因。成功した場合はnil
+	// Reasonに応じてcontext.Canceled・context.DeadlineExceeded・ErrRetriesExhausted・ErrCircuitOpen（またはErrTooManyProbes）となる
+	// 停止処理中のため実行しなかった場合と、スーパーバイザーに強制キャンセルされた場合はErrShuttingDown
+	Err error
+}
+
+// shutdownPollResult は停止処理中のため実行しなかったポーリングの結果
+var shutdownPollResult = PollResult{Reason: StopReasonCanceled, Err: ErrShuttingDown}
+
+// Poll 判定関数が完了を返すまで定期的にポーリングし、結果を1つ送信してチャネルを閉じる
+// コンテキストが終了した場合も、終了した理由を設定した結果を送信する
+func (p *taskProcessor) Poll(ctx context.Context, cfg PollConfig, probe ProbeFunc) <-chan PollResult {
+	result := make(chan PollResult, 1)
+
+	started := p.spawn(ctx, "Poll", func(ctx context.Context) {
+		defer close(result)
+		result <- p.poll(ctx, cfg, probe)
+	})
+	if !started {
+		result <- shutdownPollResult
+		close(result)
+	}
+
+	return result
+}
+
+// poll はポーリングを同期的に実行する
+func (p *taskProcessor) poll(ctx context.Context, cfg PollConfig, probe ProbeFunc) PollResult {
+	start := p.clock.Now()
+	var res PollResult
+	finish := func(reason StopReason) PollResult {
+		res.Reason = reason
+		res.Succeeded = reason == StopReasonSucceeded
+		res.Elapsed = p.clock.Now().Sub(start)
+		switch reason {
+		case StopReasonCanceled, StopReasonDeadlineExceeded:
+			res.Err = context.Cause(ctx)
+		case StopReasonRetriesExhausted:
+			res.Err = ErrRetriesExhausted
+			if res.LastErr != nil {
+				res.Err = fmt.Errorf("%w: %w", ErrRetriesExhausted, res.LastErr)
+			}
+		case StopReasonCircuitOpen:
+			res.Err = res.LastErr
+		}
+		return res
+	}
+
+	// ゼロ以下の間隔の場合は最小値に設定
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 1 * time.Nanosecond
+	}
+
+	wait := interval
+	var prevDelay time.Duration
+	for {
+		if !cfg.Immediate || res.Attempts > 0 {
+			timer := p.clock.NewTimer(wait)
+			select {
+			case <-timer.C():
+			case <-ctx.Done():
+				timer.Stop()
+				return finish(stopReasonOf(ctx))
+			}
+		}
+		if ctx.Err() != nil {
+			return finish(stopReasonOf(ctx))
+		}
+
+		done, err := p.attempt(ctx, probe, &res.Attempts)
+		if rejected, ok := err.(*probeRejectedError); ok {
+			res.LastErr = rejected.err
+			return finish(StopReasonCircuitOpen)
+		}
+		if err != nil {
+			res.LastErr = err
+		}
+		if done {
+			return finish(StopReasonSucceeded)
+		}
+		if cfg.MaxAttempts > 0 && res.Attempts >= cfg.MaxAttempts {
+			return finish(StopReasonRetriesExhausted)
+		}
+
+		if cfg.Backoff != nil {
+			state := RetryState{
+				Attempt:   res.Attempts,
+				Elapsed:   p.clock.Now().Sub(start),
+				PrevDelay: prevDelay,
+			}
+			d, ok := cfg.Backoff.NextDelay(state)
+			if !ok {
+				return finish(StopReasonRetriesExhausted)
+			}
+			wait, prevDelay = d, d
+		}
+	}
+}
+
+// probeRejectedError はサーキットブレーカーが判定関数の呼び出しを許可しなかったことを表す
+// 判定関数自身が返したErrCircuitOpenと区別するために包む
+type probeRejectedError struct {
+	err error
+}
+
+func (e *probeRejectedError) Error() string { return e.err.Error() }
+
+// attempt は判定関数を1回呼び出し、呼び出した場合はattemptsを数える
+// サーキットブレーカーが呼び出しを許可しなかった場合は*probeRejectedErrorを返す
+func (p *taskProcessor) attempt(ctx context.Context, probe ProbeFunc, attempts *int) (bool, error) {
+	if p.breaker == nil {
+		*attempts++
+		return probe(ctx)
+	}
+
+	var done, called bool
+	err := p.breaker.Execute(ctx, func(ctx context.Context) error {
+		called = true
+		*attempts++
+		var err error
+		done, err = probe(ctx)
+		return err
+	})
+	if !called {
+		return false, &probeRejectedError{err: err}
+	}
+	return done, err
+}
+
+// stopReasonOf は終了したコンテキストに対応するStopReasonを返す
+func stopReasonOf(ctx context.Context) StopReason {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return StopReasonDeadlineExceeded
+	}
+	return StopReasonCanceled
+}
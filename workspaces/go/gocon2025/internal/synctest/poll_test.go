@@ -0,0 +1,239 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// PollTest は Poll のテストに必要なデータと設定を管理する
+type PollTest struct {
+	processor synctestpkg.TaskProcessor
+}
+
+// succeedOn はn回目の試行で完了を返し、それまではerrを返す判定関数を作成する
+func succeedOn(n int, err error) synctestpkg.ProbeFunc {
+	attempts := 0
+	return func(context.Context) (bool, error) {
+		attempts++
+		if attempts >= n {
+			return true, nil
+		}
+		return false, err
+	}
+}
+
+func TestPoll(t *testing.T) {
+	setup := func(t *testing.T) *PollTest {
+		t.Helper()
+		return &PollTest{
+			processor: synctestpkg.NewTaskProcessor(),
+		}
+	}
+
+	test := setup(t)
+	errNotReady := errors.New("まだ準備できていません")
+
+	t.Run("Table Driven Test - 判定関数と試行回数の組み合わせ", func(t *testing.T) {
+		testCases := []struct {
+			name           string
+			cfg            synctestpkg.PollConfig
+			probe          func() synctestpkg.ProbeFunc
+			expectReason   synctestpkg.StopReason
+			expectAttempts int
+			expectElapsed  time.Duration
+			expectLastErr  error
+			expectErr      error
+		}{
+			{
+				name:           "初回で成功",
+				cfg:            synctestpkg.PollConfig{Interval: time.Second, MaxAttempts: 5},
+				probe:          func() synctestpkg.ProbeFunc { return succeedOn(1, nil) },
+				expectReason:   synctestpkg.StopReasonSucceeded,
+				expectAttempts: 1,
+				expectElapsed:  time.Second,
+			},
+			{
+				name:           "即時実行なら待機せずに成功",
+				cfg:            synctestpkg.PollConfig{Interval: time.Second, MaxAttempts: 5, Immediate: true},
+				probe:          func() synctestpkg.ProbeFunc { return succeedOn(1, nil) },
+				expectReason:   synctestpkg.StopReasonSucceeded,
+				expectAttempts: 1,
+				expectElapsed:  0,
+			},
+			{
+				name:           "エラーの後に成功",
+				cfg:            synctestpkg.PollConfig{Interval: 500 * time.Millisecond, MaxAttempts: 5},
+				probe:          func() synctestpkg.ProbeFunc { return succeedOn(4, errNotReady) },
+				expectReason:   synctestpkg.StopReasonSucceeded,
+				expectAttempts: 4,
+				expectElapsed:  2 * time.Second,
+				expectLastErr:  errNotReady,
+			},
+			{
+				name:           "試行回数の上限に到達",
+				cfg:            synctestpkg.PollConfig{Interval: time.Second, MaxAttempts: 3},
+				probe:          func() synctestpkg.ProbeFunc { return succeedOn(10, errNotReady) },
+				expectReason:   synctestpkg.StopReasonRetriesExhausted,
+				expectAttempts: 3,
+				expectElapsed:  3 * time.Second,
+				expectLastErr:  errNotReady,
+				expectErr:      synctestpkg.ErrRetriesExhausted,
+			},
+			{
+				name:           "上限なしなら成功するまで続ける",
+				cfg:            synctestpkg.PollConfig{Interval: time.Minute},
+				probe:          func() synctestpkg.ProbeFunc { return succeedOn(100, nil) },
+				expectReason:   synctestpkg.StopReasonSucceeded,
+				expectAttempts: 100,
+				expectElapsed:  100 * time.Minute,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 実行
+					res := <-test.processor.Poll(context.Background(), tc.cfg, tc.probe())
+
+					// 検証
+					if res.Reason != tc.expectReason {
+						t.Errorf("終了理由が期待値と異なります: got %v, want %v", res.Reason, tc.expectReason)
+					}
+					if res.Succeeded != (tc.expectReason == synctestpkg.StopReasonSucceeded) {
+						t.Errorf("成否が終了理由と一致しません: succeeded=%v, reason=%v", res.Succeeded, res.Reason)
+					}
+					if res.Attempts != tc.expectAttempts {
+						t.Errorf("試行回数が期待値と異なります: got %d, want %d", res.Attempts, tc.expectAttempts)
+					}
+					if res.Elapsed != tc.expectElapsed {
+						t.Errorf("経過時間が期待値と異なります: got %v, want %v", res.Elapsed, tc.expectElapsed)
+					}
+					if !errors.Is(res.LastErr, tc.expectLastErr) {
+						t.Errorf("最後のエラーが期待値と異なります: got %v, want %v", res.LastErr, tc.expectLastErr)
+					}
+					if (res.Err == nil) != (tc.expectErr == nil) || !errors.Is(res.Err, tc.expectErr) || (res.Err != nil && !errors.Is(res.Err, tc.expectLastErr)) {
+						t.Errorf("終了の原因が期待値と異なります: got %v, want %v", res.Err, tc.expectErr)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - コンテキスト終了による停止", func(t *testing.T) {
+		testCases := []struct {
+			name           string
+			newContext     func() (context.Context, context.CancelFunc)
+			cancelAfter    time.Duration
+			expectReason   synctestpkg.StopReason
+			expectAttempts int
+			expectErr      error
+		}{
+			{
+				name: "開始前にキャンセル",
+				newContext: func() (context.Context, context.CancelFunc) {
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+					return ctx, cancel
+				},
+				expectReason:   synctestpkg.StopReasonCanceled,
+				expectAttempts: 0,
+				expectErr:      context.Canceled,
+			},
+			{
+				name: "2回目の試行後にキャンセル",
+				newContext: func() (context.Context, context.CancelFunc) {
+					return context.WithCancel(context.Background())
+				},
+				cancelAfter:    2500 * time.Millisecond,
+				expectReason:   synctestpkg.StopReasonCanceled,
+				expectAttempts: 2,
+				expectErr:      context.Canceled,
+			},
+			{
+				name: "期限超過",
+				newContext: func() (context.Context, context.CancelFunc) {
+					return context.WithTimeout(context.Background(), 3500*time.Millisecond)
+				},
+				expectReason:   synctestpkg.StopReasonDeadlineExceeded,
+				expectAttempts: 3,
+				expectErr:      context.DeadlineExceeded,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 準備
+					ctx, cancel := tc.newContext()
+					defer cancel()
+					if tc.cancelAfter > 0 {
+						time.AfterFunc(tc.cancelAfter, cancel)
+					}
+
+					// 実行
+					cfg := synctestpkg.PollConfig{Interval: time.Second}
+					res := <-test.processor.Poll(ctx, cfg, succeedOn(100, errNotReady))
+
+					// 検証 - キャンセルと試行回数の枯渇を区別できる
+					if res.Reason != tc.expectReason {
+						t.Errorf("終了理由が期待値と異なります: got %v, want %v", res.Reason, tc.expectReason)
+					}
+					if res.Succeeded {
+						t.Error("キャンセルされたポーリングが成功扱いになっています")
+					}
+					if !errors.Is(res.Err, tc.expectErr) {
+						t.Errorf("終了の原因が期待値と異なります: got %v, want %v", res.Err, tc.expectErr)
+					}
+					if res.Attempts != tc.expectAttempts {
+						t.Errorf("試行回数が期待値と異なります: got %d, want %d", res.Attempts, tc.expectAttempts)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("判定関数にはポーリングのコンテキストが渡される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			type key struct{}
+			ctx := context.WithValue(context.Background(), key{}, "値")
+
+			var got any
+			probe := func(ctx context.Context) (bool, error) {
+				got = ctx.Value(key{})
+				return true, nil
+			}
+			<-test.processor.Poll(ctx, synctestpkg.PollConfig{Immediate: true}, probe)
+
+			if got != "値" {
+				t.Errorf("コンテキストの値が期待値と異なります: got %v", got)
+			}
+		})
+	})
+}
+
+func TestStopReasonString(t *testing.T) {
+	testCases := []struct {
+		reason synctestpkg.StopReason
+		want   string
+	}{
+		{synctestpkg.StopReasonSucceeded, "succeeded"},
+		{synctestpkg.StopReasonCanceled, "canceled"},
+		{synctestpkg.StopReasonDeadlineExceeded, "deadline exceeded"},
+		{synctestpkg.StopReasonRetriesExhausted, "retries exhausted"},
+		{synctestpkg.StopReasonCircuitOpen, "circuit open"},
+		{synctestpkg.StopReason(-1), "unknown"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := tc.reason.String(); got != tc.want {
+				t.Errorf("文字列表現が期待値と異なります: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package synctest
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// taskDuration は1タスクの処理にかかる時間
+const taskDuration = 100 * time.Millisecond
+
+// poolConfig はワーカープールの設定
+type poolConfig struct {
+	concurrency int // 同時に実行するタスク数の上限
+	queueDepth  int // 実行待ちのタスクを保持するキューの長さ
+}
+
+// WithWorkerPool はタスクを固定数のワーカーで処理するワーカープールモードを有効にする
+// VideoProcessorではconcurrency個のワーカーでフレームを並列に生成する
+// concurrencyが0以下の場合はGOMAXPROCS、queueDepthが0未満の場合は0（キューなし）として扱う
+func WithWorkerPool(concurrency, queueDepth int) Option {
+	return func(o *options) {
+		o.pool = &poolConfig{concurrency: concurrency, queueDepth: queueDepth}
+	}
+}
+
+// normalized は既定値を補った設定を返す
+func (c *poolConfig) normalized() poolConfig {
+	cfg := poolConfig{
+		concurrency: runtime.GOMAXPROCS(0),
+	}
+	if c != nil {
+		cfg = *c
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = runtime.GOMAXPROCS(0)
+	}
+	cfg.queueDepth = max(cfg.queueDepth, 0)
+	return cfg
+}
+
+// TaskReport はワーカープールで処理したタスクの結果
+type TaskReport struct {
+	Index      int       // 投入された順序（0始まり）。処理が終了した理由を通知する最後の結果ではterminalIndex
+	Task       string    // タスク名。処理を開始する前に失敗した場合は空
+	Message    string    // 処理結果のメッセージ
+	StartedAt  time.Time // 処理を開始した時刻
+	FinishedAt time.Time // 処理を完了した時刻
+	Err        error     // 処理に失敗した原因を*TaskErrorで包んだもの。成功した場合はnil
+}
+
+// terminalIndex は処理が終了した理由を通知する最後のTaskReportのIndex
+const terminalIndex = -1
+
+// Terminal はタスクの結果ではなく、処理が終了した理由を通知する最後の結果かどうかを返す
+// Errはコンテキストが終了した場合はcontext.Cause(ctx)、キューが閉じられた場合はErrQueueClosed、
+// 停止処理中のため開始しなかった場合はErrShuttingDownとなる
+func (r TaskReport) Terminal() bool {
+	return r.Index == terminalIndex
+}
+
+// Duration はタスクの処理にかかった時間を返す
+func (r TaskReport) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// ProcessWithPool チャネルから受け取ったタスクをワーカープールで処理する
+// キューが満杯の間はtasksからの受信を止めるため、送信側にバックプレッシャーがかかる
+// 失敗したタスクやサーキットブレーカーに拒否されたタスクも、Errを設定した結果として通知する
+// tasksが閉じられ、すべてのタスクが完了またはキャンセルされると結果のチャネルを閉じる
+// コンテキストが終了した場合は実行中のタスクを通知せず、Terminalな結果を最後に送信してから閉じる
+// スーパーバイザーに強制キャンセルされた場合、受信されなかったTerminalな結果は破棄する
+func (p *taskProcessor) ProcessWithPool(ctx context.Context, tasks <-chan string) <-chan TaskReport {
+	const op = "ProcessWithPool"
+	o := p.operation(op)
+	if o.pool == nil {
+		o.pool = &poolConfig{}
+	}
+	cfg := o.pool.normalized()
+	report := make(chan TaskReport, cfg.concurrency)
+
+	guarded := tracked(Guard(p.breaker, p.simulateTask))
+	simulate := func(ctx context.Context, task string) (TaskReport, error) {
+		message, err := guarded(ctx, task)
+		return TaskReport{Task: task, Message: message}, err
+	}
+
+	started := p.spawn(ctx, op, func(ctx context.Context) {
+		defer close(report)
+
+		for r := range runStream(ctx, o, tasks, simulate, cfg.concurrency) {
+			// 処理を開始する前に失敗した場合はタスク名を設定できないため、Indexで投入された順序を識別する
+			rep := r.Value
+			rep.Index = r.Index
+			rep.StartedAt = r.StartedAt
+			rep.FinishedAt = r.StartedAt.Add(r.Duration)
+			rep.Err = taskError(op, rep.Task, r.Err)
+			select {
+			case report <- rep:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			sendTerminal(ctx, report, TaskReport{Index: terminalIndex, Err: context.Cause(ctx)})
+		}
+	})
+	if !started {
+		report <- TaskReport{Index: terminalIndex, Err: ErrShuttingDown}
+		close(report)
+	}
+
+	return report
+}
+
+// simulateTask は1つのタスクの処理をシミュレーションする（各タスクに100ms必要）
+func (p *taskProcessor) simulateTask(ctx context.Context, task string) (string, error) {
+	timer := p.clock.NewTimer(taskDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return "タスク完了: " + task, nil
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	}
+}
@@ -0,0 +1,245 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// feedTasks はタスクを順に送信して閉じる入力チャネルを作成する
+func feedTasks(ctx context.Context, tasks []string) <-chan string {
+	input := make(chan string)
+	go func() {
+		defer close(input)
+		for _, task := range tasks {
+			select {
+			case input <- task:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return input
+}
+
+// taskNames は"タスク1"から始まるn個のタスク名を作成する
+func taskNames(n int) []string {
+	tasks := make([]string, n)
+	for i := range tasks {
+		tasks[i] = fmt.Sprintf("タスク%d", i+1)
+	}
+	return tasks
+}
+
+// maxOverlap は同時に実行されていたタスク数の最大値を返す
+func maxOverlap(reports []synctestpkg.TaskReport) int {
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	events := make([]event, 0, len(reports)*2)
+	for _, r := range reports {
+		events = append(events, event{r.StartedAt, 1}, event{r.FinishedAt, -1})
+	}
+	// 同時刻では終了を先に数える
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+	running, peak := 0, 0
+	for _, e := range events {
+		running += e.delta
+		peak = max(peak, running)
+	}
+	return peak
+}
+
+func TestWorkerPool(t *testing.T) {
+	t.Run("Table Driven Test - 同時実行数の上限", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			concurrency   int
+			queueDepth    int
+			taskCount     int
+			expectElapsed time.Duration
+		}{
+			{name: "タスクなし", concurrency: 3, queueDepth: 1, taskCount: 0, expectElapsed: 0},
+			{name: "ワーカー1つ", concurrency: 1, queueDepth: 0, taskCount: 5, expectElapsed: 500 * time.Millisecond},
+			{name: "端数が出るタスク数", concurrency: 3, queueDepth: 2, taskCount: 10, expectElapsed: 400 * time.Millisecond},
+			{name: "ワーカー数よりタスクが少ない", concurrency: 8, queueDepth: 4, taskCount: 3, expectElapsed: 100 * time.Millisecond},
+			{name: "大量のタスク", concurrency: 16, queueDepth: 64, taskCount: 5000, expectElapsed: 31300 * time.Millisecond},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 準備
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(tc.concurrency, tc.queueDepth))
+					start := time.Now()
+
+					// 実行 - 結果のチャネルは閉じられるのでrangeで受信できる
+					var reports []synctestpkg.TaskReport
+					for report := range processor.ProcessWithPool(context.Background(), feedTasks(context.Background(), taskNames(tc.taskCount))) {
+						reports = append(reports, report)
+					}
+
+					// 検証
+					if len(reports) != tc.taskCount {
+						t.Fatalf("完了したタスク数が期待値と異なります: got %d, want %d", len(reports), tc.taskCount)
+					}
+					if elapsed := time.Since(start); elapsed != tc.expectElapsed {
+						t.Errorf("経過時間が期待値と異なります: got %v, want %v", elapsed, tc.expectElapsed)
+					}
+					if peak := maxOverlap(reports); peak > tc.concurrency {
+						t.Errorf("同時実行数が上限を超えています: got %d, limit %d", peak, tc.concurrency)
+					}
+					seen := make(map[int]bool)
+					for _, r := range reports {
+						if r.Duration() != 100*time.Millisecond {
+							t.Errorf("タスク %q の処理時間が期待値と異なります: got %v", r.Task, r.Duration())
+						}
+						if want := fmt.Sprintf("タスク%d", r.Index+1); r.Task != want {
+							t.Errorf("投入順序とタスク名が一致しません: index=%d, task=%q", r.Index, r.Task)
+						}
+						seen[r.Index] = true
+					}
+					if len(seen) != tc.taskCount {
+						t.Errorf("重複したインデックスがあります: unique=%d, want %d", len(seen), tc.taskCount)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("キューが満杯になると送信側がブロックされる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// 準備
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(1, 2))
+			input := make(chan string)
+			var sent atomic.Int32
+			go func() {
+				defer close(input)
+				for _, task := range taskNames(10) {
+					input <- task
+					sent.Add(1)
+				}
+			}()
+
+			// 実行
+			result := processor.ProcessWithPool(context.Background(), input)
+			synctest.Wait()
+
+			// 検証 - 実行中1 + キュー2 + ディスパッチャが保持する1 だけ受け付ける
+			if got := sent.Load(); got != 4 {
+				t.Errorf("受け付けたタスク数が期待値と異なります: got %d, want 4", got)
+			}
+
+			count := 0
+			for range result {
+				count++
+			}
+			if count != 10 {
+				t.Errorf("完了したタスク数が期待値と異なります: got %d, want 10", count)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - キャンセル時の挙動", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			cancelAfter time.Duration
+			expectCount int
+		}{
+			{name: "開始直後にキャンセル", cancelAfter: 0, expectCount: 0},
+			{name: "1巡目の途中でキャンセル", cancelAfter: 50 * time.Millisecond, expectCount: 0},
+			{name: "2巡目の途中でキャンセル", cancelAfter: 150 * time.Millisecond, expectCount: 2},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 準備
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(2, 4))
+
+					// 実行
+					result := processor.ProcessWithPool(ctx, feedTasks(ctx, taskNames(10)))
+					time.AfterFunc(tc.cancelAfter, cancel)
+
+					// 検証 - キャンセル後もキャンセルの結果を最後に送信して、結果のチャネルは閉じられる
+					count := 0
+					var last synctestpkg.TaskReport
+					for r := range result {
+						if last = r; !r.Terminal() {
+							count++
+						}
+					}
+					if !last.Terminal() || !errors.Is(last.Err, context.Canceled) {
+						t.Errorf("最後の結果がキャンセルの結果ではありません: %+v", last)
+					}
+					if count != tc.expectCount {
+						t.Errorf("完了したタスク数が期待値と異なります: got %d, want %d", count, tc.expectCount)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("ブレーカーに拒否されたタスクもエラーとともに通知する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			b := synctestpkg.NewCircuitBreaker(synctestpkg.BreakerConfig{ConsecutiveFailures: 1, Cooldown: time.Minute})
+			_ = b.Execute(context.Background(), func(context.Context) error { return errors.New("依存先が停止しています") })
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(2, 0), synctestpkg.WithCircuitBreaker(b))
+			tasks := taskNames(3)
+
+			reported := make(map[int]bool)
+			for r := range processor.ProcessWithPool(context.Background(), feedTasks(context.Background(), tasks)) {
+				reported[r.Index] = true
+				if r.Task != tasks[r.Index] || r.Message != "" {
+					t.Errorf("結果が期待値と異なります: %+v", r)
+				}
+				var taskErr *synctestpkg.TaskError
+				if !errors.Is(r.Err, synctestpkg.ErrCircuitOpen) || !errors.As(r.Err, &taskErr) || taskErr.Op != "ProcessWithPool" {
+					t.Errorf("ErrCircuitOpenを包んだ*TaskErrorを期待しましたが、%vが返されました", r.Err)
+				}
+			}
+
+			// 失敗した結果を除外せずに、投入したすべてのタスクを通知する
+			if len(reported) != len(tasks) {
+				t.Errorf("通知されたタスク数が期待値と異なります: got %d, want %d", len(reported), len(tasks))
+			}
+		})
+	})
+
+	t.Run("ProcessWithGoroutineのワーカープールモードは完了後にチャネルを閉じる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(4, 4))
+			tasks := taskNames(20)
+			start := time.Now()
+
+			completed := make(map[string]bool)
+			for r := range processor.ProcessWithGoroutine(context.Background(), tasks) {
+				completed[r.Value] = r.Err == nil
+			}
+
+			for _, task := range tasks {
+				if !completed["タスク完了: "+task] {
+					t.Errorf("タスク %q が完了していません", task)
+				}
+			}
+			if elapsed := time.Since(start); elapsed != 500*time.Millisecond {
+				t.Errorf("経過時間が期待値と異なります: got %v, want 500ms", elapsed)
+			}
+		})
+	})
+}
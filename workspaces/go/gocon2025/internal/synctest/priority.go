@@ -0,0 +1,297 @@
+package synctest
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrSchedulerClosed はClose後のスケジューラにタスクを投入したことを表す
+var ErrSchedulerClosed = errors.New("synctest: scheduler closed")
+
+// SchedulingPolicy は実行待ちのタスクから次に実行するタスクを選ぶ方針
+type SchedulingPolicy int
+
+const (
+	// HighestPriorityFirst は優先度が最も高いタスクを先に実行する
+	HighestPriorityFirst SchedulingPolicy = iota
+	// EarliestDeadlineFirst は期限が最も早いタスクを先に実行する。期限のないタスクは最後に回す
+	EarliestDeadlineFirst
+)
+
+// PriorityTask は優先度付きで投入するタスク
+type PriorityTask struct {
+	Name     string                          // タスク名
+	Priority int                             // 優先度。大きいほど先に実行される
+	Deadline time.Time                       // 期限。ゼロ値の場合は期限なし
+	Run      func(ctx context.Context) error // 実行する処理
+}
+
+// ScheduledResult はスケジューラで実行したタスクの結果
+type ScheduledResult struct {
+	Name       string    // タスク名
+	Priority   int       // 投入時の優先度
+	Err        error     // 処理が返したエラー。パニックした場合は*PanicError
+	EnqueuedAt time.Time // 投入された時刻
+	StartedAt  time.Time // 処理を開始した時刻
+	FinishedAt time.Time // 処理を完了した時刻
+}
+
+// Wait は投入されてから処理を開始するまでの待ち時間を返す
+func (r ScheduledResult) Wait() time.Duration {
+	return r.StartedAt.Sub(r.EnqueuedAt)
+}
+
+// PriorityConfig は優先度付きスケジューラの設定
+type PriorityConfig struct {
+	Workers int              // タスクを実行するワーカー数。0以下の場合は1
+	Policy  SchedulingPolicy // 次に実行するタスクを選ぶ方針
+	// AgingInterval は待ち時間による優先度の底上げ間隔
+	// 待ち時間がAgingIntervalを経過するごとに実効優先度が1上がり、低優先度のタスクの飢餓を防ぐ
+	// 0以下の場合は底上げしない。HighestPriorityFirstの場合のみ有効
+	AgingInterval time.Duration
+}
+
+// PriorityScheduler は優先度や期限に従って固定数のワーカーにタスクを割り当てるインターフェース
+type PriorityScheduler interface {
+	// Submit はタスクを実行待ちに追加する。Close後はErrSchedulerClosedを返す
+	Submit(task PriorityTask) error
+	// Results は実行したタスクの結果を受信するチャネルを返す
+	// Close後に実行待ちのタスクがすべて完了するか、コンテキストが終了すると閉じられる
+	Results() <-chan ScheduledResult
+	// Len は実行待ちのタスク数を返す
+	Len() int
+	// Close は新しいタスクの受け付けを停止する。実行待ちのタスクは引き続き実行される
+	Close()
+}
+
+// priorityScheduler はPrioritySchedulerの具象実装
+type priorityScheduler struct {
+	options
+	cfg     PriorityConfig
+	start   time.Time
+	mu      sync.Mutex
+	queue   priorityQueue
+	seq     uint64
+	closed  bool
+	wake    chan struct{}
+	work    chan *priorityItem
+	results chan ScheduledResult
+}
+
+// NewPriorityScheduler は優先度付きスケジューラを作成し、ワーカーを起動する
+// ctxが終了すると実行待ちのタスクは破棄され、実行中のタスクにもキャンセルが伝播する
+func NewPriorityScheduler(ctx context.Context, cfg PriorityConfig, opts ...Option) PriorityScheduler {
+	cfg.Workers = max(cfg.Workers, 1)
+	s := &priorityScheduler{
+		options: newOptions(opts),
+		cfg:     cfg,
+		wake:    make(chan struct{}, 1),
+		work:    make(chan *priorityItem),
+		results: make(chan ScheduledResult, cfg.Workers),
+	}
+	s.start = s.clock.Now()
+	s.queue.less = s.lessFunc()
+
+	go s.dispatch(ctx)
+
+	var wg sync.WaitGroup
+	for range cfg.Workers {
+		wg.Go(func() {
+			for item := range s.work {
+				if ctx.Err() != nil {
+					continue
+				}
+				r := s.execute(ctx, item)
+				if ctx.Err() != nil {
+					continue
+				}
+				select {
+				case s.results <- r:
+				case <-ctx.Done():
+				}
+			}
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		close(s.results)
+	}()
+
+	return s
+}
+
+// Submit はタスクを実行待ちに追加する
+func (s *priorityScheduler) Submit(task PriorityTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSchedulerClosed
+	}
+	s.seq++
+	heap.Push(&s.queue, &priorityItem{task: task, enqueuedAt: s.clock.Now(), seq: s.seq})
+	s.notify()
+	return nil
+}
+
+// Results は実行したタスクの結果を受信するチャネルを返す
+func (s *priorityScheduler) Results() <-chan ScheduledResult {
+	return s.results
+}
+
+// Len は実行待ちのタスク数を返す
+func (s *priorityScheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queue.Len()
+}
+
+// Close は新しいタスクの受け付けを停止する
+func (s *priorityScheduler) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.notify()
+}
+
+// notify はディスパッチャに実行待ちの変化を通知する
+func (s *priorityScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch は実行待ちの先頭のタスクを空いているワーカーに渡す
+// 渡す前に新しいタスクが投入された場合は先頭を選び直す
+func (s *priorityScheduler) dispatch(ctx context.Context) {
+	defer close(s.work)
+
+	for {
+		s.mu.Lock()
+		if s.queue.Len() == 0 {
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return
+			}
+			select {
+			case <-s.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		top := s.queue.items[0]
+		s.mu.Unlock()
+
+		select {
+		case s.work <- top:
+			s.mu.Lock()
+			heap.Remove(&s.queue, top.index)
+			s.mu.Unlock()
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// execute はタスクを1件実行し、パニックを回復して結果に記録する
+func (s *priorityScheduler) execute(ctx context.Context, item *priorityItem) (res ScheduledResult) {
+	res = ScheduledResult{
+		Name:       item.task.Name,
+		Priority:   item.task.Priority,
+		EnqueuedAt: item.enqueuedAt,
+		StartedAt:  s.clock.Now(),
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			res.Err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+		res.FinishedAt = s.clock.Now()
+	}()
+
+	res.Err = item.task.Run(ctx)
+	return res
+}
+
+// lessFunc は設定された方針に従ってタスクの実行順を比較する関数を返す
+func (s *priorityScheduler) lessFunc() func(a, b *priorityItem) bool {
+	if s.cfg.Policy == EarliestDeadlineFirst {
+		return func(a, b *priorityItem) bool {
+			ad, bd := a.task.Deadline, b.task.Deadline
+			switch {
+			case ad.IsZero() != bd.IsZero():
+				return bd.IsZero()
+			case !ad.Equal(bd):
+				return ad.Before(bd)
+			case a.task.Priority != b.task.Priority:
+				return a.task.Priority > b.task.Priority
+			}
+			return a.seq < b.seq
+		}
+	}
+
+	return func(a, b *priorityItem) bool {
+		as, bs := s.agedScore(a), s.agedScore(b)
+		if as != bs {
+			return as > bs
+		}
+		return a.seq < b.seq
+	}
+}
+
+// agedScore は時刻に依存しない形に変形した実効優先度を返す
+// 実効優先度 Priority + (now - enqueuedAt) / AgingInterval の大小関係は、
+// nowを共通に含む項を除いた Priority - enqueuedAt / AgingInterval で比較できる
+func (s *priorityScheduler) agedScore(item *priorityItem) float64 {
+	score := float64(item.task.Priority)
+	if s.cfg.AgingInterval > 0 {
+		score -= float64(item.enqueuedAt.Sub(s.start)) / float64(s.cfg.AgingInterval)
+	}
+	return score
+}
+
+// priorityItem は実行待ちのタスク
+type priorityItem struct {
+	task       PriorityTask
+	enqueuedAt time.Time
+	seq        uint64 // 同順位のタスクを投入順に並べるための通し番号
+	index      int    // ヒープ内の位置
+}
+
+// priorityQueue はheap.Interfaceを実装した実行待ちキュー
+type priorityQueue struct {
+	items []*priorityItem
+	less  func(a, b *priorityItem) bool
+}
+
+func (q priorityQueue) Len() int           { return len(q.items) }
+func (q priorityQueue) Less(i, j int) bool { return q.less(q.items[i], q.items[j]) }
+
+func (q priorityQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+func (q *priorityQueue) Push(x any) {
+	item := x.(*priorityItem)
+	item.index = len(q.items)
+	q.items = append(q.items, item)
+}
+
+func (q *priorityQueue) Pop() any {
+	n := len(q.items)
+	item := q.items[n-1]
+	q.items[n-1] = nil
+	q.items = q.items[:n-1]
+	return item
+}
@@ -0,0 +1,262 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// sleepTask は指定時間だけ待機するタスクの処理を作成する
+func sleepTask(d time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// collectScheduled は結果のチャネルが閉じられるまで受信する
+func collectScheduled(results <-chan synctestpkg.ScheduledResult) []synctestpkg.ScheduledResult {
+	var collected []synctestpkg.ScheduledResult
+	for r := range results {
+		collected = append(collected, r)
+	}
+	return collected
+}
+
+// namesOf は結果のタスク名を受信順に返す
+func namesOf(results []synctestpkg.ScheduledResult) []string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Name
+	}
+	return names
+}
+
+func TestPriorityScheduler(t *testing.T) {
+	t.Run("Table Driven Test - 実行順序", func(t *testing.T) {
+		base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC) // synctestバブルの開始時刻
+
+		testCases := []struct {
+			name   string
+			policy synctestpkg.SchedulingPolicy
+			tasks  []synctestpkg.PriorityTask
+			expect []string
+		}{
+			{
+				name:   "優先度の高い順",
+				policy: synctestpkg.HighestPriorityFirst,
+				tasks: []synctestpkg.PriorityTask{
+					{Name: "低", Priority: 1},
+					{Name: "高", Priority: 10},
+					{Name: "中", Priority: 5},
+				},
+				expect: []string{"高", "中", "低"},
+			},
+			{
+				name:   "同じ優先度は投入順",
+				policy: synctestpkg.HighestPriorityFirst,
+				tasks: []synctestpkg.PriorityTask{
+					{Name: "A", Priority: 3},
+					{Name: "B", Priority: 3},
+					{Name: "C", Priority: 3},
+				},
+				expect: []string{"A", "B", "C"},
+			},
+			{
+				name:   "期限の早い順で期限なしは最後",
+				policy: synctestpkg.EarliestDeadlineFirst,
+				tasks: []synctestpkg.PriorityTask{
+					{Name: "期限なし", Priority: 100},
+					{Name: "1時間後", Deadline: base.Add(time.Hour)},
+					{Name: "1分後", Deadline: base.Add(time.Minute)},
+				},
+				expect: []string{"1分後", "1時間後", "期限なし"},
+			},
+			{
+				name:   "同じ期限は優先度の高い順",
+				policy: synctestpkg.EarliestDeadlineFirst,
+				tasks: []synctestpkg.PriorityTask{
+					{Name: "低", Priority: 1, Deadline: base.Add(time.Minute)},
+					{Name: "高", Priority: 9, Deadline: base.Add(time.Minute)},
+				},
+				expect: []string{"高", "低"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 準備 - 唯一のワーカーを塞いでから投入し、実行待ちで並べ替えさせる
+					s := synctestpkg.NewPriorityScheduler(context.Background(), synctestpkg.PriorityConfig{Workers: 1, Policy: tc.policy})
+					if err := s.Submit(synctestpkg.PriorityTask{Name: "先行", Run: sleepTask(time.Second)}); err != nil {
+						t.Fatal(err)
+					}
+					synctest.Wait()
+					for _, task := range tc.tasks {
+						task.Run = sleepTask(time.Second)
+						if err := s.Submit(task); err != nil {
+							t.Fatal(err)
+						}
+					}
+					if n := s.Len(); n != len(tc.tasks) {
+						t.Errorf("実行待ちのタスク数が期待値と異なります: got %d, want %d", n, len(tc.tasks))
+					}
+					s.Close()
+
+					// 実行
+					results := collectScheduled(s.Results())
+
+					// 検証
+					want := append([]string{"先行"}, tc.expect...)
+					if got := namesOf(results); !slices.Equal(got, want) {
+						t.Errorf("実行順序が期待値と異なります: got %v, want %v", got, want)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - エージングによる飢餓の防止", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			agingInterval time.Duration
+			expectLowWait time.Duration
+		}{
+			// 高優先度(3)が0.5秒ごとに投入され続けても、低優先度(0)は4秒待つと追い越す
+			{name: "エージングあり", agingInterval: time.Second, expectLowWait: 4 * time.Second},
+			// エージングがなければ高優先度がすべて終わるまで待たされる
+			{name: "エージングなし", agingInterval: 0, expectLowWait: 10 * time.Second},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					s := synctestpkg.NewPriorityScheduler(context.Background(), synctestpkg.PriorityConfig{
+						Workers:       1,
+						AgingInterval: tc.agingInterval,
+					})
+
+					// 準備
+					if err := s.Submit(synctestpkg.PriorityTask{Name: "高0", Priority: 3, Run: sleepTask(time.Second)}); err != nil {
+						t.Fatal(err)
+					}
+					synctest.Wait()
+					if err := s.Submit(synctestpkg.PriorityTask{Name: "低", Priority: 0, Run: sleepTask(time.Second)}); err != nil {
+						t.Fatal(err)
+					}
+					go func() {
+						defer s.Close()
+						time.Sleep(500 * time.Millisecond)
+						for i := 1; i < 10; i++ {
+							_ = s.Submit(synctestpkg.PriorityTask{Name: fmt.Sprintf("高%d", i), Priority: 3, Run: sleepTask(time.Second)})
+							time.Sleep(time.Second)
+						}
+					}()
+
+					// 実行
+					results := collectScheduled(s.Results())
+
+					// 検証
+					if len(results) != 11 {
+						t.Fatalf("結果の数が期待値と異なります: got %d, want 11", len(results))
+					}
+					for _, r := range results {
+						if r.Name == "低" && r.Wait() != tc.expectLowWait {
+							t.Errorf("低優先度タスクの待ち時間が期待値と異なります: got %v, want %v (順序 %v)", r.Wait(), tc.expectLowWait, namesOf(results))
+						}
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("複数ワーカーで並行に実行される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			s := synctestpkg.NewPriorityScheduler(context.Background(), synctestpkg.PriorityConfig{Workers: 3})
+			start := time.Now()
+			for i := range 9 {
+				if err := s.Submit(synctestpkg.PriorityTask{Name: fmt.Sprint(i), Priority: i, Run: sleepTask(time.Second)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s.Close()
+
+			results := collectScheduled(s.Results())
+
+			if len(results) != 9 {
+				t.Fatalf("結果の数が期待値と異なります: got %d, want 9", len(results))
+			}
+			if elapsed := time.Since(start); elapsed != 3*time.Second {
+				t.Errorf("経過時間が期待値と異なります: got %v, want 3s", elapsed)
+			}
+		})
+	})
+
+	t.Run("エラーとパニックは結果に記録される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			errFailed := errors.New("失敗")
+			s := synctestpkg.NewPriorityScheduler(context.Background(), synctestpkg.PriorityConfig{Workers: 1})
+			_ = s.Submit(synctestpkg.PriorityTask{Name: "失敗", Run: func(context.Context) error { return errFailed }})
+			_ = s.Submit(synctestpkg.PriorityTask{Name: "パニック", Run: func(context.Context) error { panic("壊れた") }})
+			s.Close()
+
+			results := collectScheduled(s.Results())
+
+			errs := make(map[string]error)
+			for _, r := range results {
+				errs[r.Name] = r.Err
+			}
+			if !errors.Is(errs["失敗"], errFailed) {
+				t.Errorf("エラーが期待値と異なります: got %v", errs["失敗"])
+			}
+			var panicErr *synctestpkg.PanicError
+			if !errors.As(errs["パニック"], &panicErr) {
+				t.Errorf("パニックがPanicErrorとして記録されていません: %v", errs["パニック"])
+			}
+		})
+	})
+
+	t.Run("Close後の投入はエラーになる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			s := synctestpkg.NewPriorityScheduler(context.Background(), synctestpkg.PriorityConfig{})
+			s.Close()
+
+			err := s.Submit(synctestpkg.PriorityTask{Name: "遅刻", Run: sleepTask(0)})
+
+			if !errors.Is(err, synctestpkg.ErrSchedulerClosed) {
+				t.Errorf("エラーが期待値と異なります: got %v", err)
+			}
+			if results := collectScheduled(s.Results()); len(results) != 0 {
+				t.Errorf("結果が届きました: %v", namesOf(results))
+			}
+		})
+	})
+
+	t.Run("キャンセルすると実行待ちのタスクは破棄される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s := synctestpkg.NewPriorityScheduler(ctx, synctestpkg.PriorityConfig{Workers: 1})
+			for i := range 5 {
+				_ = s.Submit(synctestpkg.PriorityTask{Name: fmt.Sprint(i), Run: sleepTask(time.Second)})
+			}
+			time.AfterFunc(2500*time.Millisecond, cancel)
+
+			results := collectScheduled(s.Results())
+
+			if got := namesOf(results); !slices.Equal(got, []string{"0", "1"}) {
+				t.Errorf("キャンセル前に完了したタスクが期待値と異なります: got %v", got)
+			}
+		})
+	})
+}
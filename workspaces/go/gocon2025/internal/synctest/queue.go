@@ -0,0 +1,658 @@
+package synctest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueClosed はClose後のキューを操作したことを表す
+	ErrQueueClosed = errors.New("synctest: queue closed")
+	// ErrLeaseExpired はAck・Nack・Releaseしたメッセージの受信が無効になっていることを表す
+	// 可視性タイムアウトを過ぎて再配信を待っているか、他の受信者に配信された場合に返す
+	ErrLeaseExpired = errors.New("synctest: queue lease expired")
+	// ErrQueueLogCorrupt はログの途中に読み込めないレコードがあるため、キューを開けなかったことを表す
+	// 後続の有効なレコードを失わないように、ログは切り詰めずにそのまま残す
+	ErrQueueLogCorrupt = errors.New("synctest: queue log corrupted")
+)
+
+// queueLogName はキューのディレクトリに作成するログのファイル名
+const queueLogName = "queue.wal"
+
+var (
+	// errVisibilityTimeout は可視性タイムアウトまでにAckされなかったことによる失敗の理由
+	errVisibilityTimeout = errors.New("synctest: visibility timeout expired")
+	// errLeaseAbandoned は前回開いたときに受信したままのメッセージの失敗の理由
+	errLeaseAbandoned = errors.New("synctest: lease abandoned before reopen")
+)
+
+// SyncPolicy はキューのログをストレージに同期する方針
+type SyncPolicy int
+
+const (
+	// SyncAlways は書き込みのたびに同期する。操作が返った時点でOSの異常終了や電源断でも失われない
+	SyncAlways SyncPolicy = iota
+	// SyncPeriodic はSyncIntervalごとにまとめて同期する
+	// プロセスの異常終了では失われないが、OSの異常終了や電源断では最大でSyncInterval分の操作が失われる
+	SyncPeriodic
+	// SyncNever は同期をOSに任せる。CloseとCompactの時点では同期する
+	SyncNever
+)
+
+// QueueConfig は永続キューの設定
+type QueueConfig struct {
+	// Dir はログを保存するディレクトリ。存在しない場合は作成する
+	// 同じディレクトリを複数のキューで同時に開いてはならない
+	Dir string
+	// Sync はログをストレージに同期する方針
+	Sync SyncPolicy
+	// SyncInterval はSyncPeriodicで同期する間隔。0以下の場合は100ms
+	SyncInterval time.Duration
+	// VisibilityTimeout は受信したメッセージを他の受信者から隠す時間。0以下の場合は30s
+	// この時間内にAckされなかったメッセージは失敗として再配信を待つ
+	VisibilityTimeout time.Duration
+	// MaxFailures はメッセージをデッドレターに移すまでの失敗回数。0以下の場合は3
+	MaxFailures int
+	// CompactThreshold はログを圧縮するまでに溜める不要なレコード数。0以下の場合は1000
+	// 完了したメッセージや配信の履歴などの不要なレコードがこの数に達するたびに、現在の状態だけを記録したログに置き換える
+	CompactThreshold int
+}
+
+// normalized は既定値を補った設定を返す
+func (c QueueConfig) normalized() QueueConfig {
+	if c.SyncInterval <= 0 {
+		c.SyncInterval = 100 * time.Millisecond
+	}
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = 30 * time.Second
+	}
+	if c.MaxFailures <= 0 {
+		c.MaxFailures = 3
+	}
+	if c.CompactThreshold <= 0 {
+		c.CompactThreshold = 1000
+	}
+	return c
+}
+
+// QueueMessage はキューに追加したタスク
+type QueueMessage struct {
+	ID         uint64    // 追加した順に1から割り当てられるID
+	Task       string    // タスク名
+	EnqueuedAt time.Time // 追加した時刻
+	Delivery   int       // 何回目の配信か。Ack・Nack・Releaseで受信した配信を識別する
+	Failures   int       // Nackされたか可視性タイムアウトを過ぎた回数
+	LastError  string    // 最後に失敗した理由
+	Deadline   time.Time // 可視性タイムアウトの期限。受信中のメッセージのみ
+}
+
+// QueueStats はキューの状態
+type QueueStats struct {
+	Ready      int // 配信を待っているメッセージ数
+	InFlight   int // 受信されて完了を待っているメッセージ数
+	Dead       int // デッドレターのメッセージ数
+	LogRecords int // ログに記録されているレコード数
+}
+
+// DurableQueue はログに記録して異常終了後も失われないタスクのキュー
+// メッセージは少なくとも1回配信される。受信者はAckするまでに異常終了した場合、同じメッセージを再度受信する
+type DurableQueue interface {
+	// Enqueue はタスクを追加してIDを返す
+	Enqueue(task string) (uint64, error)
+	// Dequeue は配信を待っているメッセージを追加した順に受信する
+	// メッセージがない場合は追加されるか、コンテキストが終了するか、キューが閉じられるまで待つ
+	Dequeue(ctx context.Context) (QueueMessage, error)
+	// Ack は受信したメッセージの処理が完了したことを記録し、キューから削除する
+	Ack(msg QueueMessage) error
+	// Nack は受信したメッセージの処理が失敗したことを記録する
+	// 失敗回数がMaxFailuresに達した場合はデッドレターに移し、そうでない場合は末尾に戻して再配信を待つ
+	Nack(msg QueueMessage, cause error) error
+	// Release は受信したメッセージを失敗に数えずに先頭に戻す。キャンセルで処理を中断した場合に利用する
+	Release(msg QueueMessage) error
+	// DeadLetters はデッドレターのメッセージをIDの順に返す
+	DeadLetters() []QueueMessage
+	// Stats はキューの状態を返す
+	Stats() QueueStats
+	// Compact はログを現在の状態だけを記録したログに置き換える
+	Compact() error
+	// Close は同期していない操作をストレージに書き出してログを閉じる
+	// 受信中のメッセージは、次に開いたときに失敗として再配信を待つ
+	Close() error
+}
+
+// queueEntry はキューに残っているメッセージ
+type queueEntry struct {
+	msg  QueueMessage
+	dead bool
+}
+
+// durableQueue はDurableQueueの具象実装
+type durableQueue struct {
+	clock Clock
+	cfg   QueueConfig
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	mu       sync.Mutex
+	log      *wal
+	entries  map[uint64]*queueEntry
+	ready    []uint64 // 配信を待っているメッセージのID。先頭から配信する
+	inFlight int
+	nextID   uint64
+	changed  chan struct{} // 配信を待っているメッセージが増えるか、キューが閉じられると閉じられる
+	closed   bool
+}
+
+// OpenQueue はcfg.Dirのログから状態を復元して永続キューを開く
+// ログの末尾に書き込みの途中で途切れたレコードがある場合は、そのレコードを破棄して切り詰める
+// 途中のレコードが破損している場合は、ログを変更せずにErrQueueLogCorruptを返す
+// 前回開いたときに受信したままだったメッセージは、失敗として再配信を待つ
+// WithClockで指定した時刻で追加時刻や可視性タイムアウトを判定する。それ以外のOptionは無視する
+func OpenQueue(cfg QueueConfig, opts ...Option) (DurableQueue, error) {
+	cfg = cfg.normalized()
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	log, records, err := openWAL(filepath.Join(cfg.Dir, queueLogName), cfg.Sync == SyncAlways)
+	if err != nil {
+		return nil, err
+	}
+	q := &durableQueue{
+		clock:   newOptions(opts).clock,
+		cfg:     cfg,
+		done:    make(chan struct{}),
+		log:     log,
+		entries: make(map[uint64]*queueEntry),
+		nextID:  1,
+		changed: make(chan struct{}),
+	}
+	for _, r := range records {
+		q.apply(r)
+	}
+
+	q.mu.Lock()
+	err = q.reclaimLocked(func(*queueEntry) bool { return true }, errLeaseAbandoned)
+	q.mu.Unlock()
+	if err != nil {
+		log.close()
+		return nil, err
+	}
+
+	if cfg.Sync == SyncPeriodic {
+		q.wg.Go(q.syncLoop)
+	}
+	return q, nil
+}
+
+// Enqueue はタスクを追加してIDを返す
+func (q *durableQueue) Enqueue(task string) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrQueueClosed
+	}
+	id := q.nextID
+	if err := q.writeLocked(walRecord{Op: walEnqueue, ID: id, Task: task, At: q.clock.Now()}); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Dequeue は配信を待っているメッセージを追加した順に受信する
+func (q *durableQueue) Dequeue(ctx context.Context) (QueueMessage, error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return QueueMessage{}, ErrQueueClosed
+		}
+		now := q.clock.Now()
+		if err := q.reclaimExpiredLocked(now); err != nil {
+			q.mu.Unlock()
+			return QueueMessage{}, err
+		}
+		if len(q.ready) > 0 {
+			id := q.ready[0]
+			err := q.writeLocked(walRecord{Op: walLease, ID: id, At: now.Add(q.cfg.VisibilityTimeout)})
+			msg := q.entries[id].msg
+			q.mu.Unlock()
+			if err != nil {
+				return QueueMessage{}, err
+			}
+			return msg, nil
+		}
+		changed, next := q.changed, q.nextDeadlineLocked()
+		q.mu.Unlock()
+
+		// 受信中のメッセージの可視性タイムアウトで再配信できるようになるまで待つ
+		var expired <-chan time.Time
+		var timer Timer
+		if !next.IsZero() {
+			timer = q.clock.NewTimer(next.Sub(now))
+			expired = timer.C()
+		}
+		select {
+		case <-changed:
+		case <-expired:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return QueueMessage{}, ctx.Err()
+		}
+	}
+}
+
+// Ack は受信したメッセージの処理が完了したことを記録し、キューから削除する
+func (q *durableQueue) Ack(msg QueueMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.leasedLocked(msg); err != nil {
+		return err
+	}
+	return q.writeLocked(walRecord{Op: walAck, ID: msg.ID})
+}
+
+// Nack は受信したメッセージの処理が失敗したことを記録する
+func (q *durableQueue) Nack(msg QueueMessage, cause error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, err := q.leasedLocked(msg)
+	if err != nil {
+		return err
+	}
+	if cause == nil {
+		cause = errors.New("synctest: nack")
+	}
+	return q.writeLocked(q.failure(e, cause))
+}
+
+// Release は受信したメッセージを失敗に数えずに先頭に戻す
+func (q *durableQueue) Release(msg QueueMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.leasedLocked(msg); err != nil {
+		return err
+	}
+	return q.writeLocked(walRecord{Op: walRelease, ID: msg.ID})
+}
+
+// DeadLetters はデッドレターのメッセージをIDの順に返す
+func (q *durableQueue) DeadLetters() []QueueMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var dead []QueueMessage
+	for _, e := range q.entries {
+		if e.dead {
+			dead = append(dead, e.msg)
+		}
+	}
+	slices.SortFunc(dead, func(a, b QueueMessage) int { return cmp.Compare(a.ID, b.ID) })
+	return dead
+}
+
+// Stats はキューの状態を返す
+func (q *durableQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{
+		Ready:      len(q.ready),
+		InFlight:   q.inFlight,
+		Dead:       len(q.entries) - len(q.ready) - q.inFlight,
+		LogRecords: q.log.records,
+	}
+}
+
+// Compact はログを現在の状態だけを記録したログに置き換える
+func (q *durableQueue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	return q.compactLocked()
+}
+
+// Close は同期していない操作をストレージに書き出してログを閉じる
+func (q *durableQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.changed)
+	close(q.done)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	return q.log.close()
+}
+
+// ProcessQueue 永続キューから受信したタスクをワーカープールで処理し、開始・完了時刻を通知する
+// WithWorkerPoolのconcurrency個のワーカーがそれぞれ受信と処理を繰り返すため、ワーカーが空くまでメッセージを受信しない
+// 処理に成功したメッセージはAckし、失敗したメッセージはNackしてErrを設定した結果を通知する
+// AckやNackに失敗した場合は、その原因もErrに含めて通知する。Ackできなかったメッセージは再配信されるため、同じタスクを再度通知することがある
+// キャンセルで中断したメッセージは失敗に数えずにReleaseし、Releaseに失敗した場合はその原因を終了の理由に含める
+// コンテキストが終了するかキューが閉じられると受信を止め、実行中のタスクが終了してから
+// Terminalな結果を最後に送信してチャネルを閉じる。スーパーバイザーに強制キャンセルされた場合、受信されなかったTerminalな結果は破棄する
+// キューを閉じるとAckできなくなるため、キューはチャネルが閉じられてから閉じる
+func (p *taskProcessor) ProcessQueue(ctx context.Context, q DurableQueue) <-chan TaskReport {
+	const op = "ProcessQueue"
+	o := p.operation(op)
+	cfg := o.pool.normalized()
+	report := make(chan TaskReport, cfg.concurrency)
+	tm := o.taskMetrics()
+	simulate := tracked(Guard(p.breaker, p.simulateTask))
+
+	// worker は受信を止めた原因と、中断したメッセージのReleaseに失敗した原因を返す
+	worker := func(ctx context.Context) (stop, release error) {
+		for {
+			msg, err := q.Dequeue(ctx)
+			if err != nil {
+				return err, nil
+			}
+			tm.submit()
+			j := job[string]{index: int(msg.ID - 1), input: msg.Task, task: o.registerTask(ctx, msg.Task)}
+			j.err = j.task.start()
+			tctx, span := o.startSpan(j.task.context(ctx), "task", append(taskAttributes(j.index, j.input), Attr("delivery", msg.Delivery))...)
+			r := execute(tctx, o.clock, j, simulate)
+			r.Err = j.task.finish(r.Value, r.Err)
+			span.RecordError(r.Err)
+			span.End()
+			tm.finish(r.Err, r.Duration)
+
+			switch {
+			case r.Err == nil:
+				if err := q.Ack(msg); err != nil {
+					r.Err = fmt.Errorf("ack message %d: %w", msg.ID, err)
+				}
+			case ctx.Err() != nil:
+				if err := q.Release(msg); err != nil {
+					return context.Cause(ctx), fmt.Errorf("synctest: release message %d: %w", msg.ID, err)
+				}
+				return context.Cause(ctx), nil
+			default:
+				if err := q.Nack(msg, r.Err); err != nil {
+					r.Err = errors.Join(r.Err, fmt.Errorf("nack message %d: %w", msg.ID, err))
+				}
+			}
+			if ctx.Err() != nil {
+				continue
+			}
+			select {
+			case report <- TaskReport{
+				Index:      j.index,
+				Task:       msg.Task,
+				Message:    r.Value,
+				StartedAt:  r.StartedAt,
+				FinishedAt: r.StartedAt.Add(r.Duration),
+				Err:        taskError(op, msg.Task, r.Err),
+			}:
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	started := p.spawn(ctx, op, func(ctx context.Context) {
+		defer close(report)
+
+		var wg sync.WaitGroup
+		errs := make([]error, cfg.concurrency)
+		released := make([]error, cfg.concurrency)
+		for i := range errs {
+			wg.Go(func() { errs[i], released[i] = worker(ctx) })
+		}
+		wg.Wait()
+		// コンテキストの終了、ログの書き込みの失敗、キューが閉じられたことの順に優先して終了の理由とする
+		var reason error
+		if ctx.Err() != nil {
+			reason = context.Cause(ctx)
+		}
+		for _, err := range errs {
+			if reason == nil || errors.Is(reason, ErrQueueClosed) {
+				reason = err
+			}
+		}
+		if err := errors.Join(released...); err != nil {
+			reason = errors.Join(reason, err)
+		}
+		sendTerminal(ctx, report, TaskReport{Index: terminalIndex, Err: reason})
+	})
+	if !started {
+		report <- TaskReport{Index: terminalIndex, Err: ErrShuttingDown}
+		close(report)
+	}
+
+	return report
+}
+
+// syncLoop はSyncIntervalごとにログを同期する
+func (q *durableQueue) syncLoop() {
+	ticker := q.clock.NewTicker(q.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			q.mu.Lock()
+			if !q.closed {
+				q.log.sync()
+			}
+			q.mu.Unlock()
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// writeLocked はレコードをログに追記してから状態に反映する
+// 不要なレコードがCompactThresholdに達した場合はログを圧縮する
+// 圧縮に失敗しても追記したレコードは失われないため、エラーは返さずに次の追記で再度圧縮を試みる
+func (q *durableQueue) writeLocked(records ...walRecord) error {
+	if err := q.log.append(records...); err != nil {
+		return err
+	}
+	for _, r := range records {
+		q.apply(r)
+	}
+	if q.log.records-q.liveRecordsLocked() >= q.cfg.CompactThreshold {
+		q.compactLocked()
+	}
+	return nil
+}
+
+// apply はレコードを状態に反映する。ログを復元するときと追記したときに呼び出す
+// 存在しないメッセージへの操作は無視する
+func (q *durableQueue) apply(r walRecord) {
+	if r.Op == walNext {
+		q.nextID = max(q.nextID, r.ID)
+		return
+	}
+	if r.Op == walEnqueue {
+		q.nextID = max(q.nextID, r.ID+1)
+		e := &queueEntry{
+			msg: QueueMessage{
+				ID:         r.ID,
+				Task:       r.Task,
+				EnqueuedAt: r.At,
+				Delivery:   r.Delivery,
+				Failures:   r.Failures,
+				LastError:  r.Error,
+			},
+			dead: r.Dead,
+		}
+		q.entries[r.ID] = e
+		if !e.dead {
+			q.ready = append(q.ready, r.ID)
+			q.notifyLocked()
+		}
+		return
+	}
+
+	e, ok := q.entries[r.ID]
+	if !ok || e.dead {
+		return
+	}
+	leased := !e.msg.Deadline.IsZero()
+	switch r.Op {
+	case walLease:
+		if leased {
+			return
+		}
+		q.ready = slices.DeleteFunc(q.ready, func(id uint64) bool { return id == r.ID })
+		q.inFlight++
+		e.msg.Delivery++
+		e.msg.Deadline = r.At
+	case walAck:
+		if !leased {
+			return
+		}
+		q.inFlight--
+		delete(q.entries, r.ID)
+	case walNack, walDead:
+		if !leased {
+			return
+		}
+		q.inFlight--
+		e.msg.Deadline = time.Time{}
+		e.msg.Failures++
+		e.msg.LastError = r.Error
+		if r.Op == walDead {
+			e.dead = true
+			return
+		}
+		q.ready = append(q.ready, r.ID)
+		q.notifyLocked()
+	case walRelease:
+		if !leased {
+			return
+		}
+		q.inFlight--
+		e.msg.Deadline = time.Time{}
+		q.ready = slices.Insert(q.ready, 0, r.ID)
+		q.notifyLocked()
+	}
+}
+
+// notifyLocked は配信を待っているメッセージが増えたことを待機中のDequeueに知らせる
+func (q *durableQueue) notifyLocked() {
+	if q.closed {
+		return
+	}
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// leasedLocked はmsgの配信が受信中のまま有効であればそのメッセージを返す
+func (q *durableQueue) leasedLocked(msg QueueMessage) (*queueEntry, error) {
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+	e, ok := q.entries[msg.ID]
+	if !ok || e.dead || e.msg.Deadline.IsZero() || e.msg.Delivery != msg.Delivery {
+		return nil, ErrLeaseExpired
+	}
+	if now := q.clock.Now(); !now.Before(e.msg.Deadline) {
+		if err := q.reclaimExpiredLocked(now); err != nil {
+			return nil, err
+		}
+		return nil, ErrLeaseExpired
+	}
+	return e, nil
+}
+
+// failure は受信中のメッセージの失敗を記録するレコードを返す
+func (q *durableQueue) failure(e *queueEntry, cause error) walRecord {
+	op := walNack
+	if e.msg.Failures+1 >= q.cfg.MaxFailures {
+		op = walDead
+	}
+	return walRecord{Op: op, ID: e.msg.ID, Error: cause.Error()}
+}
+
+// reclaimExpiredLocked は可視性タイムアウトを過ぎた受信中のメッセージを失敗として記録する
+func (q *durableQueue) reclaimExpiredLocked(now time.Time) error {
+	return q.reclaimLocked(func(e *queueEntry) bool { return !now.Before(e.msg.Deadline) }, errVisibilityTimeout)
+}
+
+// reclaimLocked はexpiredがtrueを返す受信中のメッセージを、IDの順にcauseの失敗として記録する
+func (q *durableQueue) reclaimLocked(expired func(e *queueEntry) bool, cause error) error {
+	if q.inFlight == 0 {
+		return nil
+	}
+	var records []walRecord
+	for _, e := range q.entries {
+		if !e.dead && !e.msg.Deadline.IsZero() && expired(e) {
+			records = append(records, q.failure(e, cause))
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	slices.SortFunc(records, func(a, b walRecord) int { return cmp.Compare(a.ID, b.ID) })
+	return q.writeLocked(records...)
+}
+
+// nextDeadlineLocked は受信中のメッセージの可視性タイムアウトの最も早い期限を返す
+func (q *durableQueue) nextDeadlineLocked() time.Time {
+	var next time.Time
+	for _, e := range q.entries {
+		if d := e.msg.Deadline; !e.dead && !d.IsZero() && (next.IsZero() || d.Before(next)) {
+			next = d
+		}
+	}
+	return next
+}
+
+// liveRecordsLocked は現在の状態を記録するのに必要なレコード数を返す
+func (q *durableQueue) liveRecordsLocked() int {
+	return 1 + len(q.entries) + q.inFlight
+}
+
+// compactLocked は現在の状態だけを記録したログに置き換える
+// 配信を待っているメッセージは配信する順に、受信中のメッセージは配信の記録とともに書き出す
+func (q *durableQueue) compactLocked() error {
+	records := []walRecord{{Op: walNext, ID: q.nextID}}
+	snapshot := func(e *queueEntry) walRecord {
+		return walRecord{
+			Op:       walEnqueue,
+			ID:       e.msg.ID,
+			Task:     e.msg.Task,
+			At:       e.msg.EnqueuedAt,
+			Delivery: e.msg.Delivery,
+			Failures: e.msg.Failures,
+			Error:    e.msg.LastError,
+			Dead:     e.dead,
+		}
+	}
+	for _, id := range q.ready {
+		records = append(records, snapshot(q.entries[id]))
+	}
+	rest := make([]*queueEntry, 0, len(q.entries)-len(q.ready))
+	for _, e := range q.entries {
+		if e.dead || !e.msg.Deadline.IsZero() {
+			rest = append(rest, e)
+		}
+	}
+	slices.SortFunc(rest, func(a, b *queueEntry) int { return cmp.Compare(a.msg.ID, b.msg.ID) })
+	for _, e := range rest {
+		if e.dead {
+			records = append(records, snapshot(e))
+			continue
+		}
+		// 配信の記録で配信回数が1増えるため、1つ少ない回数で書き出す
+		rec := snapshot(e)
+		rec.Delivery--
+		records = append(records, rec, walRecord{Op: walLease, ID: e.msg.ID, At: e.msg.Deadline})
+	}
+	return q.log.rewrite(records)
+}
@@ -0,0 +1,496 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// delivered はキューから受信したメッセージのうち検証する項目
+type delivered struct {
+	Task     string
+	Delivery int
+	Failures int
+}
+
+// openQueue はテスト用の一時ディレクトリにキューを開く
+func openQueue(t *testing.T, cfg synctestpkg.QueueConfig) synctestpkg.DurableQueue {
+	t.Helper()
+	if cfg.Dir == "" {
+		cfg.Dir = t.TempDir()
+	}
+	q, err := synctestpkg.OpenQueue(cfg)
+	if err != nil {
+		t.Fatalf("キューを開けませんでした: %v", err)
+	}
+	return q
+}
+
+// enqueue はタスクを順に追加する
+func enqueue(t *testing.T, q synctestpkg.DurableQueue, tasks ...string) {
+	t.Helper()
+	for _, task := range tasks {
+		if _, err := q.Enqueue(task); err != nil {
+			t.Fatalf("追加できませんでした: %v", err)
+		}
+	}
+}
+
+// dequeueAll は配信を待っているメッセージをすべて受信してAckする
+func dequeueAll(t *testing.T, q synctestpkg.DurableQueue) []delivered {
+	t.Helper()
+	var got []delivered
+	for q.Stats().Ready > 0 {
+		msg, err := q.Dequeue(context.Background())
+		if err != nil {
+			t.Fatalf("受信できませんでした: %v", err)
+		}
+		got = append(got, delivered{Task: msg.Task, Delivery: msg.Delivery, Failures: msg.Failures})
+		if err := q.Ack(msg); err != nil {
+			t.Fatalf("Ackできませんでした: %v", err)
+		}
+	}
+	return got
+}
+
+func TestDurableQueue(t *testing.T) {
+	t.Run("Table Driven Test - 受信したメッセージの扱い", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			settle func(q synctestpkg.DurableQueue, msg synctestpkg.QueueMessage) error
+			expect []delivered
+		}{
+			{
+				name:   "Ackすると削除する",
+				settle: func(q synctestpkg.DurableQueue, msg synctestpkg.QueueMessage) error { return q.Ack(msg) },
+				expect: []delivered{{Task: "b", Delivery: 1}},
+			},
+			{
+				name: "Nackすると末尾に戻す",
+				settle: func(q synctestpkg.DurableQueue, msg synctestpkg.QueueMessage) error {
+					return q.Nack(msg, errors.New("失敗しました"))
+				},
+				expect: []delivered{{Task: "b", Delivery: 1}, {Task: "a", Delivery: 2, Failures: 1}},
+			},
+			{
+				name:   "Releaseすると失敗に数えずに先頭に戻す",
+				settle: func(q synctestpkg.DurableQueue, msg synctestpkg.QueueMessage) error { return q.Release(msg) },
+				expect: []delivered{{Task: "a", Delivery: 2}, {Task: "b", Delivery: 1}},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					q := openQueue(t, synctestpkg.QueueConfig{})
+					defer q.Close()
+					enqueue(t, q, "a", "b")
+
+					msg, err := q.Dequeue(context.Background())
+					if err != nil {
+						t.Fatalf("受信できませんでした: %v", err)
+					}
+					if msg.Task != "a" || msg.ID != 1 || msg.Delivery != 1 {
+						t.Fatalf("追加した順に受信することを期待しました: %+v", msg)
+					}
+					if err := tc.settle(q, msg); err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+					if err := q.Ack(msg); !errors.Is(err, synctestpkg.ErrLeaseExpired) {
+						t.Errorf("処理済みのメッセージのAckはErrLeaseExpiredを期待しました: %v", err)
+					}
+
+					if got := dequeueAll(t, q); !slices.Equal(got, tc.expect) {
+						t.Errorf("受信したメッセージが期待値と異なります: got %+v, want %+v", got, tc.expect)
+					}
+					if stats := q.Stats(); stats.Ready != 0 || stats.InFlight != 0 || stats.Dead != 0 {
+						t.Errorf("すべて処理されることを期待しました: %+v", stats)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("可視性タイムアウトを過ぎると再配信する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			q := openQueue(t, synctestpkg.QueueConfig{VisibilityTimeout: time.Second})
+			defer q.Close()
+			enqueue(t, q, "a")
+			start := time.Now()
+
+			first, err := q.Dequeue(context.Background())
+			if err != nil {
+				t.Fatalf("受信できませんでした: %v", err)
+			}
+			if want := start.Add(time.Second); !first.Deadline.Equal(want) {
+				t.Errorf("期限が期待値と異なります: got %v, want %v", first.Deadline, want)
+			}
+			// 受信中の間は他の受信者に配信せず、期限を過ぎた時点で再配信する
+			second, err := q.Dequeue(context.Background())
+			if err != nil {
+				t.Fatalf("受信できませんでした: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed != time.Second {
+				t.Errorf("再配信までの時間が期待値と異なります: got %v, want %v", elapsed, time.Second)
+			}
+			if second.ID != first.ID || second.Delivery != 2 || second.Failures != 1 || second.LastError != "synctest: visibility timeout expired" {
+				t.Errorf("再配信したメッセージが期待値と異なります: %+v", second)
+			}
+
+			if err := q.Ack(first); !errors.Is(err, synctestpkg.ErrLeaseExpired) {
+				t.Errorf("期限を過ぎた配信のAckはErrLeaseExpiredを期待しました: %v", err)
+			}
+			if err := q.Ack(second); err != nil {
+				t.Errorf("再配信したメッセージはAckできることを期待しました: %v", err)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - デッドレター", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			fail        func(q synctestpkg.DurableQueue, msg synctestpkg.QueueMessage)
+			expectError string
+		}{
+			{
+				name: "Nack",
+				fail: func(q synctestpkg.DurableQueue, msg synctestpkg.QueueMessage) {
+					q.Nack(msg, errors.New("失敗しました"))
+				},
+				expectError: "失敗しました",
+			},
+			{
+				name: "可視性タイムアウト",
+				fail: func(q synctestpkg.DurableQueue, msg synctestpkg.QueueMessage) {
+					time.Sleep(time.Second)
+				},
+				expectError: "synctest: visibility timeout expired",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					q := openQueue(t, synctestpkg.QueueConfig{VisibilityTimeout: time.Second, MaxFailures: 2})
+					defer q.Close()
+					enqueue(t, q, "a")
+
+					for range 2 {
+						msg, err := q.Dequeue(context.Background())
+						if err != nil {
+							t.Fatalf("受信できませんでした: %v", err)
+						}
+						tc.fail(q, msg)
+					}
+					// デッドレターは配信しない
+					ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+					defer cancel()
+					if _, err := q.Dequeue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+						t.Errorf("デッドレターを配信しないことを期待しました: %v", err)
+					}
+
+					dead := q.DeadLetters()
+					if len(dead) != 1 || dead[0].Task != "a" || dead[0].Failures != 2 || dead[0].LastError != tc.expectError {
+						t.Errorf("デッドレターが期待値と異なります: %+v", dead)
+					}
+					if stats := q.Stats(); stats != (synctestpkg.QueueStats{Dead: 1, LogRecords: stats.LogRecords}) {
+						t.Errorf("デッドレターだけが残ることを期待しました: %+v", stats)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("追加されるまで待つ", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			q := openQueue(t, synctestpkg.QueueConfig{})
+			defer q.Close()
+			start := time.Now()
+
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				q.Enqueue("a")
+			}()
+			msg, err := q.Dequeue(context.Background())
+
+			if err != nil || msg.Task != "a" {
+				t.Fatalf("追加されたメッセージを受信することを期待しました: %+v, %v", msg, err)
+			}
+			if elapsed := time.Since(start); elapsed != 50*time.Millisecond {
+				t.Errorf("受信までの時間が期待値と異なります: got %v, want %v", elapsed, 50*time.Millisecond)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - 受信を待つのをやめる", func(t *testing.T) {
+		testCases := []struct {
+			name      string
+			stop      func(q synctestpkg.DurableQueue, cancel context.CancelFunc)
+			expectErr error
+		}{
+			{
+				name:      "キャンセル",
+				stop:      func(_ synctestpkg.DurableQueue, cancel context.CancelFunc) { cancel() },
+				expectErr: context.Canceled,
+			},
+			{
+				name:      "Close",
+				stop:      func(q synctestpkg.DurableQueue, _ context.CancelFunc) { q.Close() },
+				expectErr: synctestpkg.ErrQueueClosed,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					q := openQueue(t, synctestpkg.QueueConfig{})
+					defer q.Close()
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+
+					errc := make(chan error)
+					go func() {
+						_, err := q.Dequeue(ctx)
+						errc <- err
+					}()
+					synctest.Wait()
+					tc.stop(q, cancel)
+
+					if err := <-errc; !errors.Is(err, tc.expectErr) {
+						t.Errorf("エラーが期待値と異なります: got %v, want %v", err, tc.expectErr)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Close後の操作", func(t *testing.T) {
+		q := openQueue(t, synctestpkg.QueueConfig{})
+		enqueue(t, q, "a")
+		msg, err := q.Dequeue(context.Background())
+		if err != nil {
+			t.Fatalf("受信できませんでした: %v", err)
+		}
+		if err := q.Close(); err != nil {
+			t.Fatalf("Closeに失敗しました: %v", err)
+		}
+
+		if _, err := q.Enqueue("b"); !errors.Is(err, synctestpkg.ErrQueueClosed) {
+			t.Errorf("EnqueueはErrQueueClosedを期待しました: %v", err)
+		}
+		if err := q.Ack(msg); !errors.Is(err, synctestpkg.ErrQueueClosed) {
+			t.Errorf("AckはErrQueueClosedを期待しました: %v", err)
+		}
+		if err := q.Compact(); !errors.Is(err, synctestpkg.ErrQueueClosed) {
+			t.Errorf("CompactはErrQueueClosedを期待しました: %v", err)
+		}
+		if err := q.Close(); err != nil {
+			t.Errorf("2回目のCloseはエラーを返さないことを期待しました: %v", err)
+		}
+	})
+
+	t.Run("Table Driven Test - 再び開くと状態を復元する", func(t *testing.T) {
+		testCases := []struct {
+			name             string
+			compactThreshold int
+		}{
+			{name: "圧縮しない"},
+			// 追記のたびに圧縮したログからも同じ状態を復元する
+			{name: "追記のたびに圧縮する", compactThreshold: 1},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					cfg := synctestpkg.QueueConfig{Dir: t.TempDir(), MaxFailures: 2, CompactThreshold: tc.compactThreshold}
+					q := openQueue(t, cfg)
+					enqueue(t, q, "a", "b", "c", "d")
+					nack := func(msg synctestpkg.QueueMessage) error { return q.Nack(msg, errors.New("失敗しました")) }
+					steps := []struct {
+						task   string
+						settle func(msg synctestpkg.QueueMessage) error
+					}{
+						{task: "a", settle: nack},
+						{task: "b", settle: q.Ack},
+						{task: "c", settle: nack},
+						{task: "d", settle: func(synctestpkg.QueueMessage) error { return nil }}, // 受信したまま閉じる
+						{task: "a", settle: nack}, // 2回目の失敗でデッドレターに移す
+					}
+					for _, step := range steps {
+						msg, err := q.Dequeue(context.Background())
+						if err != nil {
+							t.Fatalf("受信できませんでした: %v", err)
+						}
+						if msg.Task != step.task {
+							t.Fatalf("受信したタスクが期待値と異なります: got %s, want %s", msg.Task, step.task)
+						}
+						if err := step.settle(msg); err != nil {
+							t.Fatalf("エラーが発生しました: %v", err)
+						}
+					}
+					if err := q.Close(); err != nil {
+						t.Fatalf("Closeに失敗しました: %v", err)
+					}
+
+					reopened := openQueue(t, cfg)
+					defer reopened.Close()
+					id, err := reopened.Enqueue("e")
+					if err != nil || id != 5 {
+						t.Errorf("IDを引き継ぐことを期待しました: got %d, %v", id, err)
+					}
+					dead := reopened.DeadLetters()
+					if len(dead) != 1 || dead[0].Task != "a" || dead[0].Failures != 2 {
+						t.Errorf("デッドレターを復元することを期待しました: %+v", dead)
+					}
+					// 受信したまま閉じたdは失敗として末尾に戻る
+					want := []delivered{
+						{Task: "c", Delivery: 2, Failures: 1},
+						{Task: "d", Delivery: 2, Failures: 1},
+						{Task: "e", Delivery: 1},
+					}
+					if got := dequeueAll(t, reopened); !slices.Equal(got, want) {
+						t.Errorf("受信したメッセージが期待値と異なります: got %+v, want %+v", got, want)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Compactで不要なレコードを取り除く", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			cfg := synctestpkg.QueueConfig{Dir: t.TempDir(), CompactThreshold: 10}
+			q := openQueue(t, cfg)
+			for i := range 20 {
+				enqueue(t, q, string(rune('a'+i)))
+				dequeueAll(t, q)
+				// 追加・配信・完了の3レコードずつ増え、不要なレコードが10に達するたびに圧縮する
+				if records := q.Stats().LogRecords; records > 10 {
+					t.Fatalf("ログのレコード数が閾値を超えています: %d", records)
+				}
+			}
+			enqueue(t, q, "u")
+			if err := q.Compact(); err != nil {
+				t.Fatalf("圧縮に失敗しました: %v", err)
+			}
+			if records := q.Stats().LogRecords; records != 2 {
+				t.Errorf("次のIDと残っているメッセージだけを記録することを期待しました: %d", records)
+			}
+			if err := q.Close(); err != nil {
+				t.Fatalf("Closeに失敗しました: %v", err)
+			}
+
+			reopened := openQueue(t, cfg)
+			defer reopened.Close()
+			if id, _ := reopened.Enqueue("v"); id != 22 {
+				t.Errorf("圧縮した後もIDを引き継ぐことを期待しました: %d", id)
+			}
+			if got := dequeueAll(t, reopened); len(got) != 2 || got[0].Task != "u" || got[1].Task != "v" {
+				t.Errorf("受信したメッセージが期待値と異なります: %+v", got)
+			}
+		})
+	})
+}
+
+func TestProcessQueue(t *testing.T) {
+	testCases := []struct {
+		name         string
+		timeout      time.Duration
+		expectTasks  []string
+		expectFinish []time.Duration
+		expectReady  []delivered
+	}{
+		{
+			name:         "すべて完了",
+			timeout:      time.Second,
+			expectTasks:  []string{"a", "b", "c"},
+			expectFinish: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			// 2並列のため、cは100msに開始して150msにキャンセルされ、失敗に数えずに戻る
+			name:         "実行中のタスクのキャンセル",
+			timeout:      150 * time.Millisecond,
+			expectTasks:  []string{"a", "b"},
+			expectFinish: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond},
+			expectReady:  []delivered{{Task: "c", Delivery: 2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				cfg := synctestpkg.QueueConfig{Dir: t.TempDir()}
+				q := openQueue(t, cfg)
+				enqueue(t, q, "a", "b", "c")
+				ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+				defer cancel()
+				processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(2, 0))
+				start := time.Now()
+
+				var tasks []string
+				var finish []time.Duration
+				var reason error
+				for r := range processor.ProcessQueue(ctx, q) {
+					if r.Terminal() {
+						reason = r.Err
+						continue
+					}
+					tasks = append(tasks, r.Task)
+					finish = append(finish, r.FinishedAt.Sub(start))
+				}
+				slices.Sort(tasks)
+
+				// 受信を止めた理由が最後に通知される
+				if !errors.Is(reason, context.DeadlineExceeded) {
+					t.Errorf("終了の理由が期待値と異なります: got %v, want %v", reason, context.DeadlineExceeded)
+				}
+				if !slices.Equal(tasks, tc.expectTasks) {
+					t.Errorf("完了したタスクが期待値と異なります: got %v, want %v", tasks, tc.expectTasks)
+				}
+				if !slices.Equal(finish, tc.expectFinish) {
+					t.Errorf("完了時刻が期待値と異なります: got %v, want %v", finish, tc.expectFinish)
+				}
+				if err := q.Close(); err != nil {
+					t.Fatalf("Closeに失敗しました: %v", err)
+				}
+				// 完了したタスクはAckされ、中断したタスクは次に開いたときに再度処理できる
+				reopened := openQueue(t, cfg)
+				defer reopened.Close()
+				if got := dequeueAll(t, reopened); !slices.Equal(got, tc.expectReady) {
+					t.Errorf("残っているメッセージが期待値と異なります: got %+v, want %+v", got, tc.expectReady)
+				}
+			})
+		})
+	}
+}
+
+func TestProcessQueueSettleError(t *testing.T) {
+	t.Run("Ackに失敗した場合はその原因を通知する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// 処理に100msかかるため、50msで可視性タイムアウトが過ぎてAckできなくなる
+			q := openQueue(t, synctestpkg.QueueConfig{Dir: t.TempDir(), VisibilityTimeout: 50 * time.Millisecond})
+			defer q.Close()
+			enqueue(t, q, "a")
+			ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+			defer cancel()
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(1, 0))
+
+			var reports []synctestpkg.TaskReport
+			for r := range processor.ProcessQueue(ctx, q) {
+				reports = append(reports, r)
+			}
+
+			if len(reports) == 0 || reports[0].Terminal() {
+				t.Fatalf("タスクの結果が通知されていません: %+v", reports)
+			}
+			var taskErr *synctestpkg.TaskError
+			if r := reports[0]; r.Task != "a" || !errors.Is(r.Err, synctestpkg.ErrLeaseExpired) || !errors.As(r.Err, &taskErr) {
+				t.Errorf("ErrLeaseExpiredを包んだ*TaskErrorを期待しましたが、%+vが返されました", r)
+			}
+		})
+	})
+}
@@ -0,0 +1,352 @@
+package synctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited は流量制限により実行枠を確保できなかったことを表す
+var ErrRateLimited = errors.New("synctest: rate limited")
+
+// Limiter は単位時間あたりの実行回数を制限するインターフェース
+type Limiter interface {
+	// Allow は今すぐ実行できる場合に実行枠を消費してtrueを返す。待つ必要がある場合は何も消費せずfalseを返す
+	Allow() bool
+	// Wait は実行枠を確保し、実行できる時刻まで待機する
+	// 待機が終わる前にctxが終了した場合は確保した枠を返却してctx.Err()を返す
+	// ctxの期限までに実行できない場合や枠を確保できない場合は待機せずにErrRateLimitedを返す
+	Wait(ctx context.Context) error
+	// Reserve は実行枠を予約する。予約した時刻までの待機は呼び出し側で行う
+	Reserve() Reservation
+}
+
+// Reservation はLimiterで予約した実行枠
+type Reservation interface {
+	// OK は予約できたかどうかを返す
+	OK() bool
+	// Delay は予約した時刻までの残り時間を返す。予約できなかった場合はtime.Durationの最大値を返す
+	Delay() time.Duration
+	// Cancel は予約を取り消して枠を返却する。予約した時刻を過ぎている場合は何もしない
+	Cancel()
+}
+
+// rateAlgorithm は流量制限のアルゴリズム
+// limiterのロックを取得した状態で呼び出される
+type rateAlgorithm interface {
+	// reserve はnowから最大maxWaitだけ待って実行できる枠を確保し、その時刻を返す
+	reserve(now time.Time, maxWait time.Duration) (at time.Time, ok bool)
+	// cancel はatに確保した枠を返却する
+	cancel(now, at time.Time)
+	// idle はnowの時点で、作成した直後と区別できない状態かどうかを返す
+	idle(now time.Time) bool
+}
+
+// limiter はLimiterの具象実装。アルゴリズムに依存しない待機や予約の取り消しを扱う
+type limiter struct {
+	options
+	mu  sync.Mutex
+	alg rateAlgorithm
+}
+
+// NewTokenBucket はトークンバケット方式のLimiterを作成する
+// トークンはintervalごとに1つ補充され、最大burst個まで貯まる。貯まっている間はburst回まで連続して実行できる
+// burstが0以下の場合は1として扱う
+func NewTokenBucket(interval time.Duration, burst int, opts ...Option) Limiter {
+	return newLimiter(&gcra{interval: interval, burst: max(burst, 1), maxQueue: maxDuration}, opts)
+}
+
+// NewLeakyBucket はリーキーバケット方式のLimiterを作成する
+// バケットからはintervalごとに1回ずつ実行が流れ出し、最大capacity件まで実行を待たせられる
+// トークンバケットと異なり連続した実行は許さず、常に一定の間隔に平準化する
+func NewLeakyBucket(interval time.Duration, capacity int, opts ...Option) Limiter {
+	return newLimiter(&gcra{interval: interval, burst: 1, maxQueue: saturatingMul(interval, float64(max(capacity, 0)))}, opts)
+}
+
+// NewSlidingWindowLog はスライディングウィンドウログ方式のLimiterを作成する
+// 実行した時刻を記録し、直近windowの間の実行回数がlimit回を超えないようにする
+// limitが0以下の場合は1として扱う
+func NewSlidingWindowLog(window time.Duration, limit int, opts ...Option) Limiter {
+	return newLimiter(&slidingWindowLog{window: window, limit: max(limit, 1)}, opts)
+}
+
+// newLimiter はアルゴリズムにOptionの設定を組み合わせたLimiterを作成する
+func newLimiter(alg rateAlgorithm, opts []Option) *limiter {
+	return &limiter{options: newOptions(opts), alg: alg}
+}
+
+// Allow は今すぐ実行できる場合に実行枠を消費する
+func (l *limiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.alg.reserve(l.clock.Now(), 0)
+	return ok
+}
+
+// Reserve は実行枠を予約する
+func (l *limiter) Reserve() Reservation {
+	return l.reserve(maxDuration)
+}
+
+// reserve は最大maxWaitだけ待って実行できる枠を予約する
+func (l *limiter) reserve(maxWait time.Duration) *reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at, ok := l.alg.reserve(l.clock.Now(), maxWait)
+	return &reservation{limiter: l, at: at, ok: ok}
+}
+
+// Wait は実行枠を確保し、実行できる時刻まで待機する
+func (l *limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	maxWait := maxDuration
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(l.clock.Now())
+	}
+
+	r := l.reserve(maxWait)
+	if !r.ok {
+		if maxWait < maxDuration {
+			return fmt.Errorf("%w: would exceed context deadline", ErrRateLimited)
+		}
+		return ErrRateLimited
+	}
+	d := r.Delay()
+	if d == 0 {
+		return nil
+	}
+
+	timer := l.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// idle は作成した直後と区別できない状態かどうかを返す
+func (l *limiter) idle() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.alg.idle(l.clock.Now())
+}
+
+// reservation はReservationの具象実装
+type reservation struct {
+	limiter  *limiter
+	at       time.Time
+	ok       bool
+	canceled bool
+}
+
+// OK は予約できたかどうかを返す
+func (r *reservation) OK() bool {
+	return r.ok
+}
+
+// Delay は予約した時刻までの残り時間を返す
+func (r *reservation) Delay() time.Duration {
+	if !r.ok {
+		return maxDuration
+	}
+	return max(r.at.Sub(r.limiter.clock.Now()), 0)
+}
+
+// Cancel は予約を取り消して枠を返却する
+func (r *reservation) Cancel() {
+	r.limiter.mu.Lock()
+	defer r.limiter.mu.Unlock()
+	if !r.ok || r.canceled {
+		return
+	}
+	r.canceled = true
+	r.limiter.alg.cancel(r.limiter.clock.Now(), r.at)
+}
+
+// gcra はGeneric Cell Rate Algorithmによるトークンバケットとリーキーバケットの実装
+// トークンの数を数える代わりに、バケットが空になる理論上の時刻tatだけを保持する
+type gcra struct {
+	interval time.Duration // 1回の実行でバケットに加わる時間
+	burst    int           // 連続して実行できる回数
+	maxQueue time.Duration // 待機できる時間の上限
+	tat      time.Time     // Theoretical Arrival Time
+}
+
+// reserve は実行できる時刻を求めて枠を確保する
+func (g *gcra) reserve(now time.Time, maxWait time.Duration) (time.Time, bool) {
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	// burst回分の実行はtatより前倒しで許可する
+	at := tat.Add(-g.interval * time.Duration(g.burst-1))
+	if at.Before(now) {
+		at = now
+	}
+	if wait := at.Sub(now); wait > maxWait || wait > g.maxQueue {
+		return time.Time{}, false
+	}
+	g.tat = tat.Add(g.interval)
+	return at, true
+}
+
+// cancel は確保した枠の分だけtatを戻す
+// 後から確保された枠がある場合にtatを戻すとその枠と同じ時刻を再び確保してしまうため、最後に確保した枠だけを返却する
+func (g *gcra) cancel(now, at time.Time) {
+	if !at.After(now) {
+		return
+	}
+	// 待機が必要な枠ではatが前倒しされていないため、確保した直後のtatはatからburst回分後になる
+	if g.tat.Equal(at.Add(g.interval * time.Duration(g.burst))) {
+		g.tat = g.tat.Add(-g.interval)
+	}
+}
+
+// idle はtatを過ぎてバケットが空になっているかどうかを返す
+func (g *gcra) idle(now time.Time) bool {
+	return !g.tat.After(now)
+}
+
+// slidingWindowLog はスライディングウィンドウログ方式の実装
+type slidingWindowLog struct {
+	window time.Duration
+	limit  int
+	log    []time.Time // 確保した枠の時刻。昇順に並べる
+}
+
+// reserve は直近windowの実行回数がlimit未満になる時刻を求めて枠を確保する
+func (s *slidingWindowLog) reserve(now time.Time, maxWait time.Duration) (time.Time, bool) {
+	// ウィンドウから外れた記録を捨てる
+	expired := 0
+	for expired < len(s.log) && !s.log[expired].After(now.Add(-s.window)) {
+		expired++
+	}
+	s.log = s.log[expired:]
+
+	at := now
+	if n := len(s.log); n >= s.limit {
+		// limit個前の記録がウィンドウから外れると実行できる
+		at = s.log[n-s.limit].Add(s.window)
+	}
+	if at.Sub(now) > maxWait {
+		return time.Time{}, false
+	}
+	i, _ := slices.BinarySearchFunc(s.log, at, time.Time.Compare)
+	s.log = slices.Insert(s.log, i, at)
+	return at, true
+}
+
+// cancel はatの記録を取り除く
+func (s *slidingWindowLog) cancel(now, at time.Time) {
+	if !at.After(now) {
+		return
+	}
+	if i, found := slices.BinarySearchFunc(s.log, at, time.Time.Compare); found {
+		s.log = slices.Delete(s.log, i, i+1)
+	}
+}
+
+// idle はすべての記録がウィンドウから外れているかどうかを返す
+func (s *slidingWindowLog) idle(now time.Time) bool {
+	return len(s.log) == 0 || !s.log[len(s.log)-1].After(now.Add(-s.window))
+}
+
+// KeyedLimiter はテナントなどのキーごとに独立した流量制限を行うインターフェース
+type KeyedLimiter[K comparable] interface {
+	// Allow はキーのLimiterで今すぐ実行できるかを判定する
+	Allow(key K) bool
+	// Wait はキーのLimiterで実行枠を確保して待機する
+	Wait(ctx context.Context, key K) error
+	// Reserve はキーのLimiterで実行枠を予約する
+	Reserve(key K) Reservation
+	// Forget はキーのLimiterを破棄する。次に利用した時点で新しいLimiterが作成される
+	Forget(key K)
+}
+
+// keyedSweepMin はKeyedLimiterが使われなくなったLimiterを破棄し始めるキーの数
+const keyedSweepMin = 64
+
+// keyedLimiter はKeyedLimiterの具象実装
+type keyedLimiter[K comparable] struct {
+	mu         sync.Mutex
+	limiters   map[K]Limiter
+	newLimiter func(key K) Limiter
+	sweepAt    int // キーの数がこの値に達したら使われなくなったLimiterを破棄する
+}
+
+// NewKeyedLimiter はキーごとにnewLimiterで作成したLimiterを使い分けるKeyedLimiterを作成する
+// newLimiterはキーが初めて利用された時点で呼び出されるため、キーに応じて異なる制限を設定できる
+// NewTokenBucketなどで作成したLimiterは、作成した直後と同じ状態に戻ると新しいキーを追加するときにまとめて破棄し、
+// 次に利用した時点でnewLimiterから作成し直す。破棄しても制限の結果は変わらない
+func NewKeyedLimiter[K comparable](newLimiter func(key K) Limiter) KeyedLimiter[K] {
+	return &keyedLimiter[K]{
+		limiters:   make(map[K]Limiter),
+		newLimiter: newLimiter,
+		sweepAt:    keyedSweepMin,
+	}
+}
+
+// Allow はキーのLimiterで今すぐ実行できるかを判定する
+func (k *keyedLimiter[K]) Allow(key K) bool {
+	return k.get(key).Allow()
+}
+
+// Wait はキーのLimiterで実行枠を確保して待機する
+func (k *keyedLimiter[K]) Wait(ctx context.Context, key K) error {
+	return k.get(key).Wait(ctx)
+}
+
+// Reserve はキーのLimiterで実行枠を予約する
+func (k *keyedLimiter[K]) Reserve(key K) Reservation {
+	return k.get(key).Reserve()
+}
+
+// Forget はキーのLimiterを破棄する
+func (k *keyedLimiter[K]) Forget(key K) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.limiters, key)
+}
+
+// get はキーのLimiterを返す。存在しない場合は作成する
+func (k *keyedLimiter[K]) get(key K) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	l, ok := k.limiters[key]
+	if !ok {
+		if len(k.limiters) >= k.sweepAt {
+			k.sweepLocked()
+		}
+		l = k.newLimiter(key)
+		k.limiters[key] = l
+	}
+	return l
+}
+
+// sweepLocked は作成した直後と同じ状態に戻ったLimiterを破棄する
+// 次に破棄するまでのキーの数を残ったキーの数の2倍にして、破棄にかかる時間を追加したキーの数に比例させる
+func (k *keyedLimiter[K]) sweepLocked() {
+	for key, l := range k.limiters {
+		if l, ok := l.(*limiter); ok && l.idle() {
+			delete(k.limiters, key)
+		}
+	}
+	k.sweepAt = max(2*len(k.limiters), keyedSweepMin)
+}
+
+// WithRateLimiter はタスクの実行を開始する前にLimiterで実行枠を確保する
+// ProcessWithDelayでは遅延の前に、ProcessWithGoroutine・ProcessWithPool・Run・RunStreamでは入力を受け付ける順に枠を確保する
+// 枠を確保できなかったタスクは実行せず、Result.ErrにErrRateLimitedを記録する
+func WithRateLimiter(l Limiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
@@ -0,0 +1,437 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("Table Driven Test - Waitで実行できる時刻", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			limiter func() synctestpkg.Limiter
+			expect  []time.Duration
+		}{
+			{
+				name:    "トークンバケットは貯まった分だけ連続して実行できる",
+				limiter: func() synctestpkg.Limiter { return synctestpkg.NewTokenBucket(time.Second, 2) },
+				expect:  []time.Duration{0, 0, time.Second, 2 * time.Second, 3 * time.Second},
+			},
+			{
+				name:    "リーキーバケットは一定間隔に平準化する",
+				limiter: func() synctestpkg.Limiter { return synctestpkg.NewLeakyBucket(time.Second, 10) },
+				expect:  []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second},
+			},
+			{
+				name:    "スライディングウィンドウログは直近の実行回数を制限する",
+				limiter: func() synctestpkg.Limiter { return synctestpkg.NewSlidingWindowLog(2*time.Second, 3) },
+				expect:  []time.Duration{0, 0, 0, 2 * time.Second, 2 * time.Second},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					l := tc.limiter()
+					start := time.Now()
+
+					var got []time.Duration
+					for range tc.expect {
+						if err := l.Wait(context.Background()); err != nil {
+							t.Fatal(err)
+						}
+						got = append(got, time.Since(start))
+					}
+
+					if !slices.Equal(got, tc.expect) {
+						t.Errorf("実行できた時刻が期待値と異なります: got %v, want %v", got, tc.expect)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 補充されるまでAllowは失敗する", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			limiter func() synctestpkg.Limiter
+			burst   int
+			refill  time.Duration
+		}{
+			{name: "トークンバケット", limiter: func() synctestpkg.Limiter { return synctestpkg.NewTokenBucket(time.Second, 3) }, burst: 3, refill: time.Second},
+			{name: "リーキーバケット", limiter: func() synctestpkg.Limiter { return synctestpkg.NewLeakyBucket(time.Second, 3) }, burst: 1, refill: time.Second},
+			{name: "スライディングウィンドウログ", limiter: func() synctestpkg.Limiter { return synctestpkg.NewSlidingWindowLog(time.Minute, 3) }, burst: 3, refill: time.Minute},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					l := tc.limiter()
+					for i := range tc.burst {
+						if !l.Allow() {
+							t.Fatalf("%d回目の実行が許可されませんでした", i+1)
+						}
+					}
+					if l.Allow() {
+						t.Fatal("上限を超えた実行が許可されました")
+					}
+
+					time.Sleep(tc.refill - time.Nanosecond)
+					if l.Allow() {
+						t.Error("補充される前に実行が許可されました")
+					}
+					time.Sleep(time.Nanosecond)
+					if !l.Allow() {
+						t.Error("補充された後に実行が許可されませんでした")
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("待機中のWaitは補充された瞬間に戻る", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			l := synctestpkg.NewTokenBucket(time.Second, 1)
+			l.Allow()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_ = l.Wait(context.Background())
+			}()
+
+			isDone := func() bool {
+				synctest.Wait()
+				select {
+				case <-done:
+					return true
+				default:
+					return false
+				}
+			}
+
+			time.Sleep(999 * time.Millisecond)
+			if isDone() {
+				t.Fatal("補充される前にWaitが戻りました")
+			}
+			time.Sleep(time.Millisecond)
+			if !isDone() {
+				t.Fatal("補充された後もWaitが戻りません")
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - 取り消した予約の枠は再利用される", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			limiter func() synctestpkg.Limiter
+		}{
+			{name: "トークンバケット", limiter: func() synctestpkg.Limiter { return synctestpkg.NewTokenBucket(time.Second, 1) }},
+			{name: "リーキーバケット", limiter: func() synctestpkg.Limiter { return synctestpkg.NewLeakyBucket(time.Second, 5) }},
+			{name: "スライディングウィンドウログ", limiter: func() synctestpkg.Limiter { return synctestpkg.NewSlidingWindowLog(time.Second, 1) }},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					l := tc.limiter()
+					l.Allow()
+
+					r := l.Reserve()
+					if !r.OK() || r.Delay() != time.Second {
+						t.Fatalf("予約が期待値と異なります: ok %v, delay %v", r.OK(), r.Delay())
+					}
+					r.Cancel()
+					r.Cancel() // 二重の取り消しは無視される
+
+					if d := l.Reserve().Delay(); d != time.Second {
+						t.Errorf("取り消した枠が再利用されていません: got %v, want 1s", d)
+					}
+					if d := l.Reserve().Delay(); d != 2*time.Second {
+						t.Errorf("次の予約の待ち時間が期待値と異なります: got %v, want 2s", d)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 後に予約された枠がある予約を取り消しても同じ時刻を重ねて予約しない", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			limiter func() synctestpkg.Limiter
+		}{
+			{name: "トークンバケット", limiter: func() synctestpkg.Limiter { return synctestpkg.NewTokenBucket(time.Second, 1) }},
+			{name: "バーストのあるトークンバケット", limiter: func() synctestpkg.Limiter { return synctestpkg.NewTokenBucket(time.Second, 3) }},
+			{name: "リーキーバケット", limiter: func() synctestpkg.Limiter { return synctestpkg.NewLeakyBucket(time.Second, 5) }},
+			{name: "スライディングウィンドウログ", limiter: func() synctestpkg.Limiter { return synctestpkg.NewSlidingWindowLog(time.Second, 1) }},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					l := tc.limiter()
+					for l.Allow() {
+					}
+					first, second := l.Reserve(), l.Reserve()
+					if first.Delay() != time.Second || second.Delay() != 2*time.Second {
+						t.Fatalf("予約が期待値と異なります: %v, %v", first.Delay(), second.Delay())
+					}
+
+					first.Cancel()
+
+					// 取り消した枠より後の2秒は予約済みのため、次の予約は3秒後になる
+					third := l.Reserve()
+					if d := third.Delay(); d != 3*time.Second {
+						t.Errorf("次の予約の待ち時間が期待値と異なります: got %v, want 3s", d)
+					}
+					// 最後の予約を取り消した場合は、その枠を再び予約できる
+					third.Cancel()
+					if d := l.Reserve().Delay(); d != 3*time.Second {
+						t.Errorf("最後に取り消した枠が再利用されていません: got %v, want 3s", d)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("キャンセルしたWaitは予約を返却する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			l := synctestpkg.NewTokenBucket(time.Second, 1)
+			l.Allow()
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(500*time.Millisecond, cancel)
+
+			err := l.Wait(ctx)
+
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("エラーが期待値と異なります: got %v", err)
+			}
+			if d := l.Reserve().Delay(); d != 500*time.Millisecond {
+				t.Errorf("返却された枠が再利用されていません: got %v, want 500ms", d)
+			}
+		})
+	})
+
+	t.Run("期限までに実行できない場合は待たずにErrRateLimitedを返す", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			l := synctestpkg.NewTokenBucket(time.Second, 1)
+			l.Allow()
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+
+			err := l.Wait(ctx)
+
+			if !errors.Is(err, synctestpkg.ErrRateLimited) {
+				t.Errorf("エラーが期待値と異なります: got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed != 0 {
+				t.Errorf("待機しました: %v", elapsed)
+			}
+			if d := l.Reserve().Delay(); d != time.Second {
+				t.Errorf("失敗したWaitが枠を消費しています: got %v, want 1s", d)
+			}
+		})
+	})
+
+	t.Run("リーキーバケットが満杯の場合は予約できない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			l := synctestpkg.NewLeakyBucket(time.Second, 2)
+			for range 3 {
+				if !l.Reserve().OK() {
+					t.Fatal("容量の範囲内で予約できませんでした")
+				}
+			}
+
+			r := l.Reserve()
+			err := l.Wait(context.Background())
+
+			if r.OK() {
+				t.Errorf("満杯のバケットで予約できました: delay %v", r.Delay())
+			}
+			if !errors.Is(err, synctestpkg.ErrRateLimited) {
+				t.Errorf("エラーが期待値と異なります: got %v", err)
+			}
+		})
+	})
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	t.Run("キーごとに独立した制限を行う", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// premiumテナントだけ3回まで連続して実行できる
+			l := synctestpkg.NewKeyedLimiter(func(tenant string) synctestpkg.Limiter {
+				if tenant == "premium" {
+					return synctestpkg.NewTokenBucket(time.Second, 3)
+				}
+				return synctestpkg.NewTokenBucket(time.Second, 1)
+			})
+
+			allowed := map[string]int{}
+			for range 5 {
+				for _, tenant := range []string{"free", "premium", "other"} {
+					if l.Allow(tenant) {
+						allowed[tenant]++
+					}
+				}
+			}
+
+			want := map[string]int{"free": 1, "premium": 3, "other": 1}
+			for tenant, n := range want {
+				if allowed[tenant] != n {
+					t.Errorf("%sの許可された回数が期待値と異なります: got %d, want %d", tenant, allowed[tenant], n)
+				}
+			}
+		})
+	})
+
+	t.Run("作成した直後の状態に戻ったキーは破棄する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// キー0だけが1時間に1回の制限で、他のキーは1秒で元の状態に戻る
+			created := map[int]int{}
+			l := synctestpkg.NewKeyedLimiter(func(key int) synctestpkg.Limiter {
+				created[key]++
+				if key == 0 {
+					return synctestpkg.NewTokenBucket(time.Hour, 1)
+				}
+				return synctestpkg.NewTokenBucket(time.Second, 1)
+			})
+			// 破棄はキーが一定数（64）に達してから新しいキーを追加したときに行う
+			for key := range 64 {
+				l.Allow(key)
+			}
+			time.Sleep(time.Second)
+
+			l.Allow(64)
+			l.Allow(1)
+
+			if created[1] != 2 {
+				t.Errorf("元の状態に戻ったキーが破棄されていません: 作成回数 %d", created[1])
+			}
+			if created[0] != 1 || l.Allow(0) {
+				t.Errorf("制限中のキーが破棄されました: 作成回数 %d", created[0])
+			}
+		})
+	})
+
+	t.Run("Forgetしたキーは新しい制限で始まる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			l := synctestpkg.NewKeyedLimiter(func(int) synctestpkg.Limiter {
+				return synctestpkg.NewTokenBucket(time.Hour, 1)
+			})
+			l.Allow(1)
+			if l.Allow(1) {
+				t.Fatal("上限を超えた実行が許可されました")
+			}
+
+			l.Forget(1)
+
+			if !l.Allow(1) {
+				t.Error("Forget後の実行が許可されませんでした")
+			}
+		})
+	})
+}
+
+func TestProcessorsWithRateLimiter(t *testing.T) {
+	t.Run("Table Driven Test - タスクの開始時刻が制限される", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			opts []synctestpkg.Option
+		}{
+			{name: "無制限モード"},
+			{name: "ワーカープールモード", opts: []synctestpkg.Option{synctestpkg.WithWorkerPool(10, 10)}},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					start := time.Now()
+					limiter := synctestpkg.NewTokenBucket(time.Second, 2)
+					opts := append(tc.opts, synctestpkg.WithRateLimiter(limiter))
+
+					var starts []time.Duration
+					for r := range synctestpkg.Run(context.Background(), []int{1, 1, 1, 1, 1}, durationsInSeconds, opts...) {
+						starts = append(starts, r.StartedAt.Sub(start))
+					}
+					slices.Sort(starts)
+
+					want := []time.Duration{0, 0, time.Second, 2 * time.Second, 3 * time.Second}
+					if !slices.Equal(starts, want) {
+						t.Errorf("開始時刻が期待値と異なります: got %v, want %v", starts, want)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("ProcessWithPoolも枠を確保してからタスクを開始する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			start := time.Now()
+			limiter := synctestpkg.NewSlidingWindowLog(time.Second, 2)
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithWorkerPool(4, 4), synctestpkg.WithRateLimiter(limiter))
+			ctx := context.Background()
+
+			var starts []time.Duration
+			for r := range processor.ProcessWithPool(ctx, feedTasks(ctx, taskNames(5))) {
+				starts = append(starts, r.StartedAt.Sub(start))
+			}
+			slices.Sort(starts)
+
+			want := []time.Duration{0, 0, time.Second, time.Second, 2 * time.Second}
+			if !slices.Equal(starts, want) {
+				t.Errorf("開始時刻が期待値と異なります: got %v, want %v", starts, want)
+			}
+		})
+	})
+
+	t.Run("ProcessWithDelayは枠を確保してから遅延する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			start := time.Now()
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithRateLimiter(synctestpkg.NewLeakyBucket(time.Second, 5)))
+			ctx := context.Background()
+
+			finished := make(chan time.Duration, 2)
+			for _, result := range []<-chan synctestpkg.Result[string]{
+				processor.ProcessWithDelay(ctx, 100*time.Millisecond, "1件目"),
+				processor.ProcessWithDelay(ctx, 100*time.Millisecond, "2件目"),
+			} {
+				go func() {
+					<-result
+					finished <- time.Since(start)
+				}()
+			}
+			done := []time.Duration{<-finished, <-finished}
+			slices.Sort(done)
+
+			want := []time.Duration{100 * time.Millisecond, 1100 * time.Millisecond}
+			if !slices.Equal(done, want) {
+				t.Errorf("完了時刻が期待値と異なります: got %v, want %v", done, want)
+			}
+		})
+	})
+
+	t.Run("枠を確保できなかったタスクはErrRateLimitedになる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// 3件目は期限の1.5秒までに枠を確保できない
+			ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+			defer cancel()
+			limiter := synctestpkg.NewLeakyBucket(time.Second, 5)
+			opts := []synctestpkg.Option{synctestpkg.WithRateLimiter(limiter), synctestpkg.WithOrderedResults(10)}
+
+			var errs []error
+			for r := range synctestpkg.Run(ctx, []int{0, 0, 0}, durationsInSeconds, opts...) {
+				errs = append(errs, r.Err)
+			}
+
+			if len(errs) != 3 || errs[0] != nil || errs[1] != nil || !errors.Is(errs[2], synctestpkg.ErrRateLimited) {
+				t.Errorf("エラーが期待値と異なります: got %v", errs)
+			}
+		})
+	})
+}
@@ -0,0 +1,363 @@
+package synctest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTaskCanceled はTaskHandle.Cancelでタスクを中断したことを表す
+	ErrTaskCanceled = errors.New("synctest: task canceled")
+	// ErrTaskNotFinished は終了していないタスクの結果を取得したことを表す
+	ErrTaskNotFinished = errors.New("synctest: task not finished")
+)
+
+// TaskState はレジストリに登録したタスクの状態
+type TaskState int
+
+const (
+	// TaskPending は受け付けて実行を待っている状態
+	TaskPending TaskState = iota
+	// TaskRunning は実行している状態
+	TaskRunning
+	// TaskSucceeded は処理が成功した状態
+	TaskSucceeded
+	// TaskFailed は処理がエラーを返したかパニックした状態
+	TaskFailed
+	// TaskCanceled はCancelまたはコンテキストの終了で中断した状態
+	TaskCanceled
+)
+
+// String はTaskStateの文字列表現を返す
+func (s TaskState) String() string {
+	switch s {
+	case TaskPending:
+		return "pending"
+	case TaskRunning:
+		return "running"
+	case TaskSucceeded:
+		return "succeeded"
+	case TaskFailed:
+		return "failed"
+	case TaskCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// Done はタスクが終了した状態かどうかを返す
+func (s TaskState) Done() bool {
+	return s >= TaskSucceeded
+}
+
+// TaskInfo はある時点のタスクの状態
+type TaskInfo struct {
+	ID          uint64    // 登録した順に1から割り当てられるID
+	Operation   string    // タスクを登録した処理名。ProcessWithGoroutineなど
+	Name        string    // タスク名。Runでは入力をfmt.Sprintで文字列にしたもの
+	State       TaskState // 現在の状態
+	SubmittedAt time.Time // 登録した時刻
+	StartedAt   time.Time // 実行を開始した時刻。開始前と実行せずにキャンセルした場合はゼロ値
+	FinishedAt  time.Time // 終了した時刻。終了前はゼロ値
+	Err         error     // 失敗またはキャンセルした原因
+}
+
+// TaskHandle はレジストリに登録した1つのタスクのハンドル
+type TaskHandle interface {
+	// ID はタスクのIDを返す
+	ID() uint64
+	// Status は現在の状態を返す
+	Status() TaskInfo
+	// Cancel はタスクだけをErrTaskCanceledで中断し、同時に実行している他のタスクには影響しない
+	// 実行待ちのタスクは実行せずに終了し、実行中のタスクはコンテキストをキャンセルする。終了したタスクに対しては何もしない
+	Cancel()
+	// Wait はタスクが終了するまで待ち、タスクのエラーを返す。ctxが先に終了した場合はctx.Err()を返す
+	Wait(ctx context.Context) error
+	// Result は処理が返した値とエラーを返す。終了していない場合はErrTaskNotFinishedを返す
+	Result() (any, error)
+}
+
+// TaskRegistryConfig はタスクレジストリの設定
+type TaskRegistryConfig struct {
+	// Retention は終了したタスクをGetとListで参照できる時間。0以下の場合は5分
+	Retention time.Duration
+	// MaxFinished は保持する終了したタスク数の上限。0以下の場合は1000
+	// 上限を超えた場合は先に終了したタスクから削除する
+	MaxFinished int
+}
+
+// normalized は既定値を補った設定を返す
+func (c TaskRegistryConfig) normalized() TaskRegistryConfig {
+	if c.Retention <= 0 {
+		c.Retention = 5 * time.Minute
+	}
+	if c.MaxFinished <= 0 {
+		c.MaxFinished = 1000
+	}
+	return c
+}
+
+// TaskRegistry はタスクにIDを割り当て、タスクごとに状態の確認とキャンセルをできるようにする
+// WithTaskRegistryを指定したTaskProcessorとRun・RunStreamは、受け付けたタスクを自動的に登録する
+// 実行待ちと実行中のタスクは終了するまで、終了したタスクはRetentionとMaxFinishedの範囲で保持する
+type TaskRegistry struct {
+	clock Clock
+	cfg   TaskRegistryConfig
+
+	mu       sync.Mutex
+	seq      uint64
+	tasks    map[uint64]*registeredTask
+	finished []*registeredTask // 保持している終了したタスク。終了した順
+}
+
+// NewTaskRegistry はタスクレジストリを作成する。WithClockで時刻の記録と保持期間の判定に使うClockを指定できる
+func NewTaskRegistry(cfg TaskRegistryConfig, opts ...Option) *TaskRegistry {
+	return &TaskRegistry{
+		clock: newOptions(opts).clock,
+		cfg:   cfg.normalized(),
+		tasks: make(map[uint64]*registeredTask),
+	}
+}
+
+// WithTaskRegistry はプロセッサが受け付けたタスクをrに登録する
+// 登録するのはProcessWithDelay・ProcessWithGoroutine・ProcessWithPool・ProcessQueue・Run・RunStreamのタスクで、
+// キャンセルしたタスクはErrTaskCanceledで失敗した結果として扱う
+// ProcessQueueでは他の失敗と同様にNackされ、MaxFailuresに達するまで再配信される
+func WithTaskRegistry(r *TaskRegistry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// Submit はfnを新しいゴルーチンで実行するタスクを登録し、ハンドルを返す
+// fnのパニックは回復し、*PanicErrorで失敗したものとして記録する
+func (r *TaskRegistry) Submit(ctx context.Context, name string, fn func(ctx context.Context) (any, error)) TaskHandle {
+	t := r.register(ctx, "Submit", name)
+	go func() {
+		j := job[string]{input: name, err: t.start()}
+		res := execute(t.ctx, r.clock, j, func(ctx context.Context, _ string) (any, error) {
+			return fn(ctx)
+		})
+		t.finish(res.Value, res.Err)
+	}()
+	return t
+}
+
+// Get はIDのタスクのハンドルを返す。保持期間を過ぎたタスクや存在しないタスクの場合はfalseを返す
+func (r *TaskRegistry) Get(id uint64) (TaskHandle, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	t, ok := r.tasks[id]
+	if !ok {
+		return nil, false
+	}
+	return t, true
+}
+
+// List は保持しているタスクの状態をIDの順に返す
+func (r *TaskRegistry) List() []TaskInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	infos := make([]TaskInfo, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		infos = append(infos, t.info)
+	}
+	slices.SortFunc(infos, func(a, b TaskInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return infos
+}
+
+// register は実行待ちのタスクを登録する。タスクのコンテキストはctxから派生し、Cancelで個別にキャンセルできる
+func (r *TaskRegistry) register(ctx context.Context, op, name string) *registeredTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+
+	r.seq++
+	t := &registeredTask{
+		registry: r,
+		done:     make(chan struct{}),
+		info: TaskInfo{
+			ID:          r.seq,
+			Operation:   op,
+			Name:        name,
+			State:       TaskPending,
+			SubmittedAt: r.clock.Now(),
+		},
+	}
+	ctx, t.cancel = context.WithCancelCause(ctx)
+	t.ctx = context.WithValue(ctx, taskKey{}, t)
+	r.tasks[t.info.ID] = t
+	return t
+}
+
+// pruneLocked は保持期間を過ぎたか上限を超えた終了したタスクを削除する
+func (r *TaskRegistry) pruneLocked() {
+	now := r.clock.Now()
+	n := 0
+	for _, t := range r.finished {
+		if len(r.finished)-n <= r.cfg.MaxFinished && now.Sub(t.info.FinishedAt) < r.cfg.Retention {
+			break
+		}
+		delete(r.tasks, t.info.ID)
+		n++
+	}
+	r.finished = slices.Delete(r.finished, 0, n)
+}
+
+// taskKey はタスクのコンテキストにハンドルを保持するキー
+type taskKey struct{}
+
+// TaskFromContext はレジストリに登録したタスクのコンテキストからハンドルを取り出す
+// Runに渡した処理などで、実行中のタスク自身のIDを得るために利用する
+func TaskFromContext(ctx context.Context) (TaskHandle, bool) {
+	t, ok := ctx.Value(taskKey{}).(*registeredTask)
+	return t, ok
+}
+
+// registeredTask はTaskHandleの具象実装
+// レジストリを指定していない場合はnilとして扱い、各メソッドは何もしない
+type registeredTask struct {
+	registry *TaskRegistry
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	done     chan struct{}
+
+	// 以下はregistry.muで保護する
+	info  TaskInfo
+	value any
+}
+
+// registerTask はレジストリを指定した場合にタスクを処理名で登録する。指定していない場合はnilを返す
+func (o options) registerTask(ctx context.Context, name string) *registeredTask {
+	if o.registry == nil {
+		return nil
+	}
+	return o.registry.register(ctx, o.op, name)
+}
+
+// taskName はタスクの入力をタスク名にする
+func taskName[T any](input T) string {
+	if s, ok := any(input).(string); ok {
+		return s
+	}
+	return fmt.Sprint(input)
+}
+
+// ID はタスクのIDを返す
+func (t *registeredTask) ID() uint64 {
+	return t.info.ID
+}
+
+// Status は現在の状態を返す
+func (t *registeredTask) Status() TaskInfo {
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+	return t.info
+}
+
+// Cancel はタスクを中断する
+func (t *registeredTask) Cancel() {
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+	if t.info.State.Done() {
+		return
+	}
+	t.cancel(ErrTaskCanceled)
+	if t.info.State == TaskPending {
+		t.finishLocked(nil, ErrTaskCanceled)
+	}
+}
+
+// Wait はタスクが終了するまで待つ
+func (t *registeredTask) Wait(ctx context.Context) error {
+	select {
+	case <-t.done:
+		return t.Status().Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Result は処理が返した値とエラーを返す
+func (t *registeredTask) Result() (any, error) {
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+	if !t.info.State.Done() {
+		return nil, ErrTaskNotFinished
+	}
+	return t.value, t.info.Err
+}
+
+// context はタスクのコンテキストを返す。レジストリを指定していない場合はctxを返す
+func (t *registeredTask) context(ctx context.Context) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return t.ctx
+}
+
+// start はタスクを実行中にする。実行前にキャンセルされていた場合はキャンセルの原因を返す
+func (t *registeredTask) start() error {
+	if t == nil {
+		return nil
+	}
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+	if t.info.State != TaskPending {
+		return t.info.Err
+	}
+	t.info.State = TaskRunning
+	t.info.StartedAt = t.registry.clock.Now()
+	return nil
+}
+
+// finish はタスクの結果を記録し、記録したエラーを返す
+// タスクのコンテキストが終了したことによる失敗は、コンテキストの終了の原因（Cancelの場合はErrTaskCanceled）に置き換える
+// 既に終了していた場合は記録済みのエラーを返す
+func (t *registeredTask) finish(value any, err error) error {
+	if t == nil {
+		return err
+	}
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+	if t.info.State.Done() {
+		return t.info.Err
+	}
+	if err != nil && t.ctx.Err() != nil {
+		err = context.Cause(t.ctx)
+	}
+	t.finishLocked(value, err)
+	return err
+}
+
+// finishLocked は終了の状態を記録し、保持している終了したタスクに加える
+func (t *registeredTask) finishLocked(value any, err error) {
+	switch {
+	case err == nil:
+		t.info.State = TaskSucceeded
+	case t.ctx.Err() != nil || errors.Is(err, ErrTaskCanceled):
+		t.info.State = TaskCanceled
+	default:
+		t.info.State = TaskFailed
+	}
+	t.value = value
+	t.info.Err = err
+	t.info.FinishedAt = t.registry.clock.Now()
+	close(t.done)
+
+	r := t.registry
+	r.finished = append(r.finished, t)
+	r.pruneLocked()
+	// 終了したタスクのコンテキストの資源を解放する
+	t.cancel(nil)
+}
@@ -0,0 +1,453 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// findTask は名前のタスクのうち最後に登録したもののハンドルを返す
+func findTask(t *testing.T, reg *synctestpkg.TaskRegistry, name string) synctestpkg.TaskHandle {
+	t.Helper()
+	infos := reg.List()
+	for _, info := range slices.Backward(infos) {
+		if info.Name != name {
+			continue
+		}
+		h, ok := reg.Get(info.ID)
+		if !ok {
+			t.Fatalf("タスク%dを取得できませんでした", info.ID)
+		}
+		return h
+	}
+	t.Fatalf("タスク%qが登録されていません: %+v", name, infos)
+	return nil
+}
+
+// taskStates はタスクの名前と状態を"name:state"の形式で返す
+func taskStates(infos []synctestpkg.TaskInfo) []string {
+	var states []string
+	for _, info := range infos {
+		states = append(states, info.Name+":"+info.State.String())
+	}
+	return states
+}
+
+func TestTaskRegistry(t *testing.T) {
+	t.Run("Table Driven Test - 実行中のタスクだけを中断する", func(t *testing.T) {
+		testCases := []struct {
+			name       string
+			run        func(ctx context.Context, reg *synctestpkg.TaskRegistry, tasks []string) []string // 完了したタスクのメッセージを返す
+			expectOp   string
+			expectDone []string
+		}{
+			{
+				name: "ProcessWithDelay",
+				run: func(ctx context.Context, reg *synctestpkg.TaskRegistry, tasks []string) []string {
+					p := synctestpkg.NewTaskProcessor(synctestpkg.WithTaskRegistry(reg))
+					var results []<-chan synctestpkg.Result[string]
+					for _, task := range tasks {
+						results = append(results, p.ProcessWithDelay(ctx, 100*time.Millisecond, task))
+					}
+					var messages []string
+					for _, ch := range results {
+						for r := range ch {
+							if r.Err == nil {
+								messages = append(messages, r.Value)
+							}
+						}
+					}
+					return messages
+				},
+				expectOp:   "ProcessWithDelay",
+				expectDone: []string{"処理完了: a", "処理完了: c"},
+			},
+			{
+				name: "ProcessWithGoroutine",
+				run: func(ctx context.Context, reg *synctestpkg.TaskRegistry, tasks []string) []string {
+					p := synctestpkg.NewTaskProcessor(synctestpkg.WithTaskRegistry(reg))
+					var messages []string
+					for r := range p.ProcessWithGoroutine(ctx, tasks) {
+						if r.Err == nil {
+							messages = append(messages, r.Value)
+						}
+					}
+					return messages
+				},
+				expectOp:   "ProcessWithGoroutine",
+				expectDone: []string{"タスク完了: a", "タスク完了: c"},
+			},
+			{
+				name: "ProcessWithPool",
+				run: func(ctx context.Context, reg *synctestpkg.TaskRegistry, tasks []string) []string {
+					p := synctestpkg.NewTaskProcessor(synctestpkg.WithTaskRegistry(reg), synctestpkg.WithWorkerPool(3, 0))
+					var messages []string
+					for r := range p.ProcessWithPool(ctx, feedTasks(ctx, tasks)) {
+						if r.Err == nil {
+							messages = append(messages, r.Message)
+						}
+					}
+					return messages
+				},
+				expectOp:   "ProcessWithPool",
+				expectDone: []string{"タスク完了: a", "タスク完了: c"},
+			},
+			{
+				name: "Run",
+				run: func(ctx context.Context, reg *synctestpkg.TaskRegistry, tasks []string) []string {
+					fn := func(ctx context.Context, task string) (string, error) {
+						select {
+						case <-time.After(100 * time.Millisecond):
+							return "完了: " + task, nil
+						case <-ctx.Done():
+							return "", ctx.Err()
+						}
+					}
+					var messages []string
+					for r := range synctestpkg.Run(ctx, tasks, fn, synctestpkg.WithTaskRegistry(reg)) {
+						if r.Err == nil {
+							messages = append(messages, r.Value)
+						} else if !errors.Is(r.Err, synctestpkg.ErrTaskCanceled) {
+							messages = append(messages, "予期しないエラー: "+r.Err.Error())
+						}
+					}
+					return messages
+				},
+				expectOp:   "Run",
+				expectDone: []string{"完了: a", "完了: c"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					reg := synctestpkg.NewTaskRegistry(synctestpkg.TaskRegistryConfig{})
+					start := time.Now()
+					done := make(chan []string)
+					go func() { done <- tc.run(context.Background(), reg, []string{"a", "b", "c"}) }()
+
+					time.Sleep(50 * time.Millisecond)
+					target := findTask(t, reg, "b")
+					target.Cancel()
+					if err := target.Wait(context.Background()); !errors.Is(err, synctestpkg.ErrTaskCanceled) {
+						t.Errorf("Waitのエラーが期待値と異なります: %v", err)
+					}
+
+					messages := <-done
+					slices.Sort(messages)
+					if !slices.Equal(messages, tc.expectDone) {
+						t.Errorf("完了したタスクが期待値と異なります: got %v, want %v", messages, tc.expectDone)
+					}
+					if elapsed := time.Since(start); elapsed != 100*time.Millisecond {
+						t.Errorf("他のタスクは中断されずに完了することを期待しました: %v", elapsed)
+					}
+
+					info := target.Status()
+					if info.State != synctestpkg.TaskCanceled || !errors.Is(info.Err, synctestpkg.ErrTaskCanceled) || info.Operation != tc.expectOp {
+						t.Errorf("中断したタスクの状態が期待値と異なります: %+v", info)
+					}
+					if got := info.FinishedAt.Sub(info.StartedAt); got != 50*time.Millisecond {
+						t.Errorf("中断した時刻が期待値と異なります: %v", got)
+					}
+					// ProcessWithDelayは呼び出しごとにゴルーチンで登録するため、登録の順序は定まらない
+					want := []string{"a:succeeded", "b:canceled", "c:succeeded"}
+					if got := slices.Sorted(slices.Values(taskStates(reg.List()))); !slices.Equal(got, want) {
+						t.Errorf("タスクの状態が期待値と異なります: got %v, want %v", got, want)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("実行待ちのタスクは実行せずに中断する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			// 1並列のため、a→b→cの順に100msずつ実行する
+			reg := synctestpkg.NewTaskRegistry(synctestpkg.TaskRegistryConfig{})
+			p := synctestpkg.NewTaskProcessor(synctestpkg.WithTaskRegistry(reg), synctestpkg.WithWorkerPool(1, 2))
+			start := time.Now()
+			reports := p.ProcessWithPool(context.Background(), feedTasks(context.Background(), []string{"a", "b", "c"}))
+
+			time.Sleep(50 * time.Millisecond)
+			want := []string{"a:running", "b:pending", "c:pending"}
+			if got := taskStates(reg.List()); !slices.Equal(got, want) {
+				t.Errorf("タスクの状態が期待値と異なります: got %v, want %v", got, want)
+			}
+			findTask(t, reg, "b").Cancel()
+
+			var tasks []string
+			for r := range reports {
+				if r.Err != nil {
+					// 実行前に中断したタスクは投入された順序とErrTaskCanceledで通知される
+					if r.Index != 1 || !errors.Is(r.Err, synctestpkg.ErrTaskCanceled) {
+						t.Errorf("中断したタスクの結果が期待値と異なります: %+v", r)
+					}
+					continue
+				}
+				tasks = append(tasks, r.Task)
+			}
+
+			if !slices.Equal(tasks, []string{"a", "c"}) {
+				t.Errorf("完了したタスクが期待値と異なります: %v", tasks)
+			}
+			if elapsed := time.Since(start); elapsed != 200*time.Millisecond {
+				t.Errorf("中断したタスクの分だけ早く終わることを期待しました: %v", elapsed)
+			}
+			info := reg.List()[1]
+			if info.State != synctestpkg.TaskCanceled || !info.StartedAt.IsZero() || info.FinishedAt.Sub(start) != 50*time.Millisecond {
+				t.Errorf("実行待ちで中断したタスクの状態が期待値と異なります: %+v", info)
+			}
+		})
+	})
+
+	t.Run("ProcessQueueで中断したタスクは失敗として再配信する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			q := openQueue(t, synctestpkg.QueueConfig{})
+			defer q.Close()
+			enqueue(t, q, "a", "b")
+			reg := synctestpkg.NewTaskRegistry(synctestpkg.TaskRegistryConfig{})
+			p := synctestpkg.NewTaskProcessor(synctestpkg.WithTaskRegistry(reg), synctestpkg.WithWorkerPool(2, 0))
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			reports := p.ProcessQueue(ctx, q)
+
+			time.Sleep(50 * time.Millisecond)
+			findTask(t, reg, "b").Cancel()
+
+			var tasks, failed []string
+			for r := range reports {
+				switch {
+				case r.Terminal():
+				case r.Err != nil:
+					if !errors.Is(r.Err, synctestpkg.ErrTaskCanceled) {
+						t.Errorf("中断したタスクのエラーが期待値と異なります: %v", r.Err)
+					}
+					failed = append(failed, r.Task+"@"+r.FinishedAt.Sub(r.StartedAt).String())
+				default:
+					tasks = append(tasks, r.Task+"@"+r.FinishedAt.Sub(r.StartedAt).String())
+				}
+			}
+
+			// bは50msにNackされて失敗として通知され、再配信されて150msに完了する
+			if !slices.Equal(tasks, []string{"a@100ms", "b@100ms"}) || !slices.Equal(failed, []string{"b@50ms"}) {
+				t.Errorf("完了したタスクが期待値と異なります: %v, 失敗 %v", tasks, failed)
+			}
+			want := []string{"a:succeeded", "b:canceled", "b:succeeded"}
+			if got := slices.Sorted(slices.Values(taskStates(reg.List()))); !slices.Equal(got, want) {
+				t.Errorf("タスクの状態が期待値と異なります: got %v, want %v", got, want)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - Submitで実行したタスクの結果", func(t *testing.T) {
+		errFailed := errors.New("失敗しました")
+
+		testCases := []struct {
+			name        string
+			fn          func(ctx context.Context) (any, error)
+			expectState synctestpkg.TaskState
+			expectValue any
+			expectErr   func(err error) bool
+		}{
+			{
+				name:        "成功",
+				fn:          func(context.Context) (any, error) { time.Sleep(time.Second); return 42, nil },
+				expectState: synctestpkg.TaskSucceeded,
+				expectValue: 42,
+				expectErr:   func(err error) bool { return err == nil },
+			},
+			{
+				name:        "失敗",
+				fn:          func(context.Context) (any, error) { time.Sleep(time.Second); return nil, errFailed },
+				expectState: synctestpkg.TaskFailed,
+				expectErr:   func(err error) bool { return errors.Is(err, errFailed) },
+			},
+			{
+				name:        "パニック",
+				fn:          func(context.Context) (any, error) { time.Sleep(time.Second); panic("壊れました") },
+				expectState: synctestpkg.TaskFailed,
+				expectErr: func(err error) bool {
+					var pe *synctestpkg.PanicError
+					return errors.As(err, &pe) && pe.Value == "壊れました"
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					reg := synctestpkg.NewTaskRegistry(synctestpkg.TaskRegistryConfig{})
+					start := time.Now()
+
+					h := reg.Submit(context.Background(), "job", tc.fn)
+
+					if _, err := h.Result(); !errors.Is(err, synctestpkg.ErrTaskNotFinished) {
+						t.Errorf("終了前の結果はErrTaskNotFinishedを返すことを期待しました: %v", err)
+					}
+					if err := h.Wait(context.Background()); !tc.expectErr(err) {
+						t.Errorf("Waitのエラーが期待値と異なります: %v", err)
+					}
+					value, err := h.Result()
+					if value != tc.expectValue || !tc.expectErr(err) {
+						t.Errorf("結果が期待値と異なります: %v, %v", value, err)
+					}
+					info := h.Status()
+					if info.ID != 1 || info.Operation != "Submit" || info.State != tc.expectState || info.FinishedAt.Sub(start) != time.Second {
+						t.Errorf("タスクの状態が期待値と異なります: %+v", info)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Waitはコンテキストが終了すると待つのをやめる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			reg := synctestpkg.NewTaskRegistry(synctestpkg.TaskRegistryConfig{})
+			h := reg.Submit(context.Background(), "slow", func(ctx context.Context) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if err := h.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Waitのエラーが期待値と異なります: %v", err)
+			}
+			if state := h.Status().State; state != synctestpkg.TaskRunning {
+				t.Errorf("タスクは実行を続けることを期待しました: %v", state)
+			}
+			h.Cancel()
+			h.Wait(context.Background())
+		})
+	})
+
+	t.Run("呼び出し元のコンテキストの終了で中断したタスク", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			reg := synctestpkg.NewTaskRegistry(synctestpkg.TaskRegistryConfig{})
+			p := synctestpkg.NewTaskProcessor(synctestpkg.WithTaskRegistry(reg), synctestpkg.WithWorkerPool(1, 0))
+			ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+			defer cancel()
+
+			for range p.ProcessWithGoroutine(ctx, []string{"a", "b", "c"}) {
+			}
+
+			want := []string{"a:succeeded", "b:canceled", "c:canceled"}
+			infos := reg.List()
+			if got := taskStates(infos); !slices.Equal(got, want) {
+				t.Errorf("タスクの状態が期待値と異なります: got %v, want %v", got, want)
+			}
+			for _, info := range infos[1:] {
+				if !errors.Is(info.Err, context.DeadlineExceeded) {
+					t.Errorf("中断した原因が期待値と異なります: %+v", info)
+				}
+			}
+		})
+	})
+
+	t.Run("TaskFromContextで実行中のタスクを取得する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			reg := synctestpkg.NewTaskRegistry(synctestpkg.TaskRegistryConfig{})
+			fn := func(ctx context.Context, n int) (uint64, error) {
+				h, ok := synctestpkg.TaskFromContext(ctx)
+				if !ok {
+					return 0, errors.New("タスクが見つかりません")
+				}
+				if state := h.Status().State; state != synctestpkg.TaskRunning {
+					return 0, errors.New("実行中ではありません: " + state.String())
+				}
+				return h.ID(), nil
+			}
+
+			var ids []uint64
+			for _, r := range collectResults(synctestpkg.Run(context.Background(), []int{10, 20, 30}, fn, synctestpkg.WithTaskRegistry(reg), synctestpkg.WithWorkerPool(1, 0))) {
+				if r.Err != nil {
+					t.Fatalf("タスクが失敗しました: %v", r.Err)
+				}
+				ids = append(ids, r.Value)
+			}
+
+			if !slices.Equal(ids, []uint64{1, 2, 3}) {
+				t.Errorf("IDが期待値と異なります: %v", ids)
+			}
+			if names := []string{reg.List()[0].Name, reg.List()[2].Name}; !slices.Equal(names, []string{"10", "30"}) {
+				t.Errorf("タスク名が期待値と異なります: %v", names)
+			}
+			if _, ok := synctestpkg.TaskFromContext(context.Background()); ok {
+				t.Error("タスクのコンテキストではない場合はfalseを返すことを期待しました")
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - 終了したタスクの保持", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			cfg    synctestpkg.TaskRegistryConfig
+			after  time.Duration // 最後のタスクが終了してから一覧を取得するまでの時間
+			expect []string
+		}{
+			{
+				name:   "保持期間内",
+				cfg:    synctestpkg.TaskRegistryConfig{Retention: 10 * time.Second},
+				after:  5 * time.Second,
+				expect: []string{"slow:running", "t1:succeeded", "t2:succeeded", "t3:succeeded"},
+			},
+			{
+				// t1は1s、t2は2s、t3は3sに終了する
+				name:   "保持期間を過ぎたタスクから削除する",
+				cfg:    synctestpkg.TaskRegistryConfig{Retention: 10 * time.Second},
+				after:  8 * time.Second,
+				expect: []string{"slow:running", "t2:succeeded", "t3:succeeded"},
+			},
+			{
+				name:   "上限を超えた分は先に終了したタスクから削除する",
+				cfg:    synctestpkg.TaskRegistryConfig{MaxFinished: 2},
+				expect: []string{"slow:running", "t2:succeeded", "t3:succeeded"},
+			},
+			{
+				name:   "既定の保持期間は5分",
+				after:  5*time.Minute - 500*time.Millisecond,
+				expect: []string{"slow:running", "t3:succeeded"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					reg := synctestpkg.NewTaskRegistry(tc.cfg)
+					// 実行中のタスクは保持期間に関係なく保持する
+					slow := reg.Submit(context.Background(), "slow", func(ctx context.Context) (any, error) {
+						<-ctx.Done()
+						return nil, ctx.Err()
+					})
+					defer slow.Cancel()
+					var first synctestpkg.TaskHandle
+					for i, name := range []string{"t1", "t2", "t3"} {
+						h := reg.Submit(context.Background(), name, func(context.Context) (any, error) {
+							time.Sleep(time.Duration(i+1) * time.Second)
+							return nil, nil
+						})
+						if i == 0 {
+							first = h
+						}
+					}
+					time.Sleep(3 * time.Second)
+					synctest.Wait()
+
+					time.Sleep(tc.after)
+					if got := taskStates(reg.List()); !slices.Equal(got, tc.expect) {
+						t.Errorf("保持しているタスクが期待値と異なります: got %v, want %v", got, tc.expect)
+					}
+					// 削除したタスクもハンドルからは参照できる
+					_, found := reg.Get(first.ID())
+					if found != slices.Contains(tc.expect, "t1:succeeded") || first.Status().State != synctestpkg.TaskSucceeded {
+						t.Errorf("削除したタスクの参照が期待値と異なります: found=%v %+v", found, first.Status())
+					}
+				})
+			})
+		}
+	})
+}
@@ -0,0 +1,345 @@
+package synctest
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"runtime/debug"
+)
+
+// 既定のフレームの大きさ
+const (
+	defaultFrameWidth  = 320
+	defaultFrameHeight = 180
+)
+
+// Frame は描画した1フレーム
+type Frame struct {
+	Index int         // フレーム番号（1始まり）。GenerateFramesが送信する番号と同じ
+	Image *image.RGBA // 描画した画像。フレームごとに新しい画像が割り当てられる
+	Err   error       // 描画に失敗した場合のエラー。パニックした場合は*PanicError。中断した場合は原因を設定し、Imageはnil
+
+	state []byte // チェックポイントに保存する、このフレームを描画した直後のレンダラーの状態
+}
+
+// FrameRenderer はフレームの画像を描画するインターフェース
+type FrameRenderer interface {
+	// Render はindex番目（1始まり）のフレームをdstに描画する。totalは総フレーム数
+	Render(ctx context.Context, dst *image.RGBA, index, total int) error
+}
+
+// FrameRendererFunc は関数をFrameRendererとして扱うアダプタ
+type FrameRendererFunc func(ctx context.Context, dst *image.RGBA, index, total int) error
+
+// Render はf(ctx, dst, index, total)を呼び出す
+func (f FrameRendererFunc) Render(ctx context.Context, dst *image.RGBA, index, total int) error {
+	return f(ctx, dst, index, total)
+}
+
+// RenderConfig はフレーム描画の設定
+type RenderConfig struct {
+	Width       int           // フレームの幅。0以下の場合は320
+	Height      int           // フレームの高さ。0以下の場合は180
+	TotalFrames int           // 描画するフレーム数
+	Renderer    FrameRenderer // フレームを描画する処理。nilの場合はWithRendererで指定したレンダラー
+	// JobID はチェックポイントを保存するジョブの識別子。空でなく、WithCheckpointsを指定した場合に
+	// 描画の進捗を保存し、中断した後にResumeで続きから描画できる
+	JobID string
+}
+
+// WithRenderer はRenderConfig.Rendererを指定しなかった場合とResumeで利用するFrameRendererを指定する
+// 指定しない場合はGradientRenderer{Cycles: 1}を利用する
+func WithRenderer(r FrameRenderer) Option {
+	return func(o *options) {
+		o.renderer = r
+	}
+}
+
+// renderConfig はプロセッサのレンダラーと既定値を補った設定を返す
+// WithCheckpointsを指定していない場合はJobIDを無視する
+func (p *videoProcessor) renderConfig(cfg RenderConfig) RenderConfig {
+	if cfg.Renderer == nil {
+		cfg.Renderer = p.renderer
+	}
+	if p.checkpoints == nil {
+		cfg.JobID = ""
+	}
+	return cfg.normalized()
+}
+
+// normalized は既定値を補った設定を返す
+func (c RenderConfig) normalized() RenderConfig {
+	if c.Width <= 0 {
+		c.Width = defaultFrameWidth
+	}
+	if c.Height <= 0 {
+		c.Height = defaultFrameHeight
+	}
+	if c.Renderer == nil {
+		c.Renderer = GradientRenderer{Cycles: 1}
+	}
+	return c
+}
+
+// RenderFrames 設定したFrameRendererで1フレーム目から順に画像を描画して送信する
+// 描画に失敗した場合はエラーを設定したフレームを送信してチャネルを閉じる
+// コンテキストが終了した場合は描画中のフレームを破棄し、送信しなかった最初のフレーム番号とcontext.Cause(ctx)を設定した
+// フレームを最後に送信してチャネルを閉じる。停止処理中のため開始しなかった場合はErrShuttingDownを設定する
+// スーパーバイザーに強制キャンセルされた場合、受信されなかった最後のフレームは破棄する
+// WithWorkerPoolを指定した場合はフレームを並列に描画し、番号順に並べ替えて送信する
+// cfg.JobIDとWithCheckpointsを指定した場合は受信されたフレームまでの進捗を保存する
+func (p *videoProcessor) RenderFrames(ctx context.Context, cfg RenderConfig) <-chan Frame {
+	cfg = p.renderConfig(cfg)
+	result := make(chan Frame, frameBuffer(cfg))
+
+	started := p.spawn(ctx, "RenderFrames", func(ctx context.Context) {
+		defer close(result)
+		next := p.streamFrames(ctx, cfg, result)
+		sendInterrupted(ctx, cfg, next, result)
+	})
+	if !started {
+		return rejectedFrames(1)
+	}
+
+	return result
+}
+
+// rejectedFrames は停止処理中のため開始しなかったことを表すフレームだけを送信して閉じたチャネルを返す
+// チェックポイントを保存する場合の結果のチャネルにはバッファがないため、別のチャネルを作成する
+func rejectedFrames(index int) <-chan Frame {
+	result := make(chan Frame, 1)
+	result <- Frame{Index: index, Err: ErrShuttingDown}
+	close(result)
+	return result
+}
+
+// sendInterrupted はコンテキストが終了したためにnext番目以降のフレームを送信しなかった場合に、その原因を設定したフレームを送信する
+// 強制キャンセルされた場合は、受信されていなければ破棄する
+func sendInterrupted(ctx context.Context, cfg RenderConfig, next int, result chan<- Frame) {
+	if ctx.Err() != nil && next <= cfg.TotalFrames {
+		sendTerminal(ctx, result, Frame{Index: next, Err: context.Cause(ctx)})
+	}
+}
+
+// renderFrames はfirst番目以降のフレームを描画してresultに送信し、送信しなかった最初のフレーム番号を返す
+// 送信を終えてもresultは閉じない
+// 状態を保存するレンダラーはWithWorkerPoolを指定しても順に描画する
+func (p *videoProcessor) renderFrames(ctx context.Context, cfg RenderConfig, first int, result chan<- Frame) int {
+	if p.pool != nil && !savesRendererState(cfg) {
+		return p.renderFramesParallel(ctx, cfg, first, result)
+	}
+
+	for i := first; i <= cfg.TotalFrames; i++ {
+		if ctx.Err() != nil {
+			return i
+		}
+		f := p.renderFrameTraced(ctx, cfg, i)
+		if ctx.Err() != nil {
+			return i
+		}
+		select {
+		case result <- f:
+		case <-ctx.Done():
+			return i
+		}
+		if f.Err != nil {
+			// 失敗したフレームが終了の原因となるため、中断したものとしては扱わない
+			return cfg.TotalFrames + 1
+		}
+		p.metrics.frame(frameKindRender)
+	}
+	return cfg.TotalFrames + 1
+}
+
+// renderFrame はindex番目のフレームを新しい画像に描画し、パニックを回復してエラーに記録する
+func renderFrame(ctx context.Context, cfg RenderConfig, index int) (f Frame) {
+	f.Index = index
+	f.Image = image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+
+	defer func() {
+		if v := recover(); v != nil {
+			f.Err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+
+	if err := cfg.Renderer.Render(ctx, f.Image, index, cfg.TotalFrames); err != nil {
+		f.Err = fmt.Errorf("synctest: render frame %d: %w", index, err)
+		return f
+	}
+	if cfg.JobID != "" {
+		f.state, f.Err = marshalRendererState(cfg.Renderer)
+	}
+	return f
+}
+
+// renderFrameTraced はフレームのスパンの中でrenderFrameを実行する
+// レンダラーはSpanFromContextでフレームのスパンに属性やイベントを追加できる
+func (p *videoProcessor) renderFrameTraced(ctx context.Context, cfg RenderConfig, index int) Frame {
+	ctx, span := p.startSpan(ctx, "frame", Attr("index", index), Attr("kind", frameKindRender))
+	defer span.End()
+	f := renderFrame(ctx, cfg, index)
+	span.RecordError(f.Err)
+	return f
+}
+
+// phase はフレームが動画全体のどの位置にあるかを[0, 1)の範囲で返す
+func phase(index, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(index-1) / float64(total)
+}
+
+// Layers は複数のFrameRendererを順に重ねて描画するFrameRendererを返す
+// 最初のレンダラーが背景となり、後のレンダラーほど手前に描画される
+func Layers(renderers ...FrameRenderer) FrameRenderer {
+	return FrameRendererFunc(func(ctx context.Context, dst *image.RGBA, index, total int) error {
+		for _, r := range renderers {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := r.Render(ctx, dst, index, total); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GradientRenderer は色相が時間とともに回転する斜めのグラデーションを描画する
+type GradientRenderer struct {
+	Cycles float64 // 動画全体で色相が何周するか。0の場合は回転しない
+}
+
+// Render はグラデーションでフレーム全体を塗りつぶす
+func (g GradientRenderer) Render(ctx context.Context, dst *image.RGBA, index, total int) error {
+	b := dst.Bounds()
+	w, h := float64(max(b.Dx(), 1)), float64(max(b.Dy(), 1))
+	offset := phase(index, total) * g.Cycles
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for x := b.Min.X; x < b.Max.X; x++ {
+			pos := (float64(x-b.Min.X)/w + float64(y-b.Min.Y)/h) / 2
+			dst.SetRGBA(x, y, hsv(offset+pos, 0.6, 0.9))
+		}
+	}
+	return nil
+}
+
+// Shape はShapeRendererが描画する図形の種類
+type Shape int
+
+const (
+	// ShapeCircle は円を描画する
+	ShapeCircle Shape = iota
+	// ShapeSquare は回転する正方形を描画する
+	ShapeSquare
+)
+
+// ShapeRenderer はリサージュ曲線に沿って動く図形を描画する
+type ShapeRenderer struct {
+	Shape Shape      // 図形の種類
+	Size  int        // 図形の外接円の半径。0以下の場合はフレームの短辺の1/8
+	Color color.RGBA // 図形の色。ゼロ値の場合は白
+}
+
+// Render は現在のフレームの位置に図形を描画する。図形の外側は描画しない
+func (s ShapeRenderer) Render(_ context.Context, dst *image.RGBA, index, total int) error {
+	b := dst.Bounds()
+	size := s.Size
+	if size <= 0 {
+		size = max(min(b.Dx(), b.Dy())/8, 1)
+	}
+	c := s.Color
+	if c == (color.RGBA{}) {
+		c = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	}
+
+	t := 2 * math.Pi * phase(index, total)
+	cx := float64(b.Min.X) + float64(b.Dx())/2 + (float64(b.Dx())/2-float64(size))*math.Sin(t)
+	cy := float64(b.Min.Y) + float64(b.Dy())/2 + (float64(b.Dy())/2-float64(size))*math.Sin(2*t)
+	r := float64(size)
+	sin, cos := math.Sincos(t)
+
+	area := image.Rect(int(cx-r), int(cy-r), int(cx+r)+1, int(cy+r)+1).Intersect(b)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			var inside bool
+			switch s.Shape {
+			case ShapeSquare:
+				// 図形の回転を打ち消す向きに座標を回してから判定する
+				rx, ry := dx*cos+dy*sin, -dx*sin+dy*cos
+				half := r / math.Sqrt2
+				inside = math.Abs(rx) <= half && math.Abs(ry) <= half
+			default:
+				inside = dx*dx+dy*dy <= r*r
+			}
+			if inside {
+				dst.SetRGBA(x, y, c)
+			}
+		}
+	}
+	return nil
+}
+
+// TextOverlay は組み込みのビットマップフォントで文字列を描画する
+// 対応する文字は数字・英字・空白と「:.-/%?」で、それ以外の文字は「?」として描画する
+type TextOverlay struct {
+	// Text は描画する文字列を返す。nilの場合は「index/total」を描画する
+	Text  func(index, total int) string
+	X, Y  int        // 文字列の左上の座標
+	Scale int        // 文字の拡大率。0以下の場合は1
+	Color color.RGBA // 文字の色。ゼロ値の場合は白
+	// Background は文字の背景の色。ゼロ値の場合は背景を描画しない
+	Background color.RGBA
+}
+
+// Render は文字列をフレームに描画する
+func (o TextOverlay) Render(_ context.Context, dst *image.RGBA, index, total int) error {
+	text := fmt.Sprintf("%d/%d", index, total)
+	if o.Text != nil {
+		text = o.Text(index, total)
+	}
+	c := o.Color
+	if c == (color.RGBA{}) {
+		c = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	}
+	scale := max(o.Scale, 1)
+	origin := dst.Bounds().Min.Add(image.Pt(o.X, o.Y))
+
+	if o.Background != (color.RGBA{}) {
+		w, h := textSize(text, scale)
+		fillRect(dst, image.Rect(origin.X-scale, origin.Y-scale, origin.X+w+scale, origin.Y+h+scale), o.Background)
+	}
+	drawText(dst, origin.X, origin.Y, scale, text, c)
+	return nil
+}
+
+// hsv は色相h（1で1周）・彩度s・明度vをRGBに変換する
+func hsv(h, s, v float64) color.RGBA {
+	h = (h - math.Floor(h)) * 6
+	i := int(h)
+	f := h - float64(i)
+	p, q, t := v*(1-s), v*(1-s*f), v*(1-s*(1-f))
+	var r, g, b float64
+	switch i {
+	case 0:
+		r, g, b = v, t, p
+	case 1:
+		r, g, b = q, v, p
+	case 2:
+		r, g, b = p, v, t
+	case 3:
+		r, g, b = p, q, v
+	case 4:
+		r, g, b = t, p, v
+	default:
+		r, g, b = v, p, q
+	}
+	return color.RGBA{R: uint8(r*255 + 0.5), G: uint8(g*255 + 0.5), B: uint8(b*255 + 0.5), A: 0xFF}
+}
@@ -0,0 +1,233 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"testing/synctest"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// collectFrames はフレームのチャネルが閉じられるまで受信して返す
+func collectFrames(frames <-chan synctestpkg.Frame) []synctestpkg.Frame {
+	var collected []synctestpkg.Frame
+	for f := range frames {
+		collected = append(collected, f)
+	}
+	return collected
+}
+
+// fill は単色で塗りつぶすレンダラー
+func fill(c color.RGBA) synctestpkg.FrameRenderer {
+	return synctestpkg.FrameRendererFunc(func(_ context.Context, dst *image.RGBA, _, _ int) error {
+		for y := dst.Bounds().Min.Y; y < dst.Bounds().Max.Y; y++ {
+			for x := dst.Bounds().Min.X; x < dst.Bounds().Max.X; x++ {
+				dst.SetRGBA(x, y, c)
+			}
+		}
+		return nil
+	})
+}
+
+// countColor は指定した色のピクセル数を数える
+func countColor(img *image.RGBA, c color.RGBA) int {
+	n := 0
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			if img.RGBAAt(x, y) == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestRenderFrames(t *testing.T) {
+	black := color.RGBA{A: 0xFF}
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+
+	t.Run("Table Driven Test - 描画したフレーム", func(t *testing.T) {
+		testCases := []struct {
+			name         string
+			cfg          synctestpkg.RenderConfig
+			expectFrames int
+			expectSize   image.Point
+		}{
+			{
+				name:         "既定の大きさとレンダラー",
+				cfg:          synctestpkg.RenderConfig{TotalFrames: 3},
+				expectFrames: 3,
+				expectSize:   image.Pt(320, 180),
+			},
+			{
+				name: "図形と文字を重ねる",
+				cfg: synctestpkg.RenderConfig{
+					Width: 64, Height: 48, TotalFrames: 4,
+					Renderer: synctestpkg.Layers(
+						synctestpkg.GradientRenderer{Cycles: 2},
+						synctestpkg.ShapeRenderer{Shape: synctestpkg.ShapeSquare},
+						synctestpkg.TextOverlay{X: 2, Y: 2},
+					),
+				},
+				expectFrames: 4,
+				expectSize:   image.Pt(64, 48),
+			},
+			{
+				name:       "フレーム数0",
+				cfg:        synctestpkg.RenderConfig{Width: 8, Height: 8},
+				expectSize: image.Pt(8, 8),
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				processor := synctestpkg.NewVideoProcessor()
+
+				frames := collectFrames(processor.RenderFrames(context.Background(), tc.cfg))
+
+				if len(frames) != tc.expectFrames {
+					t.Fatalf("フレーム数が期待値と異なります: got %d, want %d", len(frames), tc.expectFrames)
+				}
+				for i, f := range frames {
+					if f.Err != nil {
+						t.Errorf("%dフレーム目の描画に失敗しました: %v", i+1, f.Err)
+					}
+					if f.Index != i+1 {
+						t.Errorf("フレーム番号が期待値と異なります: got %d, want %d", f.Index, i+1)
+					}
+					if got := f.Image.Bounds().Size(); got != tc.expectSize {
+						t.Errorf("画像の大きさが期待値と異なります: got %v, want %v", got, tc.expectSize)
+					}
+				}
+				if len(frames) >= 2 && frames[0].Image.RGBAAt(0, 0) == frames[1].Image.RGBAAt(0, 0) {
+					t.Error("フレームごとに異なる画像が描画されることを期待しましたが、同じ色でした")
+				}
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - 図形の描画", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			shape synctestpkg.Shape
+		}{
+			{name: "円", shape: synctestpkg.ShapeCircle},
+			{name: "正方形", shape: synctestpkg.ShapeSquare},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				processor := synctestpkg.NewVideoProcessor()
+				cfg := synctestpkg.RenderConfig{
+					Width: 40, Height: 40, TotalFrames: 2,
+					Renderer: synctestpkg.Layers(fill(black), synctestpkg.ShapeRenderer{Shape: tc.shape, Size: 10, Color: red}),
+				}
+
+				frames := collectFrames(processor.RenderFrames(context.Background(), cfg))
+
+				for _, f := range frames {
+					// 半径10の外接円に収まり、内接する正方形(200)よりは大きい
+					if n := countColor(f.Image, red); n < 150 || n > 330 {
+						t.Errorf("%dフレーム目の図形の面積が想定外です: %d", f.Index, n)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("文字列は組み込みフォントで描画される", func(t *testing.T) {
+		white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+		processor := synctestpkg.NewVideoProcessor()
+		cfg := synctestpkg.RenderConfig{
+			Width: 40, Height: 20, TotalFrames: 1,
+			Renderer: synctestpkg.Layers(fill(black), synctestpkg.TextOverlay{
+				Text: func(int, int) string { return "1" },
+				X:    1, Y: 1,
+			}),
+		}
+
+		frames := collectFrames(processor.RenderFrames(context.Background(), cfg))
+
+		// 「1」のグリフは10ピクセル
+		if n := countColor(frames[0].Image, white); n != 10 {
+			t.Errorf("描画された文字のピクセル数が期待値と異なります: got %d, want 10", n)
+		}
+	})
+
+	t.Run("Table Driven Test - 描画の失敗", func(t *testing.T) {
+		errBroken := errors.New("描画に失敗")
+
+		testCases := []struct {
+			name     string
+			renderer synctestpkg.FrameRendererFunc
+			check    func(error) bool
+		}{
+			{
+				name: "エラーを返す",
+				renderer: func(_ context.Context, _ *image.RGBA, index, _ int) error {
+					if index == 2 {
+						return errBroken
+					}
+					return nil
+				},
+				check: func(err error) bool { return errors.Is(err, errBroken) },
+			},
+			{
+				name: "パニックする",
+				renderer: func(_ context.Context, _ *image.RGBA, index, _ int) error {
+					if index == 2 {
+						panic("壊れたレンダラー")
+					}
+					return nil
+				},
+				check: func(err error) bool {
+					var pe *synctestpkg.PanicError
+					return errors.As(err, &pe)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				processor := synctestpkg.NewVideoProcessor()
+				cfg := synctestpkg.RenderConfig{Width: 4, Height: 4, TotalFrames: 5, Renderer: tc.renderer}
+
+				frames := collectFrames(processor.RenderFrames(context.Background(), cfg))
+
+				if len(frames) != 2 {
+					t.Fatalf("失敗したフレームで送信を打ち切ることを期待しましたが、%dフレーム送信されました", len(frames))
+				}
+				if frames[0].Err != nil || !tc.check(frames[1].Err) {
+					t.Errorf("エラーが期待値と異なります: %v, %v", frames[0].Err, frames[1].Err)
+				}
+			})
+		}
+	})
+
+	t.Run("コンテキストのキャンセルで描画を止める", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			processor := synctestpkg.NewVideoProcessor()
+			cfg := synctestpkg.RenderConfig{Width: 4, Height: 4, TotalFrames: 100}
+
+			frames := processor.RenderFrames(ctx, cfg)
+			<-frames
+			cancel()
+			synctest.Wait()
+
+			rest := collectFrames(frames)
+			// キャンセル前に描画済みでバッファに入っていたフレームと、中断した原因を設定したフレームのみ受信できる
+			if len(rest) == 0 || len(rest) > 2 {
+				t.Fatalf("キャンセル後に描画が続きました: %dフレーム", len(rest))
+			}
+			last := rest[len(rest)-1]
+			if last.Index != len(rest)+1 || last.Image != nil || !errors.Is(last.Err, context.Canceled) {
+				t.Errorf("中断したフレームが期待値と異なります: %d, %v", last.Index, last.Err)
+			}
+		})
+	})
+}
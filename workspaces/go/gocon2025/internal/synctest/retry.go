@@ -0,0 +1,202 @@
+package synctest
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// maxDuration はtime.Durationで表現できる最大値
+const maxDuration = time.Duration(math.MaxInt64)
+
+// RetryState は失敗した試行の状態を表す
+type RetryState struct {
+	Attempt   int           // 失敗した試行の回数（1始まり）
+	Elapsed   time.Duration // 処理開始からの経過時間
+	PrevDelay time.Duration // 直前の待機時間。最初の失敗では0
+}
+
+// RetryPolicy は失敗した試行の後に次の試行まで待機する時間を決定するインターフェース
+type RetryPolicy interface {
+	// NextDelay は次の試行までの待機時間を返す。falseを返した場合はリトライを打ち切る
+	NextDelay(state RetryState) (time.Duration, bool)
+}
+
+// RetryPolicyFunc は関数をRetryPolicyとして扱うためのアダプタ
+type RetryPolicyFunc func(state RetryState) (time.Duration, bool)
+
+// NextDelay は次の試行までの待機時間を返す
+func (f RetryPolicyFunc) NextDelay(state RetryState) (time.Duration, bool) {
+	return f(state)
+}
+
+// ConstantBackoff は常に同じ時間だけ待機するRetryPolicyを作成する
+func ConstantBackoff(delay time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(RetryState) (time.Duration, bool) {
+		return delay, true
+	})
+}
+
+// LinearBackoff は試行ごとに待機時間をstepずつ増やすRetryPolicyを作成する
+func LinearBackoff(initial, step time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		return saturatingAdd(initial, saturatingMul(step, float64(s.Attempt-1))), true
+	})
+}
+
+// ExponentialBackoff は試行ごとに待機時間をmultiplier倍に増やすRetryPolicyを作成する
+func ExponentialBackoff(initial time.Duration, multiplier float64) RetryPolicy {
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		return exponential(initial, multiplier, s.Attempt), true
+	})
+}
+
+// FullJitterBackoff は指数的に増える上限から0までの一様乱数だけ待機するRetryPolicyを作成する
+// rndがnilの場合はパッケージ共有の乱数を利用する
+func FullJitterBackoff(initial time.Duration, multiplier float64, rnd *rand.Rand) RetryPolicy {
+	r := newLockedRand(rnd)
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		ceiling := exponential(initial, multiplier, s.Attempt)
+		return r.durationBetween(0, ceiling), true
+	})
+}
+
+// DecorrelatedJitterBackoff は直前の待機時間の3倍までの乱数だけ待機するRetryPolicyを作成する
+// 待機時間はbase以上capDelay以下に収まる。rndがnilの場合はパッケージ共有の乱数を利用する
+func DecorrelatedJitterBackoff(base, capDelay time.Duration, rnd *rand.Rand) RetryPolicy {
+	r := newLockedRand(rnd)
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		prev := max(s.PrevDelay, base)
+		return min(r.durationBetween(base, saturatingMul(prev, 3)), capDelay), true
+	})
+}
+
+// FibonacciBackoff はunitのフィボナッチ数倍（1, 1, 2, 3, 5, ...）だけ待機するRetryPolicyを作成する
+func FibonacciBackoff(unit time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		a, b := 1.0, 1.0
+		for range s.Attempt - 1 {
+			a, b = b, a+b
+		}
+		return saturatingMul(unit, a), true
+	})
+}
+
+// WithMaxDelay は個々の待機時間をmaxDelayで頭打ちにする
+func WithMaxDelay(policy RetryPolicy, maxDelay time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		d, ok := policy.NextDelay(s)
+		return min(d, maxDelay), ok
+	})
+}
+
+// WithMaxElapsed は待機後の経過時間がmaxElapsedを超える場合にリトライを打ち切る
+func WithMaxElapsed(policy RetryPolicy, maxElapsed time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		d, ok := policy.NextDelay(s)
+		if !ok || saturatingAdd(s.Elapsed, d) > maxElapsed {
+			return 0, false
+		}
+		return d, true
+	})
+}
+
+// WithMaxRetries は失敗がmaxRetries+1回に達した時点でリトライを打ち切る
+func WithMaxRetries(policy RetryPolicy, maxRetries int) RetryPolicy {
+	return RetryPolicyFunc(func(s RetryState) (time.Duration, bool) {
+		if s.Attempt > maxRetries {
+			return 0, false
+		}
+		return policy.NextDelay(s)
+	})
+}
+
+// PreviewDelays はポリシーが返す待機時間の列を最大n個まで返す
+// 試行自体には時間がかからないものとして経過時間を計算する
+func PreviewDelays(policy RetryPolicy, n int) []time.Duration {
+	delays := make([]time.Duration, 0, n)
+	var state RetryState
+	for attempt := 1; attempt <= n; attempt++ {
+		state.Attempt = attempt
+		d, ok := policy.NextDelay(state)
+		if !ok {
+			break
+		}
+		delays = append(delays, d)
+		state.Elapsed = saturatingAdd(state.Elapsed, d)
+		state.PrevDelay = d
+	}
+	return delays
+}
+
+// defaultRetryPolicy はProcessWithRetryにnilのポリシーを渡した場合に使うポリシー
+// 100ミリ秒から2倍ずつ待機時間を増やし、3回までリトライする
+var defaultRetryPolicy = WithMaxRetries(ExponentialBackoff(100*time.Millisecond, 2), 3)
+
+// ProcessWithRetry 処理を即座に実行し、エラーを返した場合はポリシーに従って再実行する
+// policyがnilの場合は、待機せずに再実行し続けないようdefaultRetryPolicyに従う
+func (p *taskProcessor) ProcessWithRetry(ctx context.Context, policy RetryPolicy, op func(ctx context.Context) error) <-chan PollResult {
+	if policy == nil {
+		policy = defaultRetryPolicy
+	}
+	probe := func(ctx context.Context) (bool, error) {
+		err := op(ctx)
+		return err == nil, err
+	}
+	return p.Poll(ctx, PollConfig{Immediate: true, Backoff: policy}, probe)
+}
+
+// exponential はinitial * multiplier^(attempt-1)を計算する
+func exponential(initial time.Duration, multiplier float64, attempt int) time.Duration {
+	return saturatingMul(initial, math.Pow(multiplier, float64(attempt-1)))
+}
+
+// saturatingMul はオーバーフローする場合にmaxDurationを返す乗算
+func saturatingMul(d time.Duration, factor float64) time.Duration {
+	v := float64(d) * factor
+	if v >= float64(maxDuration) {
+		return maxDuration
+	}
+	if v <= 0 {
+		return 0
+	}
+	return time.Duration(v)
+}
+
+// saturatingAdd はオーバーフローする場合にmaxDurationを返す加算
+func saturatingAdd(a, b time.Duration) time.Duration {
+	if b > 0 && a > maxDuration-b {
+		return maxDuration
+	}
+	return a + b
+}
+
+// lockedRand は複数のゴルーチンから利用できる乱数源
+type lockedRand struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// newLockedRand はrndを排他制御付きでラップする
+func newLockedRand(rnd *rand.Rand) *lockedRand {
+	return &lockedRand{rnd: rnd}
+}
+
+// durationBetween はlo以上hi以下の一様乱数を返す
+func (r *lockedRand) durationBetween(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	span := int64(hi - lo)
+	if span == math.MaxInt64 {
+		span--
+	}
+	if r.rnd == nil {
+		return lo + time.Duration(rand.Int64N(span+1))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return lo + time.Duration(r.rnd.Int64N(span+1))
+}
@@ -0,0 +1,272 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// RetryPolicyTest は RetryPolicy のテストに必要なデータと設定を管理する
+type RetryPolicyTest struct {
+	processor synctestpkg.TaskProcessor
+}
+
+func TestRetryPolicy(t *testing.T) {
+	setup := func(t *testing.T) *RetryPolicyTest {
+		t.Helper()
+		return &RetryPolicyTest{
+			processor: synctestpkg.NewTaskProcessor(),
+		}
+	}
+
+	test := setup(t)
+	ms := time.Millisecond
+
+	t.Run("Table Driven Test - 決定的なポリシーの待機時間列", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			policy synctestpkg.RetryPolicy
+			n      int
+			expect []time.Duration
+		}{
+			{
+				name:   "一定",
+				policy: synctestpkg.ConstantBackoff(100 * ms),
+				n:      4,
+				expect: []time.Duration{100 * ms, 100 * ms, 100 * ms, 100 * ms},
+			},
+			{
+				name:   "線形",
+				policy: synctestpkg.LinearBackoff(100*ms, 50*ms),
+				n:      4,
+				expect: []time.Duration{100 * ms, 150 * ms, 200 * ms, 250 * ms},
+			},
+			{
+				name:   "指数",
+				policy: synctestpkg.ExponentialBackoff(100*ms, 2),
+				n:      5,
+				expect: []time.Duration{100 * ms, 200 * ms, 400 * ms, 800 * ms, 1600 * ms},
+			},
+			{
+				name:   "フィボナッチ",
+				policy: synctestpkg.FibonacciBackoff(10 * ms),
+				n:      7,
+				expect: []time.Duration{10 * ms, 10 * ms, 20 * ms, 30 * ms, 50 * ms, 80 * ms, 130 * ms},
+			},
+			{
+				name:   "個々の待機時間の上限",
+				policy: synctestpkg.WithMaxDelay(synctestpkg.ExponentialBackoff(100*ms, 2), 500*ms),
+				n:      5,
+				expect: []time.Duration{100 * ms, 200 * ms, 400 * ms, 500 * ms, 500 * ms},
+			},
+			{
+				name:   "合計経過時間の上限",
+				policy: synctestpkg.WithMaxElapsed(synctestpkg.ExponentialBackoff(100*ms, 2), time.Second),
+				n:      10,
+				expect: []time.Duration{100 * ms, 200 * ms, 400 * ms},
+			},
+			{
+				name:   "リトライ回数の上限",
+				policy: synctestpkg.WithMaxRetries(synctestpkg.ConstantBackoff(ms), 2),
+				n:      10,
+				expect: []time.Duration{ms, ms},
+			},
+			{
+				name:   "巨大な指数でもオーバーフローしない",
+				policy: synctestpkg.WithMaxDelay(synctestpkg.ExponentialBackoff(time.Hour, 10), 24*time.Hour),
+				n:      40,
+				expect: append([]time.Duration{time.Hour, 10 * time.Hour}, slices.Repeat([]time.Duration{24 * time.Hour}, 38)...),
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				got := synctestpkg.PreviewDelays(tc.policy, tc.n)
+				if !slices.Equal(got, tc.expect) {
+					t.Errorf("待機時間列が期待値と異なります:\n got  %v\n want %v", got, tc.expect)
+				}
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - ジッター付きポリシーの範囲", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			policy func(seed uint64) synctestpkg.RetryPolicy
+			bounds func(i int, prev time.Duration) (lo, hi time.Duration)
+		}{
+			{
+				name: "フルジッター",
+				policy: func(seed uint64) synctestpkg.RetryPolicy {
+					return synctestpkg.FullJitterBackoff(100*ms, 2, rand.New(rand.NewPCG(seed, 0)))
+				},
+				bounds: func(i int, _ time.Duration) (time.Duration, time.Duration) {
+					return 0, 100 * ms << i
+				},
+			},
+			{
+				name: "非相関ジッター",
+				policy: func(seed uint64) synctestpkg.RetryPolicy {
+					return synctestpkg.DecorrelatedJitterBackoff(100*ms, 2*time.Second, rand.New(rand.NewPCG(seed, 0)))
+				},
+				bounds: func(_ int, prev time.Duration) (time.Duration, time.Duration) {
+					return 100 * ms, min(3*max(prev, 100*ms), 2*time.Second)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				for seed := range uint64(20) {
+					delays := synctestpkg.PreviewDelays(tc.policy(seed), 8)
+					if len(delays) != 8 {
+						t.Fatalf("待機時間の数が期待値と異なります: got %d, want 8", len(delays))
+					}
+					var prev time.Duration
+					for i, d := range delays {
+						lo, hi := tc.bounds(i, prev)
+						if d < lo || d > hi {
+							t.Errorf("seed=%d: %d回目の待機時間が範囲外です: got %v, want [%v, %v]", seed, i+1, d, lo, hi)
+						}
+						prev = d
+					}
+
+					// 同じシードなら同じ列になる
+					if again := synctestpkg.PreviewDelays(tc.policy(seed), 8); !slices.Equal(delays, again) {
+						t.Errorf("seed=%d: 同じシードで異なる待機時間列になりました", seed)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("ポーリングでのバックオフ", func(t *testing.T) {
+		t.Run("Table Driven Test - ポリシーに従って待機する", func(t *testing.T) {
+			testCases := []struct {
+				name           string
+				cfg            synctestpkg.PollConfig
+				succeedOn      int
+				expectReason   synctestpkg.StopReason
+				expectAttempts int
+				expectElapsed  time.Duration
+			}{
+				{
+					name: "指数バックオフで4回目に成功",
+					cfg: synctestpkg.PollConfig{
+						Interval: 100 * ms,
+						Backoff:  synctestpkg.ExponentialBackoff(100*ms, 2),
+					},
+					succeedOn:      4,
+					expectReason:   synctestpkg.StopReasonSucceeded,
+					expectAttempts: 4,
+					expectElapsed:  (100 + 100 + 200 + 400) * ms,
+				},
+				{
+					name: "合計経過時間の上限で打ち切り",
+					cfg: synctestpkg.PollConfig{
+						Immediate: true,
+						Backoff:   synctestpkg.WithMaxElapsed(synctestpkg.FibonacciBackoff(time.Second), 5*time.Second),
+					},
+					succeedOn:      100,
+					expectReason:   synctestpkg.StopReasonRetriesExhausted,
+					expectAttempts: 4,
+					expectElapsed:  (1 + 1 + 2) * time.Second,
+				},
+				{
+					name: "試行回数の上限がポリシーより優先される",
+					cfg: synctestpkg.PollConfig{
+						Immediate:   true,
+						MaxAttempts: 2,
+						Backoff:     synctestpkg.ConstantBackoff(time.Second),
+					},
+					succeedOn:      100,
+					expectReason:   synctestpkg.StopReasonRetriesExhausted,
+					expectAttempts: 2,
+					expectElapsed:  time.Second,
+				},
+			}
+
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					synctest.Test(t, func(t *testing.T) {
+						res := <-test.processor.Poll(context.Background(), tc.cfg, succeedOn(tc.succeedOn, nil))
+
+						if res.Reason != tc.expectReason {
+							t.Errorf("終了理由が期待値と異なります: got %v, want %v", res.Reason, tc.expectReason)
+						}
+						if res.Attempts != tc.expectAttempts {
+							t.Errorf("試行回数が期待値と異なります: got %d, want %d", res.Attempts, tc.expectAttempts)
+						}
+						if res.Elapsed != tc.expectElapsed {
+							t.Errorf("経過時間が期待値と異なります: got %v, want %v", res.Elapsed, tc.expectElapsed)
+						}
+					})
+				})
+			}
+		})
+	})
+
+	t.Run("リトライ付きの遅延タスク", func(t *testing.T) {
+		errFlaky := errors.New("一時的な障害")
+
+		t.Run("失敗が続いた後に成功する", func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				calls := 0
+				op := func(context.Context) error {
+					calls++
+					if calls < 3 {
+						return errFlaky
+					}
+					return nil
+				}
+
+				res := <-test.processor.ProcessWithRetry(context.Background(), synctestpkg.LinearBackoff(time.Second, time.Second), op)
+
+				if !res.Succeeded || res.Attempts != 3 {
+					t.Errorf("結果が期待値と異なります: %+v", res)
+				}
+				if res.Elapsed != 3*time.Second {
+					t.Errorf("経過時間が期待値と異なります: got %v, want 3s", res.Elapsed)
+				}
+			})
+		})
+
+		t.Run("ポリシーがnilの場合は有限回で打ち切る", func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				op := func(context.Context) error { return errFlaky }
+
+				res := <-test.processor.ProcessWithRetry(context.Background(), nil, op)
+
+				if res.Reason != synctestpkg.StopReasonRetriesExhausted || res.Attempts != 4 {
+					t.Errorf("結果が期待値と異なります: %+v", res)
+				}
+				// 100ms + 200ms + 400ms
+				if res.Elapsed != 700*time.Millisecond {
+					t.Errorf("経過時間が期待値と異なります: got %v, want 700ms", res.Elapsed)
+				}
+			})
+		})
+
+		t.Run("リトライ上限で最後のエラーを返す", func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				op := func(context.Context) error { return errFlaky }
+				policy := synctestpkg.WithMaxRetries(synctestpkg.ConstantBackoff(time.Second), 2)
+
+				res := <-test.processor.ProcessWithRetry(context.Background(), policy, op)
+
+				if res.Reason != synctestpkg.StopReasonRetriesExhausted || res.Attempts != 3 {
+					t.Errorf("結果が期待値と異なります: %+v", res)
+				}
+				if !errors.Is(res.LastErr, errFlaky) {
+					t.Errorf("最後のエラーが期待値と異なります: got %v", res.LastErr)
+				}
+			})
+		})
+	})
+}
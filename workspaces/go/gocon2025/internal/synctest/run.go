@@ -0,0 +1,251 @@
+package synctest
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Result は並行実行した処理1件の結果
+type Result[R any] struct {
+	Index     int           // 入力の順序（0始まり）
+	Value     R             // 処理が返した値
+	Err       error         // 処理が返したエラー。パニックした場合は*PanicError
+	StartedAt time.Time     // 処理を開始した時刻
+	Duration  time.Duration // 処理にかかった時間
+	Panic     any           // 処理中に回復したパニックの値。パニックしなかった場合はnil
+}
+
+// PanicError は処理中に発生したパニックを表すエラー
+type PanicError struct {
+	Value any    // recoverで得られた値
+	Stack []byte // パニック発生時のスタックトレース
+}
+
+// Error はパニックの値を含むエラーメッセージを返す
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("synctest: task panicked: %v", e.Value)
+}
+
+// Unwrap はパニックの値がエラーだった場合にそのエラーを返す
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// TaskError はタスクが失敗した原因に、処理名とタスク名を付けたエラー
+// 原因はerrors.Isとerrors.Asで取り出せる。コンテキストの終了で中断した場合はcontext.Canceledまたはcontext.DeadlineExceeded、
+// Cancelで中断した場合はErrTaskCanceled、停止処理中のため実行しなかった場合はErrShuttingDownを原因とする
+type TaskError struct {
+	Op   string // 処理名。ProcessWithDelayなど
+	Task string // タスク名
+	Err  error  // 失敗した原因
+}
+
+// Error は処理名とタスク名を含むエラーメッセージを返す
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("synctest: %s %q: %v", e.Op, e.Task, e.Err)
+}
+
+// Unwrap は失敗した原因を返す
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// taskError はerrがnilでない場合に処理名とタスク名を付けたTaskErrorを返す
+func taskError(op, task string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TaskError{Op: op, Task: task, Err: err}
+}
+
+// Run は入力ごとに処理を並行実行し、完了した順に結果を送信する
+// WithWorkerPoolを指定した場合は同時実行数が制限され、WithClockの時刻で処理時間を計測する
+// ProcessWithGoroutineと同様に、コンテキストのキャンセル後は未開始の入力を実行せず、
+// キャンセル後に完了した処理の結果も送信しない。すべての処理が終了するとチャネルを閉じる
+func Run[T, R any](ctx context.Context, inputs []T, fn func(context.Context, T) (R, error), opts ...Option) <-chan Result[R] {
+	return runSlice(ctx, newOptions(opts).operation("Run"), inputs, fn)
+}
+
+// RunStream はチャネルから受け取った入力ごとに処理を並行実行する
+// 入力のチャネルが閉じられ、すべての処理が終了すると結果のチャネルを閉じる
+func RunStream[T, R any](ctx context.Context, inputs <-chan T, fn func(context.Context, T) (R, error), opts ...Option) <-chan Result[R] {
+	o := newOptions(opts).operation("RunStream")
+	return runStream(ctx, o, inputs, fn, o.pool.normalized().concurrency)
+}
+
+// runSlice はスライスの入力をチャネル経由でrunStreamに渡す
+func runSlice[T, R any](ctx context.Context, o options, inputs []T, fn func(context.Context, T) (R, error)) <-chan Result[R] {
+	source := make(chan T)
+
+	go func() {
+		defer close(source)
+		for _, input := range inputs {
+			select {
+			case source <- input:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return runStream(ctx, o, source, fn, len(inputs))
+}
+
+// runStream は入力に順序を付与して実行し、結果をbuffer個まで保持するチャネルに送信する
+// o.poolがnilの場合は入力ごとにゴルーチンを起動する
+// o.orderが指定された場合は結果を入力の順序に並べ替えてから送信する
+// o.metricsが指定された場合は入力をo.opのタスクとして集計する
+func runStream[T, R any](ctx context.Context, o options, inputs <-chan T, fn func(context.Context, T) (R, error), buffer int) <-chan Result[R] {
+	result := make(chan Result[R], buffer)
+	out := result
+	var slots chan struct{}
+	if o.order != nil {
+		slots = make(chan struct{}, o.order.window(o.pool))
+		out = make(chan Result[R], buffer)
+		go reorder(ctx, out, result, slots)
+	}
+
+	tm := o.taskMetrics()
+	var wg sync.WaitGroup
+	worker := func(j job[T]) {
+		// キャンセル後は未開始の入力を実行しない
+		if ctx.Err() != nil {
+			tm.skip()
+			j.task.finish(nil, ctx.Err())
+			return
+		}
+		// Cancelで実行前にキャンセルされたタスクは実行せずに失敗とする
+		if err := j.task.start(); err != nil {
+			j.err = err
+		}
+		tctx, span := j.task.context(ctx), Span(noopSpan{})
+		if o.op != "" {
+			tctx, span = o.startSpan(tctx, "task", taskAttributes(j.index, j.input)...)
+		}
+		r := execute(tctx, o.clock, j, fn)
+		r.Err = j.task.finish(r.Value, r.Err)
+		span.RecordError(r.Err)
+		span.End()
+		tm.finish(r.Err, r.Duration)
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case out <- r:
+		case <-ctx.Done():
+		}
+	}
+
+	if o.pool == nil {
+		go func() {
+			for j := range dispatch(ctx, o, inputs, 0, slots, tm) {
+				wg.Go(func() { worker(j) })
+			}
+			wg.Wait()
+			close(out)
+		}()
+		return result
+	}
+
+	cfg := o.pool.normalized()
+	queue := dispatch(ctx, o, inputs, cfg.queueDepth, slots, tm)
+	for range cfg.concurrency {
+		wg.Go(func() {
+			for j := range queue {
+				worker(j)
+			}
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return result
+}
+
+// job は入力の順序を付与した実行単位
+type job[T any] struct {
+	index int
+	input T
+	err   error           // 流量制限で実行枠を確保できなかった場合のエラー
+	task  *registeredTask // レジストリに登録したタスク。レジストリを指定していない場合はnil
+}
+
+// dispatch は入力に順序を付与してqueueDepthの長さのキューに積む
+// キューが満杯の間は入力の受信を止めるため、送信側にバックプレッシャーがかかる
+// slotsがnilでない場合は、キューに積む前にslotsへの送信で先行できる入力数を制限する
+// o.limiterがnilでない場合は、入力を受け付ける順に実行枠を確保してからキューに積む
+// 受け付けた入力はtmに記録してo.registryに登録し、キューに積む前にキャンセルされた場合は打ち切ったものとして記録する
+func dispatch[T any](ctx context.Context, o options, inputs <-chan T, queueDepth int, slots chan<- struct{}, tm *taskMetrics) <-chan job[T] {
+	queue := make(chan job[T], queueDepth)
+
+	go func() {
+		defer close(queue)
+
+		for index := 0; ; index++ {
+			select {
+			case input, ok := <-inputs:
+				if !ok {
+					return
+				}
+				j := job[T]{index: index, input: input, task: o.registerTask(ctx, taskName(input))}
+				tm.submit()
+				skip := func() {
+					tm.skip()
+					j.task.finish(nil, ctx.Err())
+				}
+				if o.limiter != nil {
+					if j.err = o.limiter.Wait(ctx); ctx.Err() != nil {
+						skip()
+						return
+					}
+				}
+				if slots != nil {
+					select {
+					case slots <- struct{}{}:
+					case <-ctx.Done():
+						skip()
+						return
+					}
+				}
+				select {
+				case queue <- j:
+				case <-ctx.Done():
+					skip()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return queue
+}
+
+// execute は処理を1件実行し、パニックを回復して結果に記録する
+func execute[T, R any](ctx context.Context, clock Clock, j job[T], fn func(context.Context, T) (R, error)) (res Result[R]) {
+	res.Index = j.index
+	res.StartedAt = clock.Now()
+	if j.err != nil {
+		res.Err = j.err
+		return res
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			res.Panic = v
+			res.Err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+		res.Duration = clock.Now().Sub(res.StartedAt)
+	}()
+
+	res.Value, res.Err = fn(ctx, j.input)
+	return res
+}
@@ -0,0 +1,306 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// collectResults は結果のチャネルが閉じられるまで受信し、入力の順序で並べて返す
+func collectResults[R any](results <-chan synctestpkg.Result[R]) []synctestpkg.Result[R] {
+	var collected []synctestpkg.Result[R]
+	for r := range results {
+		collected = append(collected, r)
+	}
+	slices.SortFunc(collected, func(a, b synctestpkg.Result[R]) int { return a.Index - b.Index })
+	return collected
+}
+
+// sleepThen は入力の秒数だけ待機してから値を返す処理
+func sleepThen(ctx context.Context, seconds int) (string, error) {
+	select {
+	case <-time.After(time.Duration(seconds) * time.Second):
+		return strconv.Itoa(seconds) + "秒", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestRun(t *testing.T) {
+	errInvalid := errors.New("不正な入力")
+
+	t.Run("Table Driven Test - 処理結果の内容", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			inputs        []int
+			fn            func(context.Context, int) (string, error)
+			expectValues  []string
+			expectErrs    []error
+			expectElapsed time.Duration
+		}{
+			{
+				name:          "入力なし",
+				inputs:        nil,
+				fn:            sleepThen,
+				expectElapsed: 0,
+			},
+			{
+				name:          "すべて並行に実行される",
+				inputs:        []int{3, 1, 2},
+				fn:            sleepThen,
+				expectValues:  []string{"3秒", "1秒", "2秒"},
+				expectErrs:    []error{nil, nil, nil},
+				expectElapsed: 3 * time.Second,
+			},
+			{
+				name:   "エラーは入力ごとに返される",
+				inputs: []int{1, -1, 2},
+				fn: func(ctx context.Context, n int) (string, error) {
+					if n < 0 {
+						return "", fmt.Errorf("入力 %d: %w", n, errInvalid)
+					}
+					return sleepThen(ctx, n)
+				},
+				expectValues:  []string{"1秒", "", "2秒"},
+				expectErrs:    []error{nil, errInvalid, nil},
+				expectElapsed: 2 * time.Second,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					start := time.Now()
+
+					results := collectResults(synctestpkg.Run(context.Background(), tc.inputs, tc.fn))
+
+					if len(results) != len(tc.inputs) {
+						t.Fatalf("結果の数が期待値と異なります: got %d, want %d", len(results), len(tc.inputs))
+					}
+					for i, r := range results {
+						if r.Index != i {
+							t.Errorf("インデックスが期待値と異なります: got %d, want %d", r.Index, i)
+						}
+						if r.Value != tc.expectValues[i] {
+							t.Errorf("入力%dの値が期待値と異なります: got %q, want %q", i, r.Value, tc.expectValues[i])
+						}
+						if !errors.Is(r.Err, tc.expectErrs[i]) || (tc.expectErrs[i] == nil && r.Err != nil) {
+							t.Errorf("入力%dのエラーが期待値と異なります: got %v, want %v", i, r.Err, tc.expectErrs[i])
+						}
+						if r.Panic != nil {
+							t.Errorf("入力%dでパニックが記録されています: %v", i, r.Panic)
+						}
+						if want := time.Duration(max(tc.inputs[i], 0)) * time.Second; r.Duration != want {
+							t.Errorf("入力%dの処理時間が期待値と異なります: got %v, want %v", i, r.Duration, want)
+						}
+					}
+					if elapsed := time.Since(start); elapsed != tc.expectElapsed {
+						t.Errorf("経過時間が期待値と異なります: got %v, want %v", elapsed, tc.expectElapsed)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("パニックは回復されて結果に記録される", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			fn := func(ctx context.Context, n int) (int, error) {
+				if n == 2 {
+					panic("2は扱えません")
+				}
+				if n == 3 {
+					panic(errInvalid)
+				}
+				return n * 10, nil
+			}
+
+			results := collectResults(synctestpkg.Run(context.Background(), []int{1, 2, 3}, fn))
+
+			if results[0].Value != 10 || results[0].Err != nil {
+				t.Errorf("パニックしない入力の結果が不正です: %+v", results[0])
+			}
+
+			var panicErr *synctestpkg.PanicError
+			if !errors.As(results[1].Err, &panicErr) {
+				t.Fatalf("パニックがPanicErrorとして記録されていません: %v", results[1].Err)
+			}
+			if panicErr.Value != "2は扱えません" || results[1].Panic != "2は扱えません" {
+				t.Errorf("パニックの値が期待値と異なります: %v", panicErr.Value)
+			}
+			if len(panicErr.Stack) == 0 {
+				t.Error("スタックトレースが記録されていません")
+			}
+
+			// パニックの値がエラーならerrors.Isで辿れる
+			if !errors.Is(results[2].Err, errInvalid) {
+				t.Errorf("パニックしたエラーを辿れません: %v", results[2].Err)
+			}
+		})
+	})
+
+	t.Run("ワーカープールの同時実行数に従う", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var running, peak atomic.Int32
+			fn := func(ctx context.Context, n int) (int, error) {
+				cur := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := peak.Load()
+					if cur <= old || peak.CompareAndSwap(old, cur) {
+						break
+					}
+				}
+				time.Sleep(time.Second)
+				return n, nil
+			}
+			start := time.Now()
+
+			results := collectResults(synctestpkg.Run(context.Background(), make([]int, 10), fn, synctestpkg.WithWorkerPool(4, 0)))
+
+			if len(results) != 10 {
+				t.Fatalf("結果の数が期待値と異なります: got %d, want 10", len(results))
+			}
+			if got := peak.Load(); got != 4 {
+				t.Errorf("同時実行数の最大値が期待値と異なります: got %d, want 4", got)
+			}
+			if elapsed := time.Since(start); elapsed != 3*time.Second {
+				t.Errorf("経過時間が期待値と異なります: got %v, want 3s", elapsed)
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - キャンセル時の挙動", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			opts          []synctestpkg.Option
+			cancelAfter   time.Duration
+			expectIndices []int
+			expectStarted int32
+		}{
+			{
+				name:          "無制限モードでは完了済みの結果だけが届く",
+				cancelAfter:   2500 * time.Millisecond,
+				expectIndices: []int{0, 1},
+				expectStarted: 5,
+			},
+			{
+				name:          "ワーカープールでは未開始の入力を実行しない",
+				opts:          []synctestpkg.Option{synctestpkg.WithWorkerPool(1, 10)},
+				cancelAfter:   2500 * time.Millisecond,
+				expectIndices: []int{0},
+				expectStarted: 2,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					time.AfterFunc(tc.cancelAfter, cancel)
+
+					var started atomic.Int32
+					var canceled atomic.Int32
+					fn := func(ctx context.Context, n int) (int, error) {
+						started.Add(1)
+						_, err := sleepThen(ctx, n)
+						if errors.Is(err, context.Canceled) {
+							canceled.Add(1)
+						}
+						return n, err
+					}
+
+					results := collectResults(synctestpkg.Run(ctx, []int{1, 2, 3, 4, 5}, fn, tc.opts...))
+
+					var indices []int
+					for _, r := range results {
+						indices = append(indices, r.Index)
+					}
+					if !slices.Equal(indices, tc.expectIndices) {
+						t.Errorf("結果が届いた入力が期待値と異なります: got %v, want %v", indices, tc.expectIndices)
+					}
+					if got := started.Load(); got != tc.expectStarted {
+						t.Errorf("開始した処理の数が期待値と異なります: got %d, want %d", got, tc.expectStarted)
+					}
+					// 実行中の処理にはキャンセルが伝播する
+					if got, want := canceled.Load(), tc.expectStarted-int32(len(tc.expectIndices)); got != want {
+						t.Errorf("キャンセルを受け取った処理の数が期待値と異なります: got %d, want %d", got, want)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("RunStreamは入力チャネルが閉じられると終了する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			inputs := make(chan int)
+			go func() {
+				defer close(inputs)
+				for i := 1; i <= 3; i++ {
+					inputs <- i
+				}
+			}()
+
+			results := collectResults(synctestpkg.RunStream(context.Background(), inputs, sleepThen, synctestpkg.WithWorkerPool(2, 1)))
+
+			got := make([]string, len(results))
+			for i, r := range results {
+				got[i] = r.Value
+			}
+			if want := []string{"1秒", "2秒", "3秒"}; !slices.Equal(got, want) {
+				t.Errorf("結果が期待値と異なります: got %v, want %v", got, want)
+			}
+		})
+	})
+}
+
+func TestTaskError(t *testing.T) {
+	errInvalid := errors.New("不正な入力")
+
+	testCases := []struct {
+		name          string
+		err           error
+		expectMessage string
+	}{
+		{
+			name:          "キャンセル",
+			err:           context.Canceled,
+			expectMessage: `synctest: ProcessWithDelay "タスク": context canceled`,
+		},
+		{
+			name:          "停止処理中",
+			err:           synctestpkg.ErrShuttingDown,
+			expectMessage: `synctest: ProcessWithDelay "タスク": ` + synctestpkg.ErrShuttingDown.Error(),
+		},
+		{
+			name:          "ラップしたエラー",
+			err:           fmt.Errorf("外側: %w", errInvalid),
+			expectMessage: `synctest: ProcessWithDelay "タスク": 外側: ` + errInvalid.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error = &synctestpkg.TaskError{Op: "ProcessWithDelay", Task: "タスク", Err: tc.err}
+
+			if got := err.Error(); got != tc.expectMessage {
+				t.Errorf("エラーメッセージが期待値と異なります: got %q, want %q", got, tc.expectMessage)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("原因をerrors.Isで辿れません: %v", err)
+			}
+			var taskErr *synctestpkg.TaskError
+			if !errors.As(fmt.Errorf("包む: %w", err), &taskErr) || taskErr.Op != "ProcessWithDelay" || taskErr.Task != "タスク" {
+				t.Errorf("ラップしたエラーからTaskErrorを取り出せません: %v", err)
+			}
+		})
+	}
+}
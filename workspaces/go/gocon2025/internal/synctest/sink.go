@@ -0,0 +1,115 @@
+package synctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FrameSink は描画したフレームを書き出すインターフェース
+type FrameSink interface {
+	// WriteFrame はフレームを1つ書き出す
+	WriteFrame(f Frame) error
+	// Close は書き出しを完了させる。まとめて書き出す形式ではここでエンコードする
+	Close() error
+}
+
+// WriteFrames はチャネルが閉じられるまでフレームをsinkに書き出し、最後にsinkを閉じる
+// エラーを設定したフレームを受信した場合やコンテキストが終了した場合は、残りのフレームを読み捨ててそのエラーを返す
+// 書き出しに失敗した場合も同様に残りのフレームを読み捨てるため、フレームを送信するゴルーチンは停止しない
+func WriteFrames(ctx context.Context, frames <-chan Frame, sink FrameSink) error {
+	var err error
+	for f := range frames {
+		if err != nil {
+			continue
+		}
+		switch {
+		case f.Err != nil:
+			err = f.Err
+		case ctx.Err() != nil:
+			err = ctx.Err()
+		default:
+			err = sink.WriteFrame(f)
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return errors.Join(err, sink.Close())
+}
+
+// pngSequenceSink は連番のPNGファイルを書き出すFrameSink
+type pngSequenceSink struct {
+	dir    string
+	prefix string
+}
+
+// NewPNGSequenceSink はdirにprefixとフレーム番号を4桁で連結した名前のPNGファイルを書き出すFrameSinkを作成する
+// 例えばprefixが「frame_」の場合、1フレーム目はframe_0001.pngとなる。dirが存在しない場合は作成する
+func NewPNGSequenceSink(dir, prefix string) (FrameSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &pngSequenceSink{dir: dir, prefix: prefix}, nil
+}
+
+// WriteFrame はフレームを1つのPNGファイルとして書き出す
+func (s *pngSequenceSink) WriteFrame(f Frame) (err error) {
+	path := filepath.Join(s.dir, fmt.Sprintf("%s%04d.png", s.prefix, f.Index))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+	return png.Encode(file, f.Image)
+}
+
+// Close はファイルごとに書き出しが完了しているため何もしない
+func (s *pngSequenceSink) Close() error {
+	return nil
+}
+
+// gifSink はアニメーションGIFを書き出すFrameSink
+type gifSink struct {
+	w     io.Writer
+	delay int // フレームの表示時間（1/100秒単位）
+	anim  gif.GIF
+}
+
+// NewGIFSink はフレームを減色してためておき、Closeでwに無限ループのアニメーションGIFとして書き出すFrameSinkを作成する
+// frameDelayは1フレームの表示時間で、GIFの仕様上10ms単位に丸められる。10ms未満の場合は10msとする
+func NewGIFSink(w io.Writer, frameDelay time.Duration) FrameSink {
+	return &gifSink{
+		w:     w,
+		delay: max(int((frameDelay+5*time.Millisecond)/(10*time.Millisecond)), 1),
+	}
+}
+
+// WriteFrame はフレームをPlan9パレットに減色して追加する
+func (s *gifSink) WriteFrame(f Frame) error {
+	b := f.Image.Bounds()
+	paletted := image.NewPaletted(b, palette.Plan9)
+	draw.Draw(paletted, b, f.Image, b.Min, draw.Src)
+	s.anim.Image = append(s.anim.Image, paletted)
+	s.anim.Delay = append(s.anim.Delay, s.delay)
+	return nil
+}
+
+// Close はためておいたフレームをアニメーションGIFとして書き出す。フレームがない場合は何も書き出さない
+func (s *gifSink) Close() error {
+	if len(s.anim.Image) == 0 {
+		return nil
+	}
+	return gif.EncodeAll(s.w, &s.anim)
+}
@@ -0,0 +1,131 @@
+package synctest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// failingSink は指定したフレーム番号で書き出しに失敗するFrameSink
+type failingSink struct {
+	failAt  int
+	err     error
+	written []int
+	closed  bool
+}
+
+func (s *failingSink) WriteFrame(f synctestpkg.Frame) error {
+	if f.Index == s.failAt {
+		return s.err
+	}
+	s.written = append(s.written, f.Index)
+	return nil
+}
+
+func (s *failingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestFrameSinks(t *testing.T) {
+	cfg := synctestpkg.RenderConfig{Width: 16, Height: 9, TotalFrames: 3}
+
+	t.Run("連番のPNGファイルを書き出す", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "frames")
+		sink, err := synctestpkg.NewPNGSequenceSink(dir, "frame_")
+		if err != nil {
+			t.Fatalf("シンクを作成できません: %v", err)
+		}
+		processor := synctestpkg.NewVideoProcessor()
+
+		if err := synctestpkg.WriteFrames(context.Background(), processor.RenderFrames(context.Background(), cfg), sink); err != nil {
+			t.Fatalf("書き出しに失敗しました: %v", err)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if want := []string{"frame_0001.png", "frame_0002.png", "frame_0003.png"}; !slices.Equal(names, want) {
+			t.Fatalf("書き出したファイルが期待値と異なります: got %v, want %v", names, want)
+		}
+		file, err := os.Open(filepath.Join(dir, names[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		img, err := png.Decode(file)
+		if err != nil {
+			t.Fatalf("PNGとして読み込めません: %v", err)
+		}
+		if got := img.Bounds().Size(); got != image.Pt(16, 9) {
+			t.Errorf("画像の大きさが期待値と異なります: got %v", got)
+		}
+	})
+
+	t.Run("Table Driven Test - アニメーションGIFの表示時間", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			frameDelay  time.Duration
+			expectDelay int
+		}{
+			{name: "10ms単位の時間", frameDelay: 40 * time.Millisecond, expectDelay: 4},
+			{name: "10ms単位に丸める", frameDelay: 33 * time.Millisecond, expectDelay: 3},
+			{name: "10ms未満", frameDelay: time.Millisecond, expectDelay: 1},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				processor := synctestpkg.NewVideoProcessor()
+
+				err := synctestpkg.WriteFrames(context.Background(), processor.RenderFrames(context.Background(), cfg), synctestpkg.NewGIFSink(&buf, tc.frameDelay))
+				if err != nil {
+					t.Fatalf("書き出しに失敗しました: %v", err)
+				}
+
+				anim, err := gif.DecodeAll(&buf)
+				if err != nil {
+					t.Fatalf("GIFとして読み込めません: %v", err)
+				}
+				if len(anim.Image) != 3 {
+					t.Errorf("フレーム数が期待値と異なります: got %d, want 3", len(anim.Image))
+				}
+				for _, d := range anim.Delay {
+					if d != tc.expectDelay {
+						t.Errorf("表示時間が期待値と異なります: got %d, want %d", d, tc.expectDelay)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("書き出しに失敗しても残りのフレームを読み捨ててシンクを閉じる", func(t *testing.T) {
+		errDisk := errors.New("ディスクがいっぱいです")
+		sink := &failingSink{failAt: 2, err: errDisk}
+		processor := synctestpkg.NewVideoProcessor()
+
+		err := synctestpkg.WriteFrames(context.Background(), processor.RenderFrames(context.Background(), cfg), sink)
+
+		if !errors.Is(err, errDisk) {
+			t.Errorf("書き出しのエラーを期待しましたが、%vが返されました", err)
+		}
+		if !slices.Equal(sink.written, []int{1}) || !sink.closed {
+			t.Errorf("シンクの状態が期待値と異なります: written=%v closed=%v", sink.written, sink.closed)
+		}
+	})
+}
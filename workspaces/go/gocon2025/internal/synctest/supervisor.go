@@ -0,0 +1,364 @@
+package synctest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrShuttingDown は停止処理を開始したスーパーバイザーに新しい処理を投入したことを表す
+var ErrShuttingDown = errors.New("synctest: supervisor is shutting down")
+
+// DefaultShutdownSignals はSupervisorConfig.Signalsに指定する一般的な停止シグナル
+var DefaultShutdownSignals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+
+// SupervisorConfig はスーパーバイザーの設定
+type SupervisorConfig struct {
+	// Signals は受信すると停止処理を開始するシグナル。空の場合はシグナルを監視しない
+	Signals []os.Signal
+	// GracePeriod はシグナルで停止処理を開始した場合に実行中の処理の完了を待つ時間。0以下の場合は30秒
+	GracePeriod time.Duration
+	// OnShutdown は停止処理が完了したときに結果を受け取る。シグナルで停止した場合の結果の確認に利用する
+	OnShutdown func(ShutdownReport)
+}
+
+// LostTask は停止処理の期限までに完了せず、強制的にキャンセルされた処理
+type LostTask struct {
+	Name      string    // 処理名
+	StartedAt time.Time // 処理を開始した時刻
+	// Running は強制キャンセルの時点で処理の中で実行中だったタスク名
+	// ProcessWithGoroutineとProcessWithPoolではタスクごとに記録する
+	Running []string
+}
+
+// ShutdownReport は停止処理の結果
+type ShutdownReport struct {
+	StartedAt  time.Time  // 停止処理を開始した時刻
+	FinishedAt time.Time  // すべての処理が終了した時刻
+	Drained    int        // 期限までに完了した処理の数
+	Forced     bool       // 期限を過ぎて強制的にキャンセルしたかどうか
+	Lost       []LostTask // 強制的にキャンセルされた処理。開始した順に並ぶ
+}
+
+// Supervisor はプロセッサが起動したゴルーチンを監視し、安全に停止させるインターフェース
+type Supervisor interface {
+	// Go はnameという名前の処理を監視下のゴルーチンで実行する
+	// fnのコンテキストは強制キャンセルで終了し、context.CauseはErrShuttingDownを返す。停止処理の開始後はErrShuttingDownを返し、fnを実行しない
+	Go(name string, fn func(ctx context.Context)) error
+	// Shutdown は新しい処理の受け付けを止め、実行中の処理が完了するまで待つ
+	// ctxが先に終了した場合は実行中の処理を強制的にキャンセルし、終了を待ってからctxのエラーを返す
+	// 複数回呼び出した場合は最初の停止処理の完了を待ち、同じ結果を返す
+	Shutdown(ctx context.Context) (ShutdownReport, error)
+	// InFlight は実行中の処理名を開始した順に返す
+	InFlight() []string
+	// Done は停止処理が完了すると閉じられるチャネルを返す
+	Done() <-chan struct{}
+}
+
+// supervisor はSupervisorの具象実装
+type supervisor struct {
+	options
+	cfg     SupervisorConfig
+	ctx     context.Context // 強制キャンセルでErrShuttingDownを原因として終了するコンテキスト
+	cancel  context.CancelCauseFunc
+	wg      sync.WaitGroup
+	drained chan struct{} // 実行中の処理がすべて終了すると閉じられる
+	done    chan struct{}
+
+	mu       sync.Mutex
+	seq      uint64
+	running  map[uint64]*supervisedTask
+	closing  bool
+	forceErr error // 強制キャンセルの原因となったコンテキストのエラー
+	report   ShutdownReport
+}
+
+// supervisedTask はスーパーバイザーの監視下で実行中の処理
+type supervisedTask struct {
+	id        uint64
+	name      string
+	startedAt time.Time
+	forced    <-chan struct{} // 強制キャンセルされると閉じられる
+
+	mu    sync.Mutex
+	seq   uint64
+	tasks map[uint64]string // 処理の中で実行中のタスク
+}
+
+// supervisedKey はコンテキストにsupervisedTaskを格納するキー
+type supervisedKey struct{}
+
+// NewSupervisor はスーパーバイザーを作成する
+// cfg.Signalsを指定した場合は、シグナルを受信するとcfg.GracePeriodを期限として停止処理を開始する
+func NewSupervisor(cfg SupervisorConfig, opts ...Option) Supervisor {
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = 30 * time.Second
+	}
+	s := &supervisor{
+		options: newOptions(opts),
+		cfg:     cfg,
+		drained: make(chan struct{}),
+		done:    make(chan struct{}),
+		running: make(map[uint64]*supervisedTask),
+	}
+	s.ctx, s.cancel = context.WithCancelCause(context.Background())
+
+	if len(cfg.Signals) > 0 {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, cfg.Signals...)
+		go s.watchSignals(sig)
+	}
+	return s
+}
+
+// Go はnameという名前の処理を監視下のゴルーチンで実行する
+func (s *supervisor) Go(name string, fn func(ctx context.Context)) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return ErrShuttingDown
+	}
+	s.seq++
+	t := &supervisedTask{id: s.seq, name: name, startedAt: s.clock.Now(), forced: s.ctx.Done()}
+	s.running[t.id] = t
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.release(t)
+		fn(context.WithValue(s.ctx, supervisedKey{}, t))
+	}()
+	return nil
+}
+
+// Shutdown は新しい処理の受け付けを止め、実行中の処理が完了するまで待つ
+func (s *supervisor) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	s.mu.Lock()
+	if !s.closing {
+		s.closing = true
+		s.report.StartedAt = s.clock.Now()
+		go func() {
+			s.wg.Wait()
+			close(s.drained)
+		}()
+		go s.finish()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		s.force(ctx.Err())
+		<-s.done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report, s.forceErr
+}
+
+// InFlight は実行中の処理名を開始した順に返す
+func (s *supervisor) InFlight() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, t := range s.sortedLocked() {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+// Done は停止処理が完了すると閉じられるチャネルを返す
+func (s *supervisor) Done() <-chan struct{} {
+	return s.done
+}
+
+// release は処理の終了を記録する
+func (s *supervisor) release(t *supervisedTask) {
+	s.mu.Lock()
+	delete(s.running, t.id)
+	if s.closing && !s.report.Forced {
+		s.report.Drained++
+	}
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+// force は実行中の処理を失われた処理として記録し、強制的にキャンセルする
+// すでに強制キャンセルした場合は何もしない
+func (s *supervisor) force(cause error) {
+	s.mu.Lock()
+	if s.report.Forced {
+		s.mu.Unlock()
+		return
+	}
+	// 完了を待つ必要がなくなった場合は強制しない
+	select {
+	case <-s.drained:
+		s.mu.Unlock()
+		return
+	default:
+	}
+	s.report.Forced = true
+	s.forceErr = cause
+	for _, t := range s.sortedLocked() {
+		s.report.Lost = append(s.report.Lost, LostTask{
+			Name:      t.name,
+			StartedAt: t.startedAt,
+			Running:   t.runningTasks(),
+		})
+	}
+	s.mu.Unlock()
+
+	s.cancel(ErrShuttingDown)
+}
+
+// finish は実行中の処理がすべて終了するのを待ち、停止処理を完了させる
+func (s *supervisor) finish() {
+	<-s.drained
+	s.cancel(ErrShuttingDown)
+
+	s.mu.Lock()
+	s.report.FinishedAt = s.clock.Now()
+	report := s.report
+	s.mu.Unlock()
+
+	close(s.done)
+	if s.cfg.OnShutdown != nil {
+		s.cfg.OnShutdown(report)
+	}
+}
+
+// watchSignals はシグナルを受信するとGracePeriodを期限として停止処理を開始する
+func (s *supervisor) watchSignals(sig chan os.Signal) {
+	defer signal.Stop(sig)
+
+	select {
+	case <-sig:
+	case <-s.done:
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := s.clock.NewTimer(s.cfg.GracePeriod)
+	defer timer.Stop()
+	go func() {
+		select {
+		case <-timer.C():
+			cancel()
+		case <-s.done:
+		}
+	}()
+	s.Shutdown(ctx)
+}
+
+// sortedLocked は実行中の処理を開始した順に返す
+func (s *supervisor) sortedLocked() []*supervisedTask {
+	tasks := make([]*supervisedTask, 0, len(s.running))
+	for _, t := range s.running {
+		tasks = append(tasks, t)
+	}
+	slices.SortFunc(tasks, func(a, b *supervisedTask) int {
+		return cmp.Compare(a.id, b.id)
+	})
+	return tasks
+}
+
+// track はタスクの実行開始を記録し、終了を記録する関数を返す
+func (t *supervisedTask) track(task string) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tasks == nil {
+		t.tasks = make(map[uint64]string)
+	}
+	t.seq++
+	id := t.seq
+	t.tasks[id] = task
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.tasks, id)
+	}
+}
+
+// runningTasks は実行中のタスク名を開始した順に返す
+func (t *supervisedTask) runningTasks() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]uint64, 0, len(t.tasks))
+	for id := range t.tasks {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	var names []string
+	for _, id := range ids {
+		names = append(names, t.tasks[id])
+	}
+	return names
+}
+
+// WithSupervisor はプロセッサが起動するゴルーチンをsの監視下で実行する
+// sが停止処理を開始した後に呼び出したメソッドは処理を行わず、ErrShuttingDownを設定した結果を送信してチャネルを閉じる
+// 強制キャンセルされた処理のコンテキストはErrShuttingDownを原因として終了し、中断した結果にはctx.Err()の代わりにErrShuttingDownを設定する
+// 呼び出し元のコンテキストがキャンセルされた場合はcontext.Canceledとなるため、強制キャンセルと区別できる
+func WithSupervisor(s Supervisor) Option {
+	return func(o *options) {
+		o.supervisor = s
+	}
+}
+
+// spawn はfnを新しいゴルーチンで実行する
+// WithSupervisorを指定した場合は監視下で実行し、fnのコンテキストは強制キャンセルでもErrShuttingDownを原因として終了する
+// 停止処理中のため受け付けられなかった場合はfnを実行せずにfalseを返す
+func (o options) spawn(ctx context.Context, name string, fn func(ctx context.Context)) bool {
+	fn = o.traced(name, fn)
+	if o.supervisor == nil {
+		go fn(ctx)
+		return true
+	}
+	err := o.supervisor.Go(name, func(sctx context.Context) {
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		stop := context.AfterFunc(sctx, func() { cancel(context.Cause(sctx)) })
+		defer stop()
+		fn(context.WithValue(ctx, supervisedKey{}, sctx.Value(supervisedKey{})))
+	})
+	return err == nil
+}
+
+// sendTerminal は処理が終了した理由を通知する最後の値をchに送信する
+// 受信側が読み出しをやめていても停止処理を妨げないよう、監視下の処理が強制キャンセルされた後は
+// 受信されるのを待たずに破棄する。監視下でない場合は受信されるまで待つ
+func sendTerminal[T any](ctx context.Context, ch chan<- T, v T) {
+	select {
+	case ch <- v:
+		return
+	default:
+	}
+	var forced <-chan struct{}
+	if t, ok := ctx.Value(supervisedKey{}).(*supervisedTask); ok {
+		forced = t.forced
+	}
+	select {
+	case ch <- v:
+	case <-forced:
+	}
+}
+
+// tracked はタスクの実行中にタスク名を監視下の処理に記録する関数を返す
+// 強制キャンセルの時点で実行中だったタスクはLostTask.Runningに記録される
+func tracked[R any](fn func(context.Context, string) (R, error)) func(context.Context, string) (R, error) {
+	return func(ctx context.Context, task string) (R, error) {
+		if t, ok := ctx.Value(supervisedKey{}).(*supervisedTask); ok {
+			defer t.track(task)()
+		}
+		return fn(ctx, task)
+	}
+}
@@ -0,0 +1,351 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+func TestSupervisor(t *testing.T) {
+	t.Run("Table Driven Test - 停止処理の期限と実行中の処理", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			delay         time.Duration
+			grace         time.Duration
+			expectResult  bool
+			expectErr     error
+			expectDrained int
+			expectLost    []string
+		}{
+			{
+				name:          "期限内に完了する処理は結果を返す",
+				delay:         time.Second,
+				grace:         5 * time.Second,
+				expectResult:  true,
+				expectDrained: 1,
+			},
+			{
+				name:       "期限を過ぎた処理は強制キャンセルされる",
+				delay:      10 * time.Second,
+				grace:      time.Second,
+				expectErr:  context.DeadlineExceeded,
+				expectLost: []string{"ProcessWithDelay: 停止テスト"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					supervisor := synctestpkg.NewSupervisor(synctestpkg.SupervisorConfig{})
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithSupervisor(supervisor))
+					start := time.Now()
+
+					result := processor.ProcessWithDelay(context.Background(), tc.delay, "停止テスト")
+					synctest.Wait()
+					if got := supervisor.InFlight(); !slices.Equal(got, []string{"ProcessWithDelay: 停止テスト"}) {
+						t.Errorf("実行中の処理が期待値と異なります: got %v", got)
+					}
+
+					ctx, cancel := context.WithTimeout(context.Background(), tc.grace)
+					defer cancel()
+					report, err := supervisor.Shutdown(ctx)
+
+					if !errors.Is(err, tc.expectErr) {
+						t.Errorf("エラーが期待値と異なります: got %v, want %v", err, tc.expectErr)
+					}
+					if report.Drained != tc.expectDrained {
+						t.Errorf("完了した処理の数が期待値と異なります: got %d, want %d", report.Drained, tc.expectDrained)
+					}
+					if report.Forced != (tc.expectErr != nil) {
+						t.Errorf("強制キャンセルの有無が期待値と異なります: got %v", report.Forced)
+					}
+					var lost []string
+					for _, l := range report.Lost {
+						lost = append(lost, l.Name)
+					}
+					if !slices.Equal(lost, tc.expectLost) {
+						t.Errorf("失われた処理が期待値と異なります: got %v, want %v", lost, tc.expectLost)
+					}
+					if want := min(tc.delay, tc.grace); report.FinishedAt.Sub(start) != want {
+						t.Errorf("停止処理の完了時刻が期待値と異なります: got %v, want %v", report.FinishedAt.Sub(start), want)
+					}
+
+					// 強制キャンセルされた処理は呼び出し元のキャンセルと区別できるように、ErrShuttingDownを結果として返す
+					got := <-result
+					if (got.Err == nil) != tc.expectResult || (!tc.expectResult && !errors.Is(got.Err, synctestpkg.ErrShuttingDown)) {
+						t.Errorf("結果の有無が期待値と異なります: got %q, %v, want %v", got.Value, got.Err, tc.expectResult)
+					}
+					select {
+					case <-supervisor.Done():
+					default:
+						t.Error("停止処理の完了後にDoneが閉じられていません")
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("呼び出し元のキャンセルは強制キャンセルと区別できる", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			supervisor := synctestpkg.NewSupervisor(synctestpkg.SupervisorConfig{})
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithSupervisor(supervisor))
+			ctx, cancel := context.WithCancel(context.Background())
+
+			result := processor.ProcessWithDelay(ctx, time.Second, "呼び出し元のキャンセル")
+			cancel()
+
+			if r := <-result; !errors.Is(r.Err, context.Canceled) || errors.Is(r.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("context.Canceledを期待しましたが、%vが返されました", r.Err)
+			}
+			if _, err := supervisor.Shutdown(context.Background()); err != nil {
+				t.Fatalf("停止処理が失敗しました: %v", err)
+			}
+		})
+	})
+
+	t.Run("停止処理の開始後は新しい処理を受け付けない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			supervisor := synctestpkg.NewSupervisor(synctestpkg.SupervisorConfig{})
+			if _, err := supervisor.Shutdown(context.Background()); err != nil {
+				t.Fatalf("停止処理が失敗しました: %v", err)
+			}
+
+			if err := supervisor.Go("後から投入", func(context.Context) {}); !errors.Is(err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("ErrShuttingDownを期待しましたが、%vが返されました", err)
+			}
+
+			task := synctestpkg.NewTaskProcessor(synctestpkg.WithSupervisor(supervisor))
+			video := synctestpkg.NewVideoProcessor(synctestpkg.WithSupervisor(supervisor))
+			if r := <-task.ProcessWithDelay(context.Background(), 0, "拒否"); !errors.Is(r.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("ProcessWithDelayがErrShuttingDownを返すことを期待しましたが、%q, %vが返されました", r.Value, r.Err)
+			}
+			if r := <-task.ProcessWithPolling(context.Background(), time.Second, 3); r.Reason != synctestpkg.StopReasonCanceled || !errors.Is(r.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("ProcessWithPollingがErrShuttingDownを返すことを期待しましたが、%+vが返されました", r)
+			}
+			if r := <-task.ProcessWithGoroutine(context.Background(), []string{"拒否"}); !errors.Is(r.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("ProcessWithGoroutineがErrShuttingDownを返すことを期待しましたが、%q, %vが返されました", r.Value, r.Err)
+			}
+			if f := <-video.GenerateFrames(context.Background(), 3); f.Index != 1 || !errors.Is(f.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("GenerateFramesがErrShuttingDownを返すことを期待しましたが、%+vが返されました", f)
+			}
+			tasks := make(chan string)
+			close(tasks)
+			if r := <-task.ProcessWithPool(context.Background(), tasks); !r.Terminal() || !errors.Is(r.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("ProcessWithPoolがErrShuttingDownを返すことを期待しましたが、%+vが返されました", r)
+			}
+			q := openQueue(t, synctestpkg.QueueConfig{})
+			defer q.Close()
+			if r := <-task.ProcessQueue(context.Background(), q); !r.Terminal() || !errors.Is(r.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("ProcessQueueがErrShuttingDownを返すことを期待しましたが、%+vが返されました", r)
+			}
+			cfg := synctestpkg.RenderConfig{TotalFrames: 3, JobID: "拒否"}
+			if f := <-video.RenderFrames(context.Background(), cfg); f.Index != 1 || !errors.Is(f.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("RenderFramesがErrShuttingDownを返すことを期待しましたが、%d, %vが返されました", f.Index, f.Err)
+			}
+			if f := <-video.Resume(context.Background(), "拒否"); !errors.Is(f.Err, synctestpkg.ErrShuttingDown) {
+				t.Errorf("ResumeがErrShuttingDownを返すことを期待しましたが、%vが返されました", f.Err)
+			}
+			if pb := video.Play(context.Background(), synctestpkg.PlaybackConfig{TotalFrames: 3}); !errors.Is(pb.Err(), synctestpkg.ErrShuttingDown) {
+				t.Errorf("PlayがErrShuttingDownで終了することを期待しましたが、%vが返されました", pb.Err())
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - 結果を読み出さない処理も強制キャンセルで停止できる", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			start func(t *testing.T, supervisor synctestpkg.Supervisor)
+		}{
+			{
+				name: "RenderFrames",
+				start: func(t *testing.T, supervisor synctestpkg.Supervisor) {
+					video := synctestpkg.NewVideoProcessor(synctestpkg.WithSupervisor(supervisor))
+					video.RenderFrames(context.Background(), synctestpkg.RenderConfig{Width: 2, Height: 2, TotalFrames: 10})
+				},
+			},
+			{
+				name: "Resume",
+				start: func(t *testing.T, supervisor synctestpkg.Supervisor) {
+					dir := t.TempDir()
+					// 1フレーム目まで描画したチェックポイントを用意する
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					first := synctestpkg.NewVideoProcessor(synctestpkg.WithCheckpoints(dir, 1), synctestpkg.WithRenderer(&countingRenderer{}))
+					cfg := synctestpkg.RenderConfig{Width: 2, Height: 2, TotalFrames: 10, JobID: "abandoned"}
+					receiveFrames(t, first.RenderFrames(ctx, cfg), 1, cancel)
+
+					video := synctestpkg.NewVideoProcessor(
+						synctestpkg.WithSupervisor(supervisor),
+						synctestpkg.WithCheckpoints(dir, 1),
+						synctestpkg.WithRenderer(&countingRenderer{}),
+					)
+					video.Resume(context.Background(), "abandoned")
+				},
+			},
+			{
+				name: "ProcessWithPool",
+				start: func(t *testing.T, supervisor synctestpkg.Supervisor) {
+					task := synctestpkg.NewTaskProcessor(synctestpkg.WithSupervisor(supervisor), synctestpkg.WithWorkerPool(1, 1))
+					tasks := make(chan string, 3)
+					tasks <- "a"
+					tasks <- "b"
+					tasks <- "c"
+					close(tasks)
+					task.ProcessWithPool(context.Background(), tasks)
+				},
+			},
+			{
+				name: "ProcessQueue",
+				start: func(t *testing.T, supervisor synctestpkg.Supervisor) {
+					q := openQueue(t, synctestpkg.QueueConfig{})
+					t.Cleanup(func() { q.Close() })
+					enqueue(t, q, "a", "b", "c")
+					task := synctestpkg.NewTaskProcessor(synctestpkg.WithSupervisor(supervisor), synctestpkg.WithWorkerPool(1, 0))
+					task.ProcessQueue(context.Background(), q)
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					supervisor := synctestpkg.NewSupervisor(synctestpkg.SupervisorConfig{})
+					tc.start(t, supervisor)
+					// 結果のチャネルのバッファが埋まり、送信を待つ状態にする
+					time.Sleep(time.Second)
+
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+					defer cancel()
+					report, err := supervisor.Shutdown(ctx)
+
+					if !errors.Is(err, context.DeadlineExceeded) || !report.Forced {
+						t.Errorf("強制キャンセルで停止することを期待しましたが、%+v, %vが返されました", report, err)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("強制キャンセル時に実行中だったタスクを報告する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			supervisor := synctestpkg.NewSupervisor(synctestpkg.SupervisorConfig{})
+			processor := synctestpkg.NewTaskProcessor(
+				synctestpkg.WithSupervisor(supervisor),
+				synctestpkg.WithWorkerPool(2, 0),
+			)
+
+			// 2並列で100msずつかかるため、150msの時点では3件目と4件目が実行中
+			result := processor.ProcessWithGoroutine(context.Background(), []string{"task1", "task2", "task3", "task4", "task5"})
+			time.Sleep(50 * time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			report, err := supervisor.Shutdown(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("context.DeadlineExceededを期待しましたが、%vが返されました", err)
+			}
+
+			if len(report.Lost) != 1 || report.Lost[0].Name != "ProcessWithGoroutine" {
+				t.Fatalf("失われた処理が期待値と異なります: got %+v", report.Lost)
+			}
+			running := slices.Sorted(slices.Values(report.Lost[0].Running))
+			if want := []string{"task3", "task4"}; !slices.Equal(running, want) {
+				t.Errorf("実行中だったタスクが期待値と異なります: got %v, want %v", running, want)
+			}
+
+			var completed, canceled []string
+			for r := range result {
+				if r.Err == nil {
+					completed = append(completed, r.Value)
+				} else if errors.Is(r.Err, synctestpkg.ErrShuttingDown) {
+					canceled = append(canceled, r.Value)
+				}
+			}
+			if len(completed) != 2 || len(canceled) != 3 {
+				t.Errorf("完了したタスクとキャンセルされたタスクの数が期待値と異なります: got %v, %d", completed, len(canceled))
+			}
+		})
+	})
+
+	t.Run("複数回のShutdownは同じ結果を返す", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			supervisor := synctestpkg.NewSupervisor(synctestpkg.SupervisorConfig{})
+			processor := synctestpkg.NewVideoProcessor(synctestpkg.WithSupervisor(supervisor))
+			frames := processor.GenerateFrames(context.Background(), 100)
+
+			// 20フレーム目の生成と期限が重ならないように少しずらす
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second+25*time.Millisecond)
+			defer cancel()
+			first, firstErr := supervisor.Shutdown(ctx)
+			second, secondErr := supervisor.Shutdown(context.Background())
+
+			if !errors.Is(firstErr, context.DeadlineExceeded) || !errors.Is(secondErr, context.DeadlineExceeded) {
+				t.Errorf("エラーが期待値と異なります: got %v, %v", firstErr, secondErr)
+			}
+			if len(first.Lost) != 1 || len(second.Lost) != 1 || first.FinishedAt != second.FinishedAt {
+				t.Errorf("結果が一致しません: %+v, %+v", first, second)
+			}
+
+			count := 0
+			for f := range frames {
+				if f.Err != nil {
+					if f.Index != 21 || !errors.Is(f.Err, synctestpkg.ErrShuttingDown) {
+						t.Errorf("中断した結果が期待値と異なります: %+v", f)
+					}
+					continue
+				}
+				count++
+			}
+			if count != 20 {
+				t.Errorf("強制キャンセルまでに生成したフレーム数が期待値と異なります: got %d, want 20", count)
+			}
+		})
+	})
+
+	t.Run("シグナルを受信すると停止処理を開始する", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("シグナルの送信に対応していないため")
+		}
+
+		reports := make(chan synctestpkg.ShutdownReport, 1)
+		supervisor := synctestpkg.NewSupervisor(synctestpkg.SupervisorConfig{
+			Signals:     []os.Signal{os.Interrupt},
+			GracePeriod: 10 * time.Millisecond,
+			OnShutdown:  func(r synctestpkg.ShutdownReport) { reports <- r },
+		})
+		release := make(chan struct{})
+		defer close(release)
+		if err := supervisor.Go("応答しない処理", func(ctx context.Context) {
+			select {
+			case <-ctx.Done():
+			case <-release:
+			}
+		}); err != nil {
+			t.Fatalf("処理を投入できません: %v", err)
+		}
+
+		self, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Fatalf("プロセスを取得できません: %v", err)
+		}
+		if err := self.Signal(os.Interrupt); err != nil {
+			t.Fatalf("シグナルを送信できません: %v", err)
+		}
+
+		select {
+		case r := <-reports:
+			if !r.Forced || len(r.Lost) != 1 || r.Lost[0].Name != "応答しない処理" {
+				t.Errorf("停止処理の結果が期待値と異なります: %+v", r)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("シグナルを送信しても停止処理が完了しません")
+		}
+	})
+}
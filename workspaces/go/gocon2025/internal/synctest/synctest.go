@@ -7,126 +7,216 @@ import (
 
 // TaskProcessor は非同期でタスクを処理するインターフェース
 type TaskProcessor interface {
-	// ProcessWithDelay 指定した遅延後にタスクを処理する
-	ProcessWithDelay(ctx context.Context, delay time.Duration, message string) <-chan string
-	// ProcessWithPolling 定期的にポーリングして結果を返す
-	ProcessWithPolling(ctx context.Context, interval time.Duration, maxRetries int) <-chan bool
-	// ProcessWithGoroutine ゴルーチンでタスクを実行し、完了を通知する
-	ProcessWithGoroutine(ctx context.Context, tasks []string) <-chan string
+	// ProcessWithDelay 指定した遅延後にタスクを処理し、成功または失敗の結果を1つ返す
+	ProcessWithDelay(ctx context.Context, delay time.Duration, message string) <-chan Result[string]
+	// ProcessWithPolling 定期的にポーリングして、終了した理由を含む結果を返す
+	ProcessWithPolling(ctx context.Context, interval time.Duration, maxRetries int) <-chan PollResult
+	// Poll 判定関数が完了を返すまで定期的にポーリングし、終了理由を含む結果を返す
+	Poll(ctx context.Context, cfg PollConfig, probe ProbeFunc) <-chan PollResult
+	// ProcessWithRetry 処理を即座に実行し、失敗した場合はポリシーに従って再実行する
+	// policyがnilの場合は100ミリ秒から2倍ずつ待機時間を増やし、3回までリトライする
+	ProcessWithRetry(ctx context.Context, policy RetryPolicy, op func(ctx context.Context) error) <-chan PollResult
+	// ProcessWithGoroutine ゴルーチンでタスクを実行し、タスクごとに成功または失敗の結果を通知する
+	ProcessWithGoroutine(ctx context.Context, tasks []string) <-chan Result[string]
+	// ProcessWithPool チャネルから受け取ったタスクをワーカープールで処理し、開始・完了時刻を通知する
+	ProcessWithPool(ctx context.Context, tasks <-chan string) <-chan TaskReport
+	// ProcessQueue 永続キューから受信したタスクをワーカープールで処理し、完了したメッセージをAckする
+	ProcessQueue(ctx context.Context, q DurableQueue) <-chan TaskReport
 }
 
 // VideoProcessor は動画処理のインターフェース
 type VideoProcessor interface {
-	// GenerateFrames 動画のNフレーム目の画像を生成する
-	GenerateFrames(ctx context.Context, totalFrames int) <-chan int
+	// GenerateFrames 動画のNフレーム目の画像を生成する。中断した場合は原因を設定した結果を最後に送信する
+	GenerateFrames(ctx context.Context, totalFrames int) <-chan FrameResult
+	// RenderFrames 設定したFrameRendererで各フレームの画像を描画して順に送信する
+	RenderFrames(ctx context.Context, cfg RenderConfig) <-chan Frame
+	// StartRender フレームを描画してsinkに書き出すジョブを開始し、進捗を確認できるハンドルを返す
+	StartRender(ctx context.Context, cfg RenderConfig, sink FrameSink) RenderJob
+	// Resume チェックポイントを保存したジョブを、受信された最後のフレームの次から描画して送信する
+	Resume(ctx context.Context, jobID string) <-chan Frame
+	// Play フレームを生成しながら指定したフレームレートで再生する
+	Play(ctx context.Context, cfg PlaybackConfig) Playback
 }
 
 // taskProcessor はTaskProcessorの具象実装
-type taskProcessor struct{}
+type taskProcessor struct {
+	options
+}
 
 // NewTaskProcessor TaskProcessorの新しいインスタンスを作成する
-func NewTaskProcessor() TaskProcessor {
-	return &taskProcessor{}
+func NewTaskProcessor(opts ...Option) TaskProcessor {
+	return &taskProcessor{
+		options: newOptions(opts),
+	}
 }
 
-// ProcessWithDelay 指定した遅延後にタスクを処理する
-func (p *taskProcessor) ProcessWithDelay(ctx context.Context, delay time.Duration, message string) <-chan string {
-	result := make(chan string, 1)
+// ProcessWithDelay 指定した遅延後にタスクを処理し、結果を1つ送信してチャネルを閉じる
+// 失敗した場合はErrに*TaskErrorを設定した結果を送信する。コンテキストが終了した場合の原因はcontext.Cause(ctx)となる
+// WithDeduplicationを指定した場合は、同じ遅延とメッセージの同時呼び出しを1回の処理にまとめる
+func (p *taskProcessor) ProcessWithDelay(ctx context.Context, delay time.Duration, message string) <-chan Result[string] {
+	const op = "ProcessWithDelay"
+	result := make(chan Result[string], 1)
+
+	process := func(ctx context.Context) (string, error) {
+		if p.limiter != nil {
+			if err := p.limiter.Wait(ctx); err != nil {
+				return "", err
+			}
+		}
+		select {
+		case <-p.clock.After(delay):
+			return "処理完了: " + message, nil
+		case <-ctx.Done():
+			return "", context.Cause(ctx)
+		}
+	}
 
-	go func() {
+	started := p.spawn(ctx, op+": "+message, func(ctx context.Context) {
 		defer close(result)
 
-		select {
-		case <-time.After(delay):
-			result <- "処理完了: " + message
-		case <-ctx.Done():
-			return
+		o := p.operation(op)
+		tm := o.taskMetrics()
+		tm.submit()
+		// 開始前にCancelされた場合もタスクのコンテキストが終了しているため、processはすぐに返る
+		task := o.registerTask(ctx, message)
+		task.start()
+		startedAt := p.clock.Now()
+		tctx, span := p.startSpan(task.context(ctx), "task", Attr("task", message), Attr("delay", delay.String()))
+
+		var res string
+		var err error
+		if p.dedup != nil {
+			var shared bool
+			res, err, shared = p.dedup.DoContext(tctx, delayKey{delay: delay, message: message}, process)
+			span.SetAttributes(Attr("shared", shared))
+		} else {
+			res, err = process(tctx)
 		}
-	}()
+		err = task.finish(res, err)
+		span.RecordError(err)
+		span.End()
+		elapsed := p.clock.Now().Sub(startedAt)
+		tm.finish(err, elapsed)
+		result <- Result[string]{Value: res, Err: taskError(op, message, err), StartedAt: startedAt, Duration: elapsed}
+	})
+	if !started {
+		result <- Result[string]{Err: taskError(op, message, ErrShuttingDown)}
+		close(result)
+	}
 
 	return result
 }
 
-// ProcessWithPolling 定期的にポーリングして結果を返す
-func (p *taskProcessor) ProcessWithPolling(ctx context.Context, interval time.Duration, maxRetries int) <-chan bool {
-	result := make(chan bool, 1)
+// ProcessWithPolling 定期的にポーリングして、終了した理由を含む結果を1つ送信してチャネルを閉じる
+// 3回目の試行で成功するシミュレーションで、任意の条件でポーリングする場合はPollを利用する
+// WithCircuitBreakerで指定したブレーカーが開いている場合は試行せずにErrCircuitOpenで終了する
+func (p *taskProcessor) ProcessWithPolling(ctx context.Context, interval time.Duration, maxRetries int) <-chan PollResult {
+	result := make(chan PollResult, 1)
 
-	go func() {
+	started := p.spawn(ctx, "ProcessWithPolling", func(ctx context.Context) {
 		defer close(result)
 
-		// ゼロ以下の間隔の場合は最小値に設定
-		if interval <= 0 {
-			interval = 1 * time.Nanosecond
+		// 3回目で成功するシミュレーション
+		attempts := 0
+		probe := func(context.Context) (bool, error) {
+			attempts++
+			return attempts >= 3, nil
 		}
 
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-
-		retries := 0
-		for {
-			select {
-			case <-ticker.C:
-				retries++
-				// 3回目で成功するシミュレーション
-				if retries >= 3 {
-					result <- true
-					return
-				}
-				if retries >= maxRetries {
-					result <- false
-					return
-				}
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
+		result <- p.poll(ctx, PollConfig{Interval: interval, MaxAttempts: max(maxRetries, 1)}, probe)
+	})
+	if !started {
+		result <- shutdownPollResult
+		close(result)
+	}
 
 	return result
 }
 
-// ProcessWithGoroutine ゴルーチンでタスクを実行し、完了を通知する
-func (p *taskProcessor) ProcessWithGoroutine(ctx context.Context, tasks []string) <-chan string {
-	result := make(chan string, len(tasks))
-
-	for _, task := range tasks {
-		go func(t string) {
-			select {
-			case <-time.After(100 * time.Millisecond): // 各タスクに100ms必要
-				result <- "タスク完了: " + t
-			case <-ctx.Done():
-				return
+// ProcessWithGoroutine ゴルーチンでタスクを実行し、タスクごとに結果を完了した順に通知する
+// 失敗したタスクはErrに*TaskErrorを設定した結果を通知する。コンテキストが終了した場合は、
+// 完了していないタスクをcontext.Cause(ctx)で失敗した結果として通知するため、チャネルを閉じるまでにすべてのタスクの結果が揃う
+func (p *taskProcessor) ProcessWithGoroutine(ctx context.Context, tasks []string) <-chan Result[string] {
+	const op = "ProcessWithGoroutine"
+	result := make(chan Result[string], len(tasks))
+
+	started := p.spawn(ctx, op, func(ctx context.Context) {
+		defer close(result)
+
+		reported := make([]bool, len(tasks))
+		for r := range runSlice(ctx, p.operation(op), tasks, tracked(Guard(p.breaker, p.simulateTask))) {
+			r.Err = taskError(op, tasks[r.Index], r.Err)
+			reported[r.Index] = true
+			result <- r
+		}
+		for i, ok := range reported {
+			if !ok {
+				result <- Result[string]{Index: i, Err: taskError(op, tasks[i], context.Cause(ctx))}
 			}
-		}(task)
+		}
+	})
+	if !started {
+		for i, task := range tasks {
+			result <- Result[string]{Index: i, Err: taskError(op, task, ErrShuttingDown)}
+		}
+		close(result)
 	}
 
 	return result
 }
 
 // videoProcessor はVideoProcessorの具象実装
-type videoProcessor struct{}
+type videoProcessor struct {
+	options
+}
 
 // NewVideoProcessor VideoProcessorの新しいインスタンスを作成する
-func NewVideoProcessor() VideoProcessor {
-	return &videoProcessor{}
+func NewVideoProcessor(opts ...Option) VideoProcessor {
+	return &videoProcessor{
+		options: newOptions(opts),
+	}
 }
 
-// GenerateFrames 動画のNフレーム目の画像を生成する
-func (p *videoProcessor) GenerateFrames(ctx context.Context, totalFrames int) <-chan int {
-	result := make(chan int, totalFrames)
+// GenerateFrames 動画のNフレーム目の画像を生成し、1フレーム目から順に送信する
+// WithWorkerPoolを指定した場合はフレームを並列に生成し、番号順に並べ替えて送信する
+// コンテキストが終了した場合は、次のフレーム番号とcontext.Cause(ctx)を設定した結果を最後に送信してチャネルを閉じる
+func (p *videoProcessor) GenerateFrames(ctx context.Context, totalFrames int) <-chan FrameResult {
+	// 中断した場合の結果は生成できなかったフレームの代わりに送信するため、送信は常にバッファに収まる
+	result := make(chan FrameResult, max(totalFrames, 1))
 
-	go func() {
+	started := p.spawn(ctx, "GenerateFrames", func(ctx context.Context) {
 		defer close(result)
 
-		for i := 1; i <= totalFrames; i++ {
-			select {
-			case <-time.After(50 * time.Millisecond): // 各フレーム生成に50ms必要
-				result <- i
-			case <-ctx.Done():
-				return
-			}
+		var next int
+		if p.pool != nil {
+			next = p.generateFramesParallel(ctx, totalFrames, result)
+		} else {
+			next = p.generateFrames(ctx, totalFrames, result)
+		}
+		if next <= totalFrames {
+			result <- FrameResult{Index: next, Err: context.Cause(ctx)}
 		}
-	}()
+	})
+	if !started {
+		result <- FrameResult{Index: 1, Err: ErrShuttingDown}
+		close(result)
+	}
 
 	return result
 }
+
+// generateFrames はフレームを順に生成してresultに送信し、送信できなかった最初のフレーム番号を返す
+func (p *videoProcessor) generateFrames(ctx context.Context, totalFrames int, result chan<- FrameResult) int {
+	for i := 1; i <= totalFrames; i++ {
+		_, span := p.startSpan(ctx, "frame", Attr("index", i), Attr("kind", frameKindGenerate))
+		f := p.generateFrame(ctx, i)
+		span.RecordError(f.Err)
+		span.End()
+		if f.Err != nil {
+			return i
+		}
+		result <- f
+		p.metrics.frame(frameKindGenerate)
+	}
+	return totalFrames + 1
+}
@@ -2,6 +2,7 @@ package synctest_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -93,8 +94,8 @@ func TestTaskProcessor(t *testing.T) {
 						// 検証 - synctestによりほぼ瞬時に完了する
 						synctest.Wait()
 						got := <-result
-						if got != tc.expected {
-							t.Errorf("結果が期待値と異なります: got %q, want %q", got, tc.expected)
+						if got.Value != tc.expected || got.Err != nil {
+							t.Errorf("結果が期待値と異なります: got %q, %v, want %q", got.Value, got.Err, tc.expected)
 						}
 					})
 				})
@@ -103,25 +104,32 @@ func TestTaskProcessor(t *testing.T) {
 
 		t.Run("Table Driven Test - コンテキストキャンセルのエッジケース", func(t *testing.T) {
 			testCases := []struct {
-				name           string
-				delay          time.Duration
-				cancelTiming   string
-				cancelAfter    time.Duration
-				expectCanceled bool
+				name         string
+				delay        time.Duration
+				cancelTiming string
+				cancelAfter  time.Duration
+				expectErr    error
 			}{
 				{
-					name:           "即座にキャンセル",
-					delay:          1 * time.Second,
-					cancelTiming:   "immediate",
-					cancelAfter:    0,
-					expectCanceled: true,
+					name:         "即座にキャンセル",
+					delay:        1 * time.Second,
+					cancelTiming: "immediate",
+					cancelAfter:  0,
+					expectErr:    context.Canceled,
+				},
+				{
+					name:         "遅延前にキャンセル",
+					delay:        500 * time.Millisecond,
+					cancelTiming: "before_delay",
+					cancelAfter:  100 * time.Millisecond,
+					expectErr:    context.Canceled,
 				},
 				{
-					name:           "遅延前にキャンセル",
-					delay:          500 * time.Millisecond,
-					cancelTiming:   "before_delay",
-					cancelAfter:    100 * time.Millisecond,
-					expectCanceled: true,
+					name:         "遅延前に期限切れ",
+					delay:        500 * time.Millisecond,
+					cancelTiming: "deadline",
+					cancelAfter:  100 * time.Millisecond,
+					expectErr:    context.DeadlineExceeded,
 				},
 				// 注意: synctestでは処理完了後のキャンセルテストは複雑になるため除外
 			}
@@ -131,6 +139,9 @@ func TestTaskProcessor(t *testing.T) {
 					synctest.Test(t, func(t *testing.T) {
 						// 準備
 						ctx, cancel := context.WithCancel(context.Background())
+						if tc.cancelTiming == "deadline" {
+							ctx, cancel = context.WithTimeout(context.Background(), tc.cancelAfter)
+						}
 						defer cancel()
 
 						// 実行
@@ -149,10 +160,19 @@ func TestTaskProcessor(t *testing.T) {
 
 						// 検証
 						synctest.Wait()
-						_, ok := <-result
-
-						if tc.expectCanceled && ok {
-							t.Error("キャンセル後にチャネルが閉じられることを期待しましたが、まだ開いています")
+						got, ok := <-result
+						if !ok {
+							t.Fatal("キャンセルの結果を受信する前にチャネルが閉じられました")
+						}
+						if !errors.Is(got.Err, tc.expectErr) {
+							t.Errorf("エラーが期待値と異なります: got %v, want %v", got.Err, tc.expectErr)
+						}
+						var taskErr *synctestpkg.TaskError
+						if !errors.As(got.Err, &taskErr) || taskErr.Op != "ProcessWithDelay" || taskErr.Task != "キャンセルテスト" {
+							t.Errorf("*TaskErrorで処理名とタスク名を含むことを期待しました: %#v", got.Err)
+						}
+						if _, ok := <-result; ok {
+							t.Error("結果の送信後にチャネルが閉じられることを期待しましたが、まだ開いています")
 						}
 					})
 				})
@@ -238,9 +258,15 @@ func TestTaskProcessor(t *testing.T) {
 
 						// 検証
 						synctest.Wait()
-						success := <-result
-						if success != tc.expectSuccess {
-							t.Errorf("期待値と異なります: got %v, want %v (%s)", success, tc.expectSuccess, tc.description)
+						got := <-result
+						if got.Succeeded != tc.expectSuccess {
+							t.Errorf("期待値と異なります: got %v, want %v (%s)", got.Succeeded, tc.expectSuccess, tc.description)
+						}
+						if tc.expectSuccess && got.Err != nil {
+							t.Errorf("成功した場合はエラーがないことを期待しました: %v (%s)", got.Err, tc.description)
+						}
+						if !tc.expectSuccess && (got.Reason != synctestpkg.StopReasonRetriesExhausted || !errors.Is(got.Err, synctestpkg.ErrRetriesExhausted)) {
+							t.Errorf("リトライ回数の上限で終了することを期待しました: %v, %v (%s)", got.Reason, got.Err, tc.description)
 						}
 					})
 				})
@@ -290,11 +316,17 @@ func TestTaskProcessor(t *testing.T) {
 							cancel()
 						}()
 
-						// 検証 - キャンセル後にチャネルが適切に処理される
+						// 検証 - キャンセルで終了した結果を受信した後にチャネルが閉じられる
 						synctest.Wait()
-						_, ok := <-result
-						if ok {
-							t.Error("キャンセル後にチャネルが閉じられることを期待しましたが、まだ開いています")
+						got, ok := <-result
+						if !ok {
+							t.Fatal("キャンセルの結果を受信する前にチャネルが閉じられました")
+						}
+						if got.Succeeded || got.Reason != synctestpkg.StopReasonCanceled || !errors.Is(got.Err, context.Canceled) {
+							t.Errorf("キャンセルで終了することを期待しました: %+v", got)
+						}
+						if _, ok := <-result; ok {
+							t.Error("結果の送信後にチャネルが閉じられることを期待しましたが、まだ開いています")
 						}
 					})
 				})
@@ -369,7 +401,10 @@ func TestTaskProcessor(t *testing.T) {
 						// 結果を収集
 						for range tc.tasks {
 							taskResult := <-result
-							completedTasks[taskResult] = true
+							if taskResult.Err != nil {
+								t.Errorf("タスク %q が失敗しました: %v (%s)", tc.tasks[taskResult.Index], taskResult.Err, tc.description)
+							}
+							completedTasks[taskResult.Value] = true
 						}
 
 						// すべてのタスクが完了していることを確認
@@ -413,28 +448,22 @@ func TestTaskProcessor(t *testing.T) {
 							cancel()
 						}
 
-						// 検証 - キャンセル後に残りのゴルーチンが適切に終了することを確認
+						// 検証 - キャンセル後もすべてのタスクの結果がキャンセルのエラーとともに送信される
 						synctest.Wait()
 
-						// 完了したタスク数をカウント
-						completedCount := 0
-						for {
-							select {
-							case _, ok := <-result:
-								if !ok {
-									// チャネルが閉じられた場合は終了
-									goto done
-								}
-								completedCount++
-							default:
-								// これ以上データがない場合は終了
-								goto done
+						reported := make(map[int]bool)
+						for r := range result {
+							reported[r.Index] = true
+							if !errors.Is(r.Err, context.Canceled) {
+								t.Errorf("タスク %d のエラーが期待値と異なります: got %v, want %v", r.Index, r.Err, context.Canceled)
+							}
+							var taskErr *synctestpkg.TaskError
+							if !errors.As(r.Err, &taskErr) || taskErr.Op != "ProcessWithGoroutine" || taskErr.Task != tc.tasks[r.Index] {
+								t.Errorf("*TaskErrorで処理名とタスク名を含むことを期待しました: %#v", r.Err)
 							}
 						}
-					done:
-						// キャンセルされた場合、完了タスク数は元のタスク数以下になる
-						if completedCount > len(tc.tasks) {
-							t.Errorf("完了したタスク数が元のタスク数を超えています: completed=%d, expected<=%d", completedCount, len(tc.tasks))
+						if len(reported) != len(tc.tasks) {
+							t.Errorf("結果を受信したタスク数が期待値と異なります: got %d, want %d", len(reported), len(tc.tasks))
 						}
 					})
 				})
@@ -504,9 +533,9 @@ func TestVideoProcessor(t *testing.T) {
 						// 検証 - フレームが順番に生成されることを確認
 						synctest.Wait()
 						for expectedFrame := 1; expectedFrame <= tc.totalFrames; expectedFrame++ {
-							frameNumber := <-result
-							if frameNumber != expectedFrame {
-								t.Errorf("フレーム番号が期待値と異なります: got %d, want %d (%s)", frameNumber, expectedFrame, tc.description)
+							frame := <-result
+							if frame.Index != expectedFrame || frame.Err != nil {
+								t.Errorf("フレームが期待値と異なります: got %d, %v, want %d (%s)", frame.Index, frame.Err, expectedFrame, tc.description)
 							}
 						}
 
@@ -557,24 +586,25 @@ func TestVideoProcessor(t *testing.T) {
 						synctest.Wait()
 
 						generatedFrames := 0
-						for {
-							select {
-							case frameNumber, ok := <-result:
-								if !ok {
-									// チャネルが閉じられた場合は終了
-									goto done
-								}
-								generatedFrames++
-								// フレーム番号が順番通りであることを確認
-								if frameNumber != generatedFrames {
-									t.Errorf("フレーム番号が期待値と異なります: got %d, want %d (%s)", frameNumber, generatedFrames, tc.description)
-								}
-							default:
-								// これ以上データがない場合は終了
-								goto done
+						var last synctestpkg.FrameResult
+						for frame := range result {
+							last = frame
+							if frame.Err != nil {
+								break
+							}
+							generatedFrames++
+							// フレーム番号が順番通りであることを確認
+							if frame.Index != generatedFrames {
+								t.Errorf("フレーム番号が期待値と異なります: got %d, want %d (%s)", frame.Index, generatedFrames, tc.description)
 							}
 						}
-					done:
+						// 最後に生成できなかったフレームの番号とキャンセルのエラーが送信される
+						if last.Index != generatedFrames+1 || !errors.Is(last.Err, context.Canceled) {
+							t.Errorf("中断した結果が期待値と異なります: got %d, %v, want %d, %v (%s)", last.Index, last.Err, generatedFrames+1, context.Canceled, tc.description)
+						}
+						if _, ok := <-result; ok {
+							t.Errorf("中断した結果の送信後にチャネルが閉じられることを期待しましたが、まだ開いています (%s)", tc.description)
+						}
 						// 最低限期待されるフレーム数が生成されていることを確認
 						if generatedFrames < tc.expectMinFrames {
 							t.Errorf("生成されたフレーム数が期待値を下回ります: got %d, want >= %d (%s)", generatedFrames, tc.expectMinFrames, tc.description)
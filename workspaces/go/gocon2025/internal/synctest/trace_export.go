@@ -0,0 +1,127 @@
+package synctest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ErrExporterClosed は閉じたエクスポーターにスパンを書き出そうとしたことを表す
+var ErrExporterClosed = errors.New("synctest: exporter is closed")
+
+// InMemoryExporter は終了したスパンをメモリに保持するエクスポーター。テストでの検証に利用する
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter はInMemoryExporterを作成する
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan はスパンを保持する
+func (e *InMemoryExporter) ExportSpan(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans は保持しているスパンを終了した順に返す
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.spans)
+}
+
+// Reset は保持しているスパンを破棄する
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// JSONLinesExporter は終了したスパンを1行に1つのJSONで書き出すエクスポーター
+type JSONLinesExporter struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer // NewJSONLinesFileExporterで開いたファイル。それ以外はnil
+	closed bool
+}
+
+// NewJSONLinesExporter はwにスパンを書き出すJSONLinesExporterを作成する
+// 書き出しはバッファリングされるため、最後にCloseまたはFlushを呼び出す
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{w: bufio.NewWriter(w)}
+}
+
+// NewJSONLinesFileExporter はpathのファイルの末尾にスパンを書き出すJSONLinesExporterを作成する
+// ファイルが存在しない場合は作成する。Closeでファイルを閉じる
+func NewJSONLinesFileExporter(path string) (*JSONLinesExporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	e := NewJSONLinesExporter(f)
+	e.closer = f
+	return e, nil
+}
+
+// ExportSpan はスパンをJSONの1行として書き出す
+func (e *JSONLinesExporter) ExportSpan(span SpanData) error {
+	b, err := json.Marshal(span)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrExporterClosed
+	}
+	if _, err := e.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Flush はバッファに残っているスパンを書き出す
+func (e *JSONLinesExporter) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.w.Flush()
+}
+
+// Close はバッファに残っているスパンを書き出し、ファイルを開いた場合は閉じる
+func (e *JSONLinesExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	err := e.w.Flush()
+	if e.closer != nil {
+		err = errors.Join(err, e.closer.Close())
+	}
+	return err
+}
+
+// ReadJSONLines はJSONLinesExporterが書き出したスパンを読み込む
+func ReadJSONLines(r io.Reader) ([]SpanData, error) {
+	var spans []SpanData
+	dec := json.NewDecoder(r)
+	for {
+		var s SpanData
+		if err := dec.Decode(&s); errors.Is(err, io.EOF) {
+			return spans, nil
+		} else if err != nil {
+			return spans, err
+		}
+		spans = append(spans, s)
+	}
+}
@@ -0,0 +1,177 @@
+package synctest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+func TestJSONLinesExporter(t *testing.T) {
+	// record はリクエストのスパンとその子のタスクのスパンを書き出す
+	record := func(exporter synctestpkg.SpanExporter) {
+		tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{Exporter: exporter})
+		ctx, root := tracer.Start(context.Background(), "request", synctestpkg.Attr("user", "gopher"))
+		_, task := tracer.Start(ctx, "task")
+		time.Sleep(100 * time.Millisecond)
+		task.AddEvent("retry", synctestpkg.Attr("attempt", 2))
+		task.RecordError(errors.New("失敗しました"))
+		task.End()
+		root.End()
+	}
+
+	t.Run("Table Driven Test - 書き出したスパンを読み込む", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			export func(t *testing.T) []byte
+		}{
+			{
+				name: "io.Writer",
+				export: func(t *testing.T) []byte {
+					var buf bytes.Buffer
+					exporter := synctestpkg.NewJSONLinesExporter(&buf)
+					record(exporter)
+					if err := exporter.Close(); err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+					return buf.Bytes()
+				},
+			},
+			{
+				name: "ファイル",
+				export: func(t *testing.T) []byte {
+					path := filepath.Join(t.TempDir(), "spans.jsonl")
+					exporter, err := synctestpkg.NewJSONLinesFileExporter(path)
+					if err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+					record(exporter)
+					if err := exporter.Close(); err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+					b, err := os.ReadFile(path)
+					if err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+					return b
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					spans, err := synctestpkg.ReadJSONLines(bytes.NewReader(tc.export(t)))
+					if err != nil {
+						t.Fatalf("エラーが発生しました: %v", err)
+					}
+
+					// 終了した順に書き出される
+					if len(spans) != 2 || spans[0].Name != "task" || spans[1].Name != "request" {
+						t.Fatalf("読み込んだスパンが期待値と異なります: %+v", spans)
+					}
+					task, root := spans[0], spans[1]
+					if task.TraceID != root.TraceID || task.ParentID != root.SpanID || root.ParentID != "" {
+						t.Errorf("親子関係が復元されることを期待しました: task=%+v root=%+v", task, root)
+					}
+					if task.Duration() != 100*time.Millisecond {
+						t.Errorf("スパンの長さが期待値と異なります: got %v, want %v", task.Duration(), 100*time.Millisecond)
+					}
+					if root.Attributes["user"] != "gopher" {
+						t.Errorf("属性が期待値と異なります: %v", root.Attributes)
+					}
+					if len(task.Events) != 1 || task.Events[0].Name != "retry" || !task.Events[0].Time.Equal(task.EndTime) {
+						t.Errorf("イベントが期待値と異なります: %+v", task.Events)
+					}
+					if task.Error != "失敗しました" {
+						t.Errorf("エラーが期待値と異なります: got %q", task.Error)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("ファイルの末尾に追記する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spans.jsonl")
+			for range 2 {
+				exporter, err := synctestpkg.NewJSONLinesFileExporter(path)
+				if err != nil {
+					t.Fatalf("エラーが発生しました: %v", err)
+				}
+				record(exporter)
+				if err := exporter.Close(); err != nil {
+					t.Fatalf("エラーが発生しました: %v", err)
+				}
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("エラーが発生しました: %v", err)
+			}
+			defer f.Close()
+			spans, err := synctestpkg.ReadJSONLines(f)
+			if err != nil {
+				t.Fatalf("エラーが発生しました: %v", err)
+			}
+			if len(spans) != 4 {
+				t.Errorf("2回分のスパンが残ることを期待しました: got %d", len(spans))
+			}
+		})
+	})
+
+	t.Run("閉じた後の書き出し", func(t *testing.T) {
+		var buf bytes.Buffer
+		exporter := synctestpkg.NewJSONLinesExporter(&buf)
+		if err := exporter.Close(); err != nil {
+			t.Fatalf("エラーが発生しました: %v", err)
+		}
+
+		if err := exporter.ExportSpan(synctestpkg.SpanData{Name: "late"}); !errors.Is(err, synctestpkg.ErrExporterClosed) {
+			t.Errorf("ErrExporterClosedを期待しました: %v", err)
+		}
+		if err := exporter.Close(); err != nil {
+			t.Errorf("2回目のCloseはエラーを返さないことを期待しました: %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("何も書き出されないことを期待しました: %q", buf.String())
+		}
+	})
+
+	t.Run("壊れた行", func(t *testing.T) {
+		spans, err := synctestpkg.ReadJSONLines(bytes.NewBufferString("{\"name\":\"ok\"}\n{\"name\":"))
+		if err == nil {
+			t.Errorf("エラーを期待しました")
+		}
+		if len(spans) != 1 || spans[0].Name != "ok" {
+			t.Errorf("壊れた行より前のスパンを返すことを期待しました: %+v", spans)
+		}
+	})
+}
+
+func TestInMemoryExporter(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		exporter := synctestpkg.NewInMemoryExporter()
+		tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{Exporter: exporter})
+
+		_, span := tracer.Start(context.Background(), "first")
+		span.End()
+		spans := exporter.Spans()
+		exporter.Reset()
+		_, span = tracer.Start(context.Background(), "second")
+		span.End()
+
+		if len(spans) != 1 || spans[0].Name != "first" {
+			t.Errorf("Resetの前に取得したスパンは変わらないことを期待しました: %+v", spans)
+		}
+		if got := exporter.Spans(); len(got) != 1 || got[0].Name != "second" {
+			t.Errorf("Resetの後のスパンだけを保持することを期待しました: %+v", got)
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package synctest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Attribute はスパンやイベントに付与するキーと値の組
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Attr はAttributeを作成する
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanContext はスパンを識別するID。ゼロ値はスパンがないことを表す
+type SpanContext struct {
+	TraceID string // 一連の処理で共通のID。32桁の16進数
+	SpanID  string // スパンごとのID。16桁の16進数
+}
+
+// IsValid はスパンを識別できるかどうかを返す
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// SpanEvent はスパンの途中で発生した出来事
+type SpanEvent struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// SpanData は終了したスパンの記録。エクスポーターに渡される
+type SpanData struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_span_id,omitempty"` // 親のスパンのID。ルートのスパンでは空
+	Name       string         `json:"name"`
+	StartTime  time.Time      `json:"start_time"`
+	EndTime    time.Time      `json:"end_time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Events     []SpanEvent    `json:"events,omitempty"`
+	Error      string         `json:"error,omitempty"` // RecordErrorで記録したエラー
+}
+
+// Duration はスパンの開始から終了までの時間を返す
+func (d SpanData) Duration() time.Duration {
+	return d.EndTime.Sub(d.StartTime)
+}
+
+// Span は処理1つ分の区間を記録するインターフェース
+type Span interface {
+	// SpanContext はスパンのIDを返す
+	SpanContext() SpanContext
+	// SetAttributes は属性を追加する。同じキーの場合は上書きする
+	SetAttributes(attrs ...Attribute)
+	// AddEvent は現在時刻のイベントを追加する
+	AddEvent(name string, attrs ...Attribute)
+	// RecordError はスパンが表す処理のエラーを記録する。nilの場合は何もしない
+	RecordError(err error)
+	// End はスパンを終了してエクスポーターに渡す。2回目以降の呼び出しは何もしない
+	End()
+}
+
+// SpanExporter は終了したスパンを書き出すインターフェース
+type SpanExporter interface {
+	// ExportSpan は終了したスパンを書き出す。複数のゴルーチンから同時に呼び出される
+	ExportSpan(span SpanData) error
+}
+
+// Tracer はスパンを開始するインターフェース
+type Tracer interface {
+	// Start はctxのスパンを親とするスパンを開始し、そのスパンを持つコンテキストを返す
+	// ctxにスパンがない場合は新しいトレースのルートのスパンになる
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// tracer はTracerの具象実装
+type tracer struct {
+	clock    Clock
+	exporter SpanExporter
+	onError  func(error)
+}
+
+// TracerConfig はトレーサーの設定
+type TracerConfig struct {
+	// Exporter は終了したスパンの書き出し先
+	Exporter SpanExporter
+	// OnExportError はスパンの書き出しに失敗したときに呼び出される。nilの場合は失敗を無視する
+	OnExportError func(error)
+}
+
+// NewTracer はスパンをcfg.Exporterに書き出すトレーサーを作成する
+// WithClockを指定した場合はその時刻でスパンの開始・終了時刻を記録する。それ以外のOptionは無視する
+func NewTracer(cfg TracerConfig, opts ...Option) Tracer {
+	o := newOptions(opts)
+	return &tracer{clock: o.clock, exporter: cfg.Exporter, onError: cfg.OnExportError}
+}
+
+// WithTracer はプロセッサの処理・タスク・フレームごとにtのスパンを記録する
+// 各メソッドの処理のスパンは呼び出し時のコンテキストのスパンの子になり、タスクとフレームのスパンは処理のスパンの子になる
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// Start はctxのスパンを親とするスパンを開始する
+func (t *tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent := SpanFromContext(ctx).SpanContext()
+	s := &span{
+		tracer: t,
+		data: SpanData{
+			TraceID:   parent.TraceID,
+			SpanID:    fmt.Sprintf("%016x", rand.Uint64()),
+			ParentID:  parent.SpanID,
+			Name:      name,
+			StartTime: t.clock.Now(),
+		},
+	}
+	if !parent.IsValid() {
+		s.data.TraceID = fmt.Sprintf("%016x%016x", rand.Uint64(), rand.Uint64())
+		s.data.ParentID = ""
+	}
+	s.SetAttributes(attrs...)
+	return ContextWithSpan(ctx, s), s
+}
+
+// span はSpanの具象実装
+type span struct {
+	tracer *tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext はスパンのIDを返す
+func (s *span) SpanContext() SpanContext {
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID}
+}
+
+// SetAttributes は属性を追加する
+func (s *span) SetAttributes(attrs ...Attribute) {
+	if len(attrs) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]any, len(attrs))
+	}
+	for _, a := range attrs {
+		s.data.Attributes[a.Key] = a.Value
+	}
+}
+
+// AddEvent は現在時刻のイベントを追加する
+func (s *span) AddEvent(name string, attrs ...Attribute) {
+	e := SpanEvent{Name: name, Time: s.tracer.clock.Now()}
+	if len(attrs) > 0 {
+		e.Attributes = make(map[string]any, len(attrs))
+		for _, a := range attrs {
+			e.Attributes[a.Key] = a.Value
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Events = append(s.data.Events, e)
+	}
+}
+
+// RecordError はスパンが表す処理のエラーを記録する
+func (s *span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Error = err.Error()
+	}
+}
+
+// End はスパンを終了してエクスポーターに渡す
+func (s *span) End() {
+	now := s.tracer.clock.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = now
+	data := s.data
+	data.Attributes = maps.Clone(s.data.Attributes)
+	s.mu.Unlock()
+
+	if s.tracer.exporter == nil {
+		return
+	}
+	if err := s.tracer.exporter.ExportSpan(data); err != nil && s.tracer.onError != nil {
+		s.tracer.onError(err)
+	}
+}
+
+// noopSpan はトレーサーを指定していない場合に利用する何も記録しないスパン
+type noopSpan struct{}
+
+func (noopSpan) SpanContext() SpanContext      { return SpanContext{} }
+func (noopSpan) SetAttributes(...Attribute)    {}
+func (noopSpan) AddEvent(string, ...Attribute) {}
+func (noopSpan) RecordError(error)             {}
+func (noopSpan) End()                          {}
+
+// spanKey はコンテキストにスパンを保持するためのキー
+type spanKey struct{}
+
+// ContextWithSpan はspanを持つコンテキストを返す
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext はctxのスパンを返す。スパンがない場合は何も記録しないスパンを返す
+// FrameRendererや処理関数の中で、タスクやフレームのスパンに属性やイベントを追加するのに利用する
+func SpanFromContext(ctx context.Context) Span {
+	if s, ok := ctx.Value(spanKey{}).(Span); ok {
+		return s
+	}
+	return noopSpan{}
+}
+
+// startSpan はトレーサーを指定した場合にスパンを開始する。指定していない場合は何も記録しないスパンを返す
+func (o options) startSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if o.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return o.tracer.Start(ctx, name, attrs...)
+}
+
+// traced はfnを処理名のスパンの中で実行する関数を返す
+func (o options) traced(name string, fn func(ctx context.Context)) func(ctx context.Context) {
+	if o.tracer == nil {
+		return fn
+	}
+	return func(ctx context.Context) {
+		ctx, span := o.tracer.Start(ctx, name)
+		defer span.End()
+		fn(ctx)
+	}
+}
+
+// taskAttributes はタスクのスパンの属性を返す。入力が文字列の場合はタスク名として記録する
+func taskAttributes[T any](index int, input T) []Attribute {
+	attrs := []Attribute{Attr("index", index)}
+	if task, ok := any(input).(string); ok {
+		attrs = append(attrs, Attr("task", task))
+	}
+	return attrs
+}
@@ -0,0 +1,326 @@
+package synctest_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// spansNamed はnameのスパンを開始した順に返す
+func spansNamed(spans []synctestpkg.SpanData, name string) []synctestpkg.SpanData {
+	var named []synctestpkg.SpanData
+	for _, s := range spans {
+		if s.Name == name {
+			named = append(named, s)
+		}
+	}
+	slices.SortStableFunc(named, func(a, b synctestpkg.SpanData) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return named
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("ProcessWithGoroutineの並列実行を再構成する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			exporter := synctestpkg.NewInMemoryExporter()
+			tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{Exporter: exporter})
+			processor := synctestpkg.NewTaskProcessor(synctestpkg.WithTracer(tracer), synctestpkg.WithWorkerPool(2, 0))
+			start := time.Now()
+
+			ctx, root := tracer.Start(context.Background(), "request")
+			drain(processor.ProcessWithGoroutine(ctx, []string{"a", "b", "c"}))
+			root.End()
+
+			spans := exporter.Spans()
+			operations := spansNamed(spans, "ProcessWithGoroutine")
+			if len(operations) != 1 {
+				t.Fatalf("処理のスパンが1つであることを期待しました: %v", operations)
+			}
+			operation := operations[0]
+			if operation.ParentID != root.SpanContext().SpanID || operation.TraceID != root.SpanContext().TraceID {
+				t.Errorf("処理のスパンが呼び出し元のスパンの子であることを期待しました: %+v", operation)
+			}
+			if operation.Duration() != 200*time.Millisecond {
+				t.Errorf("処理のスパンの長さが期待値と異なります: got %v, want %v", operation.Duration(), 200*time.Millisecond)
+			}
+
+			// 2並列のため、cは先に終わったタスクの後に開始する
+			tasks := spansNamed(spans, "task")
+			testCases := []struct {
+				task  string
+				start time.Duration
+				end   time.Duration
+			}{
+				{task: "a", start: 0, end: 100 * time.Millisecond},
+				{task: "b", start: 0, end: 100 * time.Millisecond},
+				{task: "c", start: 100 * time.Millisecond, end: 200 * time.Millisecond},
+			}
+			if len(tasks) != len(testCases) {
+				t.Fatalf("タスクのスパンの数が期待値と異なります: got %d, want %d", len(tasks), len(testCases))
+			}
+			slices.SortStableFunc(tasks, func(a, b synctestpkg.SpanData) int {
+				return a.Attributes["index"].(int) - b.Attributes["index"].(int)
+			})
+			for i, tc := range testCases {
+				s := tasks[i]
+				if s.Attributes["task"] != tc.task {
+					t.Errorf("タスク名が期待値と異なります: got %v, want %v", s.Attributes["task"], tc.task)
+				}
+				if s.ParentID != operation.SpanID || s.TraceID != operation.TraceID {
+					t.Errorf("タスク%sのスパンが処理のスパンの子であることを期待しました: %+v", tc.task, s)
+				}
+				if got := s.StartTime.Sub(start); got != tc.start {
+					t.Errorf("タスク%sの開始時刻が期待値と異なります: got %v, want %v", tc.task, got, tc.start)
+				}
+				if got := s.EndTime.Sub(start); got != tc.end {
+					t.Errorf("タスク%sの終了時刻が期待値と異なります: got %v, want %v", tc.task, got, tc.end)
+				}
+			}
+		})
+	})
+
+	t.Run("Table Driven Test - エラーの記録", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			run         func(ctx context.Context, tracer synctestpkg.Tracer)
+			timeout     time.Duration
+			spanName    string
+			expectError []string
+		}{
+			{
+				name: "キャンセルされたタスク",
+				run: func(ctx context.Context, tracer synctestpkg.Tracer) {
+					processor := synctestpkg.NewTaskProcessor(synctestpkg.WithTracer(tracer), synctestpkg.WithWorkerPool(1, 0))
+					drain(processor.ProcessWithGoroutine(ctx, []string{"a", "b"}))
+				},
+				timeout:     150 * time.Millisecond,
+				spanName:    "task",
+				expectError: []string{"", context.DeadlineExceeded.Error()},
+			},
+			{
+				name: "レンダラーのエラー",
+				run: func(ctx context.Context, tracer synctestpkg.Tracer) {
+					renderer := synctestpkg.FrameRendererFunc(func(_ context.Context, _ *image.RGBA, index, _ int) error {
+						if index == 2 {
+							return errors.New("描画に失敗しました")
+						}
+						return nil
+					})
+					processor := synctestpkg.NewVideoProcessor(synctestpkg.WithTracer(tracer), synctestpkg.WithRenderer(renderer))
+					drain(processor.RenderFrames(ctx, synctestpkg.RenderConfig{TotalFrames: 3, Width: 4, Height: 4}))
+				},
+				// エラーが発生した時点で描画を打ち切る
+				spanName:    "frame",
+				expectError: []string{"", "synctest: render frame 2: 描画に失敗しました"},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					exporter := synctestpkg.NewInMemoryExporter()
+					tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{Exporter: exporter})
+					ctx := context.Background()
+					if tc.timeout > 0 {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+						defer cancel()
+					}
+
+					tc.run(ctx, tracer)
+					synctest.Wait()
+
+					var errs []string
+					for _, s := range spansNamed(exporter.Spans(), tc.spanName) {
+						errs = append(errs, s.Error)
+					}
+					if !slices.Equal(errs, tc.expectError) {
+						t.Errorf("記録されたエラーが期待値と異なります: got %q, want %q", errs, tc.expectError)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - フレームのスパン", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			opts   []synctestpkg.Option
+			run    func(ctx context.Context, processor synctestpkg.VideoProcessor)
+			kind   string
+			parent string
+		}{
+			{
+				name: "GenerateFrames",
+				run: func(ctx context.Context, processor synctestpkg.VideoProcessor) {
+					drain(processor.GenerateFrames(ctx, 3))
+				},
+				kind:   "generate",
+				parent: "GenerateFrames",
+			},
+			{
+				name: "GenerateFramesの並列生成",
+				opts: []synctestpkg.Option{synctestpkg.WithWorkerPool(2, 0)},
+				run: func(ctx context.Context, processor synctestpkg.VideoProcessor) {
+					drain(processor.GenerateFrames(ctx, 3))
+				},
+				kind:   "generate",
+				parent: "GenerateFrames",
+			},
+			{
+				name: "RenderFrames",
+				run: func(ctx context.Context, processor synctestpkg.VideoProcessor) {
+					drain(processor.RenderFrames(ctx, synctestpkg.RenderConfig{TotalFrames: 3, Width: 4, Height: 4}))
+				},
+				kind:   "render",
+				parent: "RenderFrames",
+			},
+			{
+				name: "RenderFramesの並列描画",
+				opts: []synctestpkg.Option{synctestpkg.WithWorkerPool(2, 0)},
+				run: func(ctx context.Context, processor synctestpkg.VideoProcessor) {
+					drain(processor.RenderFrames(ctx, synctestpkg.RenderConfig{TotalFrames: 3, Width: 4, Height: 4}))
+				},
+				kind:   "render",
+				parent: "RenderFrames",
+			},
+			{
+				name: "Play",
+				run: func(ctx context.Context, processor synctestpkg.VideoProcessor) {
+					drain(processor.Play(ctx, synctestpkg.PlaybackConfig{TotalFrames: 3}).Frames())
+				},
+				kind:   "playback",
+				parent: "Play",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					exporter := synctestpkg.NewInMemoryExporter()
+					tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{Exporter: exporter})
+					processor := synctestpkg.NewVideoProcessor(append(tc.opts, synctestpkg.WithTracer(tracer))...)
+
+					tc.run(context.Background(), processor)
+					synctest.Wait()
+
+					spans := exporter.Spans()
+					parents := spansNamed(spans, tc.parent)
+					if len(parents) != 1 {
+						t.Fatalf("処理のスパンが1つであることを期待しました: %v", parents)
+					}
+					var indices []int
+					for _, s := range spansNamed(spans, "frame") {
+						if s.Attributes["kind"] != tc.kind {
+							t.Errorf("フレームの種類が期待値と異なります: got %v, want %v", s.Attributes["kind"], tc.kind)
+						}
+						if s.ParentID != parents[0].SpanID {
+							t.Errorf("フレームのスパンが処理のスパンの子であることを期待しました: %+v", s)
+						}
+						indices = append(indices, s.Attributes["index"].(int))
+					}
+					slices.Sort(indices)
+					if want := []int{1, 2, 3}; !slices.Equal(indices, want) {
+						t.Errorf("フレームのスパンが期待値と異なります: got %v, want %v", indices, want)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("レンダラーからフレームのスパンに属性とイベントを追加する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			exporter := synctestpkg.NewInMemoryExporter()
+			tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{Exporter: exporter})
+			renderer := synctestpkg.FrameRendererFunc(func(ctx context.Context, dst *image.RGBA, index, _ int) error {
+				span := synctestpkg.SpanFromContext(ctx)
+				span.SetAttributes(synctestpkg.Attr("pixels", dst.Bounds().Dx()*dst.Bounds().Dy()))
+				span.AddEvent("painted", synctestpkg.Attr("index", index))
+				return nil
+			})
+			processor := synctestpkg.NewVideoProcessor(synctestpkg.WithTracer(tracer), synctestpkg.WithRenderer(renderer))
+
+			drain(processor.RenderFrames(context.Background(), synctestpkg.RenderConfig{TotalFrames: 1, Width: 4, Height: 2}))
+			synctest.Wait()
+
+			frames := spansNamed(exporter.Spans(), "frame")
+			if len(frames) != 1 {
+				t.Fatalf("フレームのスパンが1つであることを期待しました: %v", frames)
+			}
+			if got := frames[0].Attributes["pixels"]; got != 8 {
+				t.Errorf("レンダラーが追加した属性が期待値と異なります: got %v, want %v", got, 8)
+			}
+			if events := frames[0].Events; len(events) != 1 || events[0].Name != "painted" || events[0].Attributes["index"] != 1 {
+				t.Errorf("レンダラーが追加したイベントが期待値と異なります: %+v", events)
+			}
+		})
+	})
+
+	t.Run("Endは2回目以降の呼び出しで何もしない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			exporter := synctestpkg.NewInMemoryExporter()
+			tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{Exporter: exporter})
+
+			_, span := tracer.Start(context.Background(), "request")
+			time.Sleep(10 * time.Millisecond)
+			span.End()
+			time.Sleep(10 * time.Millisecond)
+			span.SetAttributes(synctestpkg.Attr("late", true))
+			span.End()
+
+			spans := exporter.Spans()
+			if len(spans) != 1 {
+				t.Fatalf("スパンが1回だけ書き出されることを期待しました: %v", spans)
+			}
+			if spans[0].Duration() != 10*time.Millisecond {
+				t.Errorf("スパンの長さが期待値と異なります: got %v, want %v", spans[0].Duration(), 10*time.Millisecond)
+			}
+			if _, ok := spans[0].Attributes["late"]; ok {
+				t.Errorf("終了後に追加した属性が記録されないことを期待しました: %v", spans[0].Attributes)
+			}
+		})
+	})
+
+	t.Run("トレーサーを指定しない場合はスパンを記録しない", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			renderer := synctestpkg.FrameRendererFunc(func(ctx context.Context, _ *image.RGBA, _, _ int) error {
+				if synctestpkg.SpanFromContext(ctx).SpanContext().IsValid() {
+					t.Errorf("トレーサーを指定しない場合はスパンがないことを期待しました")
+				}
+				return nil
+			})
+			processor := synctestpkg.NewVideoProcessor(synctestpkg.WithRenderer(renderer))
+
+			drain(processor.RenderFrames(context.Background(), synctestpkg.RenderConfig{TotalFrames: 2, Width: 4, Height: 4}))
+		})
+	})
+
+	t.Run("書き出しの失敗を通知する", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			exporter := synctestpkg.NewJSONLinesExporter(io.Discard)
+			if err := exporter.Close(); err != nil {
+				t.Fatalf("エラーが発生しました: %v", err)
+			}
+			var errs []error
+			tracer := synctestpkg.NewTracer(synctestpkg.TracerConfig{
+				Exporter:      exporter,
+				OnExportError: func(err error) { errs = append(errs, err) },
+			})
+
+			_, span := tracer.Start(context.Background(), "request")
+			span.End()
+
+			if len(errs) != 1 || !errors.Is(errs[0], synctestpkg.ErrExporterClosed) {
+				t.Errorf("ErrExporterClosedが通知されることを期待しました: %v", errs)
+			}
+		})
+	})
+}
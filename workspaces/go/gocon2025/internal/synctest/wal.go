@@ -0,0 +1,248 @@
+package synctest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// walHeaderSize はレコードの先頭に付与するペイロードの長さとチェックサムの大きさ
+const walHeaderSize = 8
+
+// walMaxRecordSize はレコードのペイロードの上限。壊れた長さで巨大な領域を確保しないようにする
+const walMaxRecordSize = 1 << 24
+
+// walTable はレコードのチェックサムの計算に利用するCRC-32Cの表
+var walTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walOp はログに記録する操作の種類
+type walOp string
+
+const (
+	walNext    walOp = "next"    // 次に割り当てるID。圧縮したログの先頭に記録する
+	walEnqueue walOp = "enqueue" // メッセージの追加
+	walLease   walOp = "lease"   // 受信者への配信
+	walAck     walOp = "ack"     // 処理の完了
+	walNack    walOp = "nack"    // 処理の失敗。再配信を待つ
+	walRelease walOp = "release" // 失敗に数えずに受信前に戻す
+	walDead    walOp = "dead"    // 失敗回数が上限に達したためデッドレターに移す
+)
+
+// walRecord はログの1レコード
+type walRecord struct {
+	Op       walOp     `json:"op"`
+	ID       uint64    `json:"id"`
+	Task     string    `json:"task,omitempty"`
+	At       time.Time `json:"at,omitzero"`        // enqueueでは追加した時刻、leaseでは可視性タイムアウトの期限
+	Delivery int       `json:"delivery,omitempty"` // 圧縮したenqueueで引き継ぐ配信回数
+	Failures int       `json:"failures,omitempty"` // 圧縮したenqueueで引き継ぐ失敗回数
+	Error    string    `json:"error,omitempty"`    // nackとdeadの失敗の理由。圧縮したenqueueでは最後の失敗の理由
+	Dead     bool      `json:"dead,omitempty"`     // 圧縮したenqueueでデッドレターであることを表す
+}
+
+// appendWALRecord はレコードをbufの末尾に書き込む
+// 形式はペイロードの長さ(4バイト)、ペイロードのCRC-32C(4バイト)、JSONのペイロードの順
+func appendWALRecord(buf []byte, r walRecord) ([]byte, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return buf, err
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.Checksum(payload, walTable))
+	return append(buf, payload...), nil
+}
+
+// readWAL はログを先頭から読み込み、完全なレコードと最後の完全なレコードの終わりの位置を返す
+// 途中で途切れたレコードや、ファイルの末尾まで続くレコードのチェックサムが一致しない場合は、
+// 書き込みの途中で異常終了したものとして、そのレコードを読み込まずに返す
+// 読み込めないレコードの後にさらにデータがある場合や長さが上限を超える場合は、
+// 書き込みの途中ではなく破損したものとしてErrQueueLogCorruptを返す
+func readWAL(r io.Reader) ([]walRecord, int64, error) {
+	br := bufio.NewReader(r)
+	var (
+		records []walRecord
+		valid   int64
+		header  [walHeaderSize]byte
+	)
+	for {
+		if _, err := io.ReadFull(br, header[:]); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return records, valid, nil
+		} else if err != nil {
+			return records, valid, err
+		}
+		size := binary.LittleEndian.Uint32(header[:4])
+		if size > walMaxRecordSize {
+			// 書き込みの途中で途切れた場合でもヘッダーの長さは正しいため、上限を超える長さは破損として扱う
+			return records, valid, corruptWAL(valid)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return records, valid, nil
+		} else if err != nil {
+			return records, valid, err
+		}
+		var rec walRecord
+		if crc32.Checksum(payload, walTable) != binary.LittleEndian.Uint32(header[4:]) || json.Unmarshal(payload, &rec) != nil {
+			if _, err := br.Peek(1); errors.Is(err, io.EOF) {
+				return records, valid, nil
+			} else if err != nil {
+				return records, valid, err
+			}
+			return records, valid, corruptWAL(valid)
+		}
+		records = append(records, rec)
+		valid += walHeaderSize + int64(size)
+	}
+}
+
+// corruptWAL はoffsetから始まるレコードが破損していることを表すエラーを返す
+func corruptWAL(offset int64) error {
+	return fmt.Errorf("%w: bad record at offset %d", ErrQueueLogCorrupt, offset)
+}
+
+// wal は追記専用のログファイル
+type wal struct {
+	path    string
+	f       *os.File
+	size    int64 // 完全なレコードの終わりの位置
+	records int   // ファイルに記録されているレコード数
+	always  bool  // 追記するたびに同期するかどうか
+	dirty   bool  // 同期していない追記があるかどうか
+}
+
+// openWAL はpathのログを開いて記録されているレコードを読み込む
+// 末尾に途切れたレコードがある場合は、最後の完全なレコードの終わりまでファイルを切り詰める
+// 途中のレコードが破損している場合は、ファイルを変更せずにErrQueueLogCorruptを返す
+func openWAL(path string, always bool) (*wal, []walRecord, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	records, valid, err := readWAL(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("synctest: read queue log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.Size() != valid {
+		if err := f.Truncate(valid); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return &wal{path: path, f: f, size: valid, records: len(records), always: always}, records, nil
+}
+
+// append はレコードを1回の書き込みで追記する
+// 書き込みに失敗した場合は、途中まで書き込んだレコードを切り詰めて追記前の状態に戻す
+func (w *wal) append(records ...walRecord) error {
+	var buf []byte
+	for _, r := range records {
+		var err error
+		if buf, err = appendWALRecord(buf, r); err != nil {
+			return err
+		}
+	}
+	if _, err := w.f.Write(buf); err != nil {
+		return errors.Join(err, w.rollback())
+	}
+	if w.always {
+		if err := w.f.Sync(); err != nil {
+			return errors.Join(err, w.rollback())
+		}
+	} else {
+		w.dirty = true
+	}
+	w.size += int64(len(buf))
+	w.records += len(records)
+	return nil
+}
+
+// rollback はファイルを最後の完全なレコードの終わりまで切り詰める
+func (w *wal) rollback() error {
+	if err := w.f.Truncate(w.size); err != nil {
+		return err
+	}
+	_, err := w.f.Seek(w.size, io.SeekStart)
+	return err
+}
+
+// sync は同期していない追記をストレージに書き出す
+func (w *wal) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// rewrite はrecordsだけを記録した一時ファイルを作成し、ログと置き換える
+// 置き換えはリネームで行うため、途中で異常終了しても元のログか新しいログのどちらかが残る
+func (w *wal) rewrite(records []walRecord) error {
+	var buf []byte
+	for _, r := range records {
+		var err error
+		if buf, err = appendWALRecord(buf, r); err != nil {
+			return err
+		}
+	}
+	dir := filepath.Dir(w.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(w.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmp.Name(), w.path); err != nil {
+		tmp.Close()
+		return err
+	}
+	// リネームした後は新しいログに切り替える。古いログを閉じられなかった場合もエラーとして返す
+	closeErr := w.f.Close()
+	w.f, w.size, w.records, w.dirty = tmp, int64(len(buf)), len(records), false
+	return errors.Join(closeErr, syncDir(dir))
+}
+
+// close は同期していない追記を書き出してファイルを閉じる
+func (w *wal) close() error {
+	return errors.Join(w.sync(), w.f.Close())
+}
+
+// syncDir はディレクトリのエントリの変更をストレージに書き出す
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,251 @@
+package synctest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/synctest"
+
+	synctestpkg "github.com/connect0459/connect-lab/go/gocon2025/internal/synctest"
+)
+
+// logPath はキューのディレクトリのログのパスを返す
+func logPath(dir string) string {
+	return filepath.Join(dir, "queue.wal")
+}
+
+// logSize はキューのログの大きさを返す
+func logSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	info, err := os.Stat(logPath(dir))
+	if err != nil {
+		t.Fatalf("ログを確認できませんでした: %v", err)
+	}
+	return info.Size()
+}
+
+// crash はキューを閉じずにプロセスが異常終了した時点のログを別のディレクトリに複製する
+func crash(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(logPath(dir))
+	if err != nil {
+		t.Fatalf("ログを読み込めませんでした: %v", err)
+	}
+	crashed := t.TempDir()
+	if err := os.WriteFile(logPath(crashed), data, 0o644); err != nil {
+		t.Fatalf("ログを複製できませんでした: %v", err)
+	}
+	return crashed
+}
+
+func TestQueueRecovery(t *testing.T) {
+	t.Run("Table Driven Test - ログの途中で途切れた場合", func(t *testing.T) {
+		// sizes[i]はi+1件目のレコードを書き込んだ後のログの大きさ
+		// 1件目はaの追加、2件目はbの追加、3件目はaの配信
+		testCases := []struct {
+			name    string
+			corrupt func(t *testing.T, path string, sizes []int64)
+			expect  []delivered
+		}{
+			{
+				name:    "途切れていない",
+				corrupt: func(*testing.T, string, []int64) {},
+				// 受信したままのaは失敗として末尾に戻る
+				expect: []delivered{{Task: "b", Delivery: 1}, {Task: "a", Delivery: 2, Failures: 1}, {Task: "c", Delivery: 1}},
+			},
+			{
+				name: "配信のペイロードの途中",
+				corrupt: func(t *testing.T, path string, sizes []int64) {
+					truncate(t, path, sizes[2]-3)
+				},
+				expect: []delivered{{Task: "a", Delivery: 1}, {Task: "b", Delivery: 1}, {Task: "c", Delivery: 1}},
+			},
+			{
+				name: "配信のヘッダーの途中",
+				corrupt: func(t *testing.T, path string, sizes []int64) {
+					truncate(t, path, sizes[1]+5)
+				},
+				expect: []delivered{{Task: "a", Delivery: 1}, {Task: "b", Delivery: 1}, {Task: "c", Delivery: 1}},
+			},
+			{
+				name: "2件目の追加の途中",
+				corrupt: func(t *testing.T, path string, sizes []int64) {
+					truncate(t, path, sizes[0]+12)
+				},
+				expect: []delivered{{Task: "a", Delivery: 1}, {Task: "c", Delivery: 1}},
+			},
+			{
+				name: "1件目の追加の途中",
+				corrupt: func(t *testing.T, path string, sizes []int64) {
+					truncate(t, path, 1)
+				},
+				expect: []delivered{{Task: "c", Delivery: 1}},
+			},
+			{
+				name: "最後のレコードのチェックサムの不一致",
+				corrupt: func(t *testing.T, path string, sizes []int64) {
+					data, err := os.ReadFile(path)
+					if err != nil {
+						t.Fatalf("ログを読み込めませんでした: %v", err)
+					}
+					data[len(data)-2] ^= 0xff
+					if err := os.WriteFile(path, data, 0o644); err != nil {
+						t.Fatalf("ログを書き込めませんでした: %v", err)
+					}
+				},
+				expect: []delivered{{Task: "a", Delivery: 1}, {Task: "b", Delivery: 1}, {Task: "c", Delivery: 1}},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					dir := t.TempDir()
+					q := openQueue(t, synctestpkg.QueueConfig{Dir: dir})
+					var sizes []int64
+					enqueue(t, q, "a")
+					sizes = append(sizes, logSize(t, dir))
+					enqueue(t, q, "b")
+					sizes = append(sizes, logSize(t, dir))
+					if _, err := q.Dequeue(context.Background()); err != nil {
+						t.Fatalf("受信できませんでした: %v", err)
+					}
+					sizes = append(sizes, logSize(t, dir))
+
+					crashed := crash(t, dir)
+					q.Close()
+					tc.corrupt(t, logPath(crashed), sizes)
+
+					recovered := openQueue(t, synctestpkg.QueueConfig{Dir: crashed})
+					// 途切れたレコードを切り詰めた後に追記したレコードも、再び開いたときに読み込める
+					enqueue(t, recovered, "c")
+					if err := recovered.Close(); err != nil {
+						t.Fatalf("Closeに失敗しました: %v", err)
+					}
+					reopened := openQueue(t, synctestpkg.QueueConfig{Dir: crashed})
+					defer reopened.Close()
+
+					if got := dequeueAll(t, reopened); !slices.Equal(got, tc.expect) {
+						t.Errorf("復元したメッセージが期待値と異なります: got %+v, want %+v", got, tc.expect)
+					}
+				})
+			})
+		}
+	})
+
+	t.Run("途切れたレコードを切り詰める", func(t *testing.T) {
+		dir := t.TempDir()
+		q := openQueue(t, synctestpkg.QueueConfig{Dir: dir})
+		enqueue(t, q, "a")
+		valid := logSize(t, dir)
+		enqueue(t, q, "b")
+		q.Close()
+		truncate(t, logPath(dir), valid+4)
+
+		recovered := openQueue(t, synctestpkg.QueueConfig{Dir: dir})
+		defer recovered.Close()
+
+		if size := logSize(t, dir); size != valid {
+			t.Errorf("最後の完全なレコードまで切り詰めることを期待しました: got %d, want %d", size, valid)
+		}
+		if stats := recovered.Stats(); stats.Ready != 1 || stats.LogRecords != 1 {
+			t.Errorf("復元した状態が期待値と異なります: %+v", stats)
+		}
+	})
+
+	t.Run("Table Driven Test - 途中のレコードが破損している場合", func(t *testing.T) {
+		// offsetは1件目のレコードの先頭からの位置
+		testCases := []struct {
+			name   string
+			offset int
+		}{
+			{name: "チェックサムの不一致", offset: 12},
+			{name: "長さが上限を超える", offset: 3},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				dir := t.TempDir()
+				q := openQueue(t, synctestpkg.QueueConfig{Dir: dir})
+				enqueue(t, q, "a", "b", "c")
+				if err := q.Close(); err != nil {
+					t.Fatalf("Closeに失敗しました: %v", err)
+				}
+				data, err := os.ReadFile(logPath(dir))
+				if err != nil {
+					t.Fatalf("ログを読み込めませんでした: %v", err)
+				}
+				data[tc.offset] ^= 0xff
+				if err := os.WriteFile(logPath(dir), data, 0o644); err != nil {
+					t.Fatalf("ログを書き込めませんでした: %v", err)
+				}
+
+				_, err = synctestpkg.OpenQueue(synctestpkg.QueueConfig{Dir: dir})
+
+				if !errors.Is(err, synctestpkg.ErrQueueLogCorrupt) {
+					t.Errorf("ErrQueueLogCorruptを期待しましたが、%vが返されました", err)
+				}
+				// 後続の有効なレコードを失わないように、ログは変更しない
+				after, err := os.ReadFile(logPath(dir))
+				if err != nil {
+					t.Fatalf("ログを読み込めませんでした: %v", err)
+				}
+				if !bytes.Equal(after, data) {
+					t.Errorf("破損したログが変更されました: got %d bytes, want %d bytes", len(after), len(data))
+				}
+			})
+		}
+	})
+
+	t.Run("Table Driven Test - プロセスの異常終了", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			policy synctestpkg.SyncPolicy
+		}{
+			{name: "SyncAlways", policy: synctestpkg.SyncAlways},
+			{name: "SyncPeriodic", policy: synctestpkg.SyncPeriodic},
+			{name: "SyncNever", policy: synctestpkg.SyncNever},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					// 同期の方針にかかわらず、書き込んだ操作はプロセスの異常終了では失われない
+					dir := t.TempDir()
+					q := openQueue(t, synctestpkg.QueueConfig{Dir: dir, Sync: tc.policy})
+					enqueue(t, q, "a", "b")
+					msg, err := q.Dequeue(context.Background())
+					if err != nil {
+						t.Fatalf("受信できませんでした: %v", err)
+					}
+					if err := q.Ack(msg); err != nil {
+						t.Fatalf("Ackできませんでした: %v", err)
+					}
+
+					crashed := crash(t, dir)
+					if err := q.Close(); err != nil {
+						t.Fatalf("Closeに失敗しました: %v", err)
+					}
+					recovered := openQueue(t, synctestpkg.QueueConfig{Dir: crashed, Sync: tc.policy})
+					defer recovered.Close()
+
+					if got, want := dequeueAll(t, recovered), []delivered{{Task: "b", Delivery: 1}}; !slices.Equal(got, want) {
+						t.Errorf("復元したメッセージが期待値と異なります: got %+v, want %+v", got, want)
+					}
+				})
+			})
+		}
+	})
+}
+
+// truncate はファイルをsizeバイトに切り詰める
+func truncate(t *testing.T, path string, size int64) {
+	t.Helper()
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatalf("ログを切り詰められませんでした: %v", err)
+	}
+}
@@ -1,7 +1,17 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/connect0459/connect-lab/go/gocon2025/internal/cli"
+)
 
 func main() {
-	fmt.Println("Hello, GoCon 2025!")
+	// Ctrl+Cで実行中のサブコマンドをキャンセルする
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := cli.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
 }